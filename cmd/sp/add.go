@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/spf13/cobra"
+)
+
+var (
+	addDate    string
+	addSection string
+	addTodo    bool
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add [text]",
+	Short: "Append a timestamped note to a day without opening the editor",
+	Long: `Append a bullet stamped with the current time to today's page, or to
+the day named by --date. The words of the text are joined with spaces;
+without any, the text is read from standard input, so the output of a
+command can be captured too. Further lines of the text are indented under
+the bullet.
+
+--section adds the note under a "## " section of that title, created at
+the end of the day when missing. --todo adds it as a "- [ ]" task.`,
+	Example: `  sp add "standup moved to 10:30"
+  sp add --todo --section Todo renew the certificate
+  make test 2>&1 | tail -5 | sp add --section Builds`,
+	RunE: runAdd,
+}
+
+func init() {
+	addCmd.Flags().StringVar(&addDate, "date", "", "Add to this day (YYYY-MM-DD) instead of today")
+	addCmd.Flags().StringVarP(&addSection, "section", "s", "", `Add under the "## " section with this title`)
+	addCmd.Flags().BoolVarP(&addTodo, "todo", "t", false, `Add a "- [ ]" todo instead of a note`)
+	rootCmd.AddCommand(addCmd)
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	date := scratchpad.Today()
+	if addDate != "" {
+		var err error
+		if date, err = parseDateArg(addDate); err != nil {
+			return err
+		}
+	}
+	text := strings.Join(args, " ")
+	if len(args) == 0 {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("failed to read standard input: %w", err)
+		}
+		text = string(data)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	entry := scratchpad.Entry{Text: text, Section: addSection, Todo: addTodo, At: clock.Now()}
+	if _, err := mgr.AddEntry(date, entry); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Added to %s.\n", date)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var attachDate string
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <file>",
+	Short: "Keep a copy of a file with a day's notes",
+	Long: `Copy a file, such as a screenshot, a log or a PDF, into the day's folder
+under attachments/ in the storage directory and add a Markdown link to it
+at the end of the day (images are embedded). The day records the file, so
+deleting the day moves its attachments to the trash with it.
+
+The day is today unless --date names another. In an encrypted store the
+copy is encrypted too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAttach,
+}
+
+func init() {
+	attachCmd.Flags().StringVar(&attachDate, "date", "", "Attach to this day (YYYY-MM-DD) instead of today")
+	rootCmd.AddCommand(attachCmd)
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	date := scratchpad.Today()
+	if attachDate != "" {
+		var err error
+		if date, err = parseDateArg(attachDate); err != nil {
+			return err
+		}
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer f.Close()
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	a, err := mgr.Attach(date, filepath.Base(args[0]), f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Attached %s to %s.\n", a.Name, date)
+	return nil
+}
+
+// makeAttachmentLoader lists a day's attachments for the notebook.
+func makeAttachmentLoader(mgr *scratchpad.Manager) tui.AttachmentLoader {
+	return func(date string) ([]tui.Attachment, error) {
+		sp, err := mgr.GetByDate(date)
+		if err != nil {
+			return nil, err
+		}
+		attachments := make([]tui.Attachment, 0, len(sp.Attachments))
+		for _, a := range sp.Attachments {
+			attachments = append(attachments, tui.Attachment{Name: a.Name, Size: a.Size})
+		}
+		return attachments, nil
+	}
+}
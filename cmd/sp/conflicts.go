@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var conflictsTake string
+
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "List conflict copies left by file-sync tools",
+	Long: `List the conflict copies Syncthing, Dropbox, Nextcloud or ownCloud left in
+the storage directory when a day changed on two machines at once. Copies
+are never shown as days of their own; merge each into its day with
+'sp conflicts resolve <date>' or with m in the notebook.`,
+	Args: cobra.NoArgs,
+	RunE: runConflicts,
+}
+
+var conflictsResolveCmd = &cobra.Command{
+	Use:   "resolve <date>",
+	Short: "Merge a conflict copy into its day",
+	Long: `Merge a conflict copy into its day and move the copy to the trash.
+
+--take merged (the default) combines both versions against the newest
+revision in the day's history older than either of them. Regions changed
+on both sides keep both versions between conflict markers, to be settled
+by editing the day. --take local keeps this machine's version and
+--take remote the copy's. Either way the day keeps the templates applied
+on both sides.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConflictsResolve,
+}
+
+func init() {
+	conflictsResolveCmd.Flags().StringVar(&conflictsTake, "take", "merged", "Version to keep: merged, local or remote")
+	conflictsCmd.AddCommand(conflictsResolveCmd)
+	rootCmd.AddCommand(conflictsCmd)
+}
+
+func runConflicts(cmd *cobra.Command, _ []string) error {
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	copies, err := mgr.ConflictCopies()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(copies) == 0 {
+		fmt.Fprintln(out, "No conflict copies.")
+		return nil
+	}
+	for _, c := range copies {
+		var status string
+		if merge, lerr := mgr.LoadConflict(c); lerr != nil {
+			status = fmt.Sprintf("unreadable: %v", lerr)
+		} else {
+			status = mergeStatus(merge)
+		}
+		fmt.Fprintf(out, "%s  %-9s  %s  %s\n", c.Date, c.Tool, c.Path, status)
+	}
+	return nil
+}
+
+func mergeStatus(merge *scratchpad.ConflictMerge) string {
+	_, conflicts := merge.Merged()
+	status := "merges cleanly"
+	switch {
+	case conflicts == 1:
+		status = "1 conflicting region"
+	case conflicts > 1:
+		status = fmt.Sprintf("%d conflicting regions", conflicts)
+	}
+	if merge.Base == nil {
+		status += " (no common revision)"
+	}
+	return status
+}
+
+func runConflictsResolve(cmd *cobra.Command, args []string) error {
+	date, err := parseDateArg(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	pending, err := conflictCopiesOf(mgr, date)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return fmt.Errorf("no conflict copy of %s", date)
+	}
+	c := pending[0]
+	merge, err := mgr.LoadConflict(c)
+	if err != nil {
+		return err
+	}
+
+	var content string
+	conflicts := 0
+	switch conflictsTake {
+	case "merged":
+		content, conflicts = merge.Merged()
+	case "local":
+		content = merge.Local.Content
+	case "remote":
+		content = merge.Remote.Content
+	default:
+		return fmt.Errorf("--take must be merged, local or remote, got %q", conflictsTake)
+	}
+	if err := mgr.ResolveConflict(c, content); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Merged %s into %s; the copy is in .trash/conflicts.\n", c.Path, date)
+	if conflicts > 0 {
+		fmt.Fprintf(out, "%s has conflict markers around %d region(s); edit the day to keep one version.\n", date, conflicts)
+	}
+	switch rest := len(pending) - 1; {
+	case rest == 1:
+		fmt.Fprintf(out, "1 more conflict copy of %s left.\n", date)
+	case rest > 1:
+		fmt.Fprintf(out, "%d more conflict copies of %s left.\n", rest, date)
+	}
+	return nil
+}
+
+// conflictCopiesOf lists the conflict copies of one day.
+func conflictCopiesOf(mgr *scratchpad.Manager, date string) ([]scratchpad.ConflictCopy, error) {
+	copies, err := mgr.ConflictCopies()
+	if err != nil {
+		return nil, err
+	}
+	var of []scratchpad.ConflictCopy
+	for _, c := range copies {
+		if c.Date == date {
+			of = append(of, c)
+		}
+	}
+	return of, nil
+}
+
+// conflictDates lists the days that have conflict copies.
+func conflictDates(copies []scratchpad.ConflictCopy) []string {
+	var dates []string
+	for _, c := range copies {
+		if len(dates) == 0 || dates[len(dates)-1] != c.Date {
+			dates = append(dates, c.Date)
+		}
+	}
+	return dates
+}
+
+func makeConflictLoader(mgr *scratchpad.Manager) tui.ConflictLoader {
+	return func(date string) (*tui.SyncConflict, error) {
+		copies, err := conflictCopiesOf(mgr, date)
+		if err != nil || len(copies) == 0 {
+			return nil, err
+		}
+		merge, err := mgr.LoadConflict(copies[0])
+		if err != nil {
+			return nil, err
+		}
+		conflict := &tui.SyncConflict{
+			Date:    date,
+			Source:  copies[0].Tool + " copy",
+			Local:   merge.Local.Content,
+			Remote:  merge.Remote.Content,
+			HasBase: merge.Base != nil,
+		}
+		if merge.Base != nil {
+			conflict.Base = merge.Base.Content
+		}
+		return conflict, nil
+	}
+}
+
+// makeConflictResolver resolves the copy makeConflictLoader showed: both
+// take the first copy of the day.
+func makeConflictResolver(mgr *scratchpad.Manager) tui.ConflictResolver {
+	return func(date, content string) error {
+		copies, err := conflictCopiesOf(mgr, date)
+		if err != nil {
+			return err
+		}
+		if len(copies) == 0 {
+			return fmt.Errorf("no conflict copy of %s", date)
+		}
+		return mgr.ResolveConflict(copies[0], content)
+	}
+}
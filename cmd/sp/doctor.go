@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/spf13/cobra"
+)
+
+var doctorFix bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the day files for problems",
+	Long: `Check every day file in the store and print a summary of problems:
+
+  filename     the name is not a real YYYY-MM-DD date
+  parse        the file cannot be read as a day
+  date         the date field does not match the file name
+  timestamps   created is later than modified
+  permissions  the file is not writable by you, or is writable by others
+  duplicate    the same day is stored in more than one file
+  encrypted    the content is sealed and the store was not unlocked
+
+With --fix, unloadable files are moved into .quarantine/ inside the storage
+directory (nothing is deleted) and permissions are corrected. Exits non-zero
+while errors remain.`,
+	Args:         cobra.NoArgs,
+	RunE:         runDoctor,
+	SilenceUsage: true, // a failed check is not a usage error
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Quarantine bad files and correct permissions")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+	report, err := mgr.Check()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Checked %d day files in %s.\n", report.Files, mgr.Dir())
+	if len(report.Problems) == 0 {
+		fmt.Fprintln(out, "No problems found.")
+		return nil
+	}
+
+	errorsLeft, warnings, fixable := 0, 0, 0
+	quarantined := make(map[string]bool)
+	for _, p := range report.Problems {
+		marker := "!"
+		if p.Severity == scratchpad.SeverityError {
+			marker = "✗"
+		}
+		fmt.Fprintf(out, "%s %s  %s: %s\n", marker, p.Path, p.Check, p.Detail)
+
+		fixed := false
+		switch {
+		case p.Fix == scratchpad.FixNone:
+		case quarantined[p.Path]:
+			fixed = true
+		case !doctorFix:
+			fixable++
+		default:
+			action, rerr := mgr.Repair(p)
+			if rerr != nil {
+				fmt.Fprintf(out, "    fix failed: %v\n", rerr)
+				break
+			}
+			fmt.Fprintf(out, "    fixed: %s\n", action)
+			quarantined[p.Path] = p.Fix == scratchpad.FixQuarantine
+			fixed = true
+		}
+		switch {
+		case fixed:
+		case p.Severity == scratchpad.SeverityError:
+			errorsLeft++
+		default:
+			warnings++
+		}
+	}
+
+	if doctorFix {
+		if err := commitStore(mgr, cfg, "Repair store"); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\n%d errors, %d warnings remaining.\n", errorsLeft, warnings)
+	if fixable > 0 {
+		fmt.Fprintf(out, "Run 'sp doctor --fix' to repair %d of them.\n", fixable)
+	}
+	if errorsLeft > 0 {
+		return fmt.Errorf("%d problems need attention", errorsLeft)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pders01/sp/internal/config"
+	"github.com/pders01/sp/internal/paths"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/vault"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// envPassphrase supplies the passphrase non-interactively, e.g. from a
+// password manager in scripts.
+const envPassphrase = "SP_PASSPHRASE"
+
+var (
+	encryptKeyFile string
+	agentTTL       string
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the content of every day at rest",
+	Long: `Encrypt the content of every day, revision and trashed day with AES-256-GCM.
+Dates, timestamps and applied templates stay readable so the calendar and
+listings work without the key; the page text does not.
+
+The key is derived from a passphrase (asked twice, or taken from
+$SP_PASSPHRASE) or, with --key-file or [encryption] key_file, from a key
+file, which is generated when it does not exist yet. Lose both and the
+content cannot be recovered.
+
+Running it again on an encrypted store seals any files left in plain text
+by an interrupted run.`,
+	Args: cobra.NoArgs,
+	RunE: runEncrypt,
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store every day in plain text again",
+	Args:  cobra.NoArgs,
+	RunE:  runDecrypt,
+}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep the store's key in memory so sp asks for it only once",
+	Long: `Run in the foreground and keep unlocked keys in memory. Other sp runs
+ask the agent over a local socket before prompting, and hand it the key
+after a prompt. Keys are forgotten after --ttl, on 'sp agent lock', or when
+the agent stops. Other runs only talk to the agent with agent = true
+under [encryption] in config.toml.
+
+The socket is $SP_AGENT_SOCK, else sp-agent.sock in $XDG_RUNTIME_DIR, else
+a private directory under the system temp directory. A socket or
+directory that is not owned by you, or is open to other users, is
+refused.`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Make the running agent forget every key",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentLock,
+}
+
+func init() {
+	encryptCmd.Flags().StringVar(&encryptKeyFile, "key-file", "", "Derive the key from this file instead of a passphrase")
+	agentCmd.Flags().StringVar(&agentTTL, "ttl", "8h", `Forget keys this long after unlocking ("0" keeps them)`)
+	agentCmd.AddCommand(agentLockCmd)
+	rootCmd.AddCommand(encryptCmd, decryptCmd, agentCmd)
+}
+
+// unlockStore sets the store's cipher when it has been encrypted. Plain
+// stores are left alone.
+func unlockStore(mgr *scratchpad.Manager, cfg *config.Config, interactive bool) error {
+	params, err := vault.Load(mgr.Dir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c, err := unlockVault(params, cfg, mgr.Dir(), interactive)
+	if err != nil {
+		return err
+	}
+	mgr.SetCipher(c)
+	return nil
+}
+
+// unlockVault tries the agent first when [encryption] agent is on, then
+// the key file, $SP_PASSPHRASE or, when interactive, a prompt. The TUI
+// opens workspaces without one, so it does not read keys meant for it.
+func unlockVault(params *vault.Params, cfg *config.Config, dir string, interactive bool) (*vault.Cipher, error) {
+	socket := vault.SocketPath()
+	if cfg.Encryption.Agent {
+		if key, err := vault.AgentKey(socket, params.ID()); err == nil {
+			if c, err := params.Unlock(key); err == nil {
+				return c, nil
+			}
+		}
+	}
+	var secret []byte
+	var err error
+	if params.KDF == vault.KDFKeyFile {
+		if cfg.Encryption.KeyFile == "" {
+			return nil, errors.New("this store is encrypted with a key file; set [encryption] key_file in config.toml")
+		}
+		secret, err = vault.ReadKeyFile(cfg.Encryption.KeyFile)
+	} else if interactive || os.Getenv(envPassphrase) != "" {
+		secret, err = readPassphrase("Passphrase for "+dir+": ", false)
+	} else {
+		return nil, fmt.Errorf("the store is encrypted; run sp agent or set $%s to open it here", envPassphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := params.DeriveKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	c, err := params.Unlock(key)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Encryption.Agent {
+		_ = vault.AgentStore(socket, params.ID(), key) // no agent running
+	}
+	return c, nil
+}
+
+// readPassphrase takes the passphrase from $SP_PASSPHRASE or asks on the
+// terminal without echo; confirmNew asks twice.
+func readPassphrase(prompt string, confirmNew bool) ([]byte, error) {
+	if p := os.Getenv(envPassphrase); p != "" {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("the store is encrypted and there is no terminal to ask for the passphrase; set $%s or run sp agent", envPassphrase)
+	}
+	ask := func(prompt string) ([]byte, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		p, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return p, nil
+	}
+	p, err := ask(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirmNew {
+		again, err := ask("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(again) != string(p) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+func runEncrypt(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if mgr.Encrypted() {
+		n, err := mgr.Recrypt(mgr.Cipher())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Store is already encrypted; sealed %d remaining files.\n", n)
+		return commitStore(mgr, cfg, "Encrypt remaining days")
+	}
+
+	keyFile := cfg.Encryption.KeyFile
+	if encryptKeyFile != "" {
+		if keyFile, err = paths.Expand(encryptKeyFile); err != nil {
+			return err
+		}
+		if keyFile, err = filepath.Abs(keyFile); err != nil {
+			return err
+		}
+	}
+	var params *vault.Params
+	var secret []byte
+	if keyFile != "" {
+		if _, serr := os.Stat(keyFile); errors.Is(serr, fs.ErrNotExist) {
+			if err := vault.GenerateKeyFile(keyFile); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Generated key file %s. Back it up: without it the store cannot be read.\n", keyFile)
+		}
+		if secret, err = vault.ReadKeyFile(keyFile); err != nil {
+			return err
+		}
+		params, err = vault.NewParams(vault.KDFKeyFile)
+	} else {
+		if secret, err = readPassphrase("New passphrase: ", true); err != nil {
+			return err
+		}
+		params, err = vault.NewParams(vault.KDFPassphrase)
+	}
+	if err != nil {
+		return err
+	}
+	key, err := params.DeriveKey(secret)
+	if err != nil {
+		return err
+	}
+	if err := params.Seal(key); err != nil {
+		return err
+	}
+	c, err := params.Unlock(key)
+	if err != nil {
+		return err
+	}
+	// The parameters go first: files sealed without them on disk could
+	// never be opened again, while a store with parameters and some plain
+	// files still reads fine and is finished by running encrypt again.
+	if err := params.Save(mgr.Dir()); err != nil {
+		return err
+	}
+	n, err := mgr.Recrypt(c)
+	if err != nil {
+		return fmt.Errorf("%w (run sp encrypt again to finish)", err)
+	}
+	if cfg.Encryption.Agent {
+		_ = vault.AgentStore(vault.SocketPath(), params.ID(), key)
+	}
+	fmt.Fprintf(out, "Encrypted %d files.\n", n)
+	if err := commitStore(mgr, cfg, "Encrypt store"); err != nil {
+		return err
+	}
+	if cfg.Storage.Git.Enabled {
+		fmt.Fprintln(out, "Note: earlier commits still hold the days in plain text; git history is not rewritten.")
+	}
+	if keyFile != "" && keyFile != cfg.Encryption.KeyFile {
+		fmt.Fprintf(out, "Set key_file = %q under [encryption] in config.toml so sp can unlock the store.\n", keyFile)
+	}
+	return nil
+}
+
+func runDecrypt(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+	if !mgr.Encrypted() {
+		return errors.New("the store is not encrypted")
+	}
+	n, err := mgr.Recrypt(nil)
+	if err != nil {
+		return fmt.Errorf("%w (run sp decrypt again to finish)", err)
+	}
+	if err := vault.Remove(mgr.Dir()); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Decrypted %d files.\n", n)
+	return commitStore(mgr, cfg, "Decrypt store")
+}
+
+func runAgent(cmd *cobra.Command, _ []string) error {
+	ttl, err := config.ParseDuration(agentTTL)
+	if err != nil {
+		return fmt.Errorf("--ttl: %w", err)
+	}
+	socket := vault.SocketPath()
+	l, err := vault.Listen(socket)
+	if err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		l.Close()
+	}()
+	fmt.Fprintf(cmd.OutOrStdout(), "sp agent listening on %s\n", socket)
+	return vault.NewAgent(ttl).Serve(l)
+}
+
+func runAgentLock(cmd *cobra.Command, _ []string) error {
+	if err := vault.AgentLock(vault.SocketPath()); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Agent locked.")
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pders01/sp/internal/diff"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <date>",
+	Short: "List saved revisions of a day",
+	Long: `List the saved revisions of a day, newest first, with a summary of the
+lines each save added and removed. Pass the number or ID shown here to
+'sp restore' to roll the day back.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <date> <rev>",
+	Short: "Roll a day back to an earlier revision",
+	Long: `Replace a day's content with an earlier revision. <rev> is either the
+number shown by 'sp history' (1 is the newest) or the full revision ID.
+The version being replaced stays in history, so a restore can be undone.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd, restoreCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	date, err := parseDateArg(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	revs, err := mgr.History(date)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(revs) == 0 {
+		fmt.Fprintf(out, "No revisions recorded for %s.\n", date)
+		return nil
+	}
+
+	contents := make([]string, len(revs))
+	for i, rev := range revs {
+		if sp, lerr := mgr.LoadRevision(date, rev.ID); lerr == nil {
+			contents[i] = sp.Content
+		}
+	}
+	fmt.Fprintf(out, "%s — %d revisions\n", date, len(revs))
+	for i, rev := range revs {
+		// Each save is summarized against the one before it; the oldest
+		// kept revision has nothing to compare with.
+		summary := "oldest kept"
+		if i+1 < len(revs) {
+			summary = diff.Summarize(contents[i+1], contents[i]).String()
+		}
+		marker := ""
+		if i == 0 {
+			marker = "  (current)"
+		}
+		fmt.Fprintf(out, "%3d  %s  %s  %-12s%s\n",
+			i+1, rev.Saved.Format("2006-01-02 15:04:05"), rev.ID, summary, marker)
+	}
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	date, err := parseDateArg(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	id, err := resolveRevision(mgr, date, args[1])
+	if err != nil {
+		return err
+	}
+	if _, err := mgr.Restore(date, id); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", date, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Restored %s to revision %s.\n", date, id)
+	return nil
+}
+
+// resolveRevision maps a list number from 'sp history' to its revision ID.
+// Anything that is not a valid list number is treated as an ID.
+func resolveRevision(mgr *scratchpad.Manager, date, rev string) (string, error) {
+	n, err := strconv.Atoi(rev)
+	if err != nil {
+		return rev, nil
+	}
+	revs, err := mgr.History(date)
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || n > len(revs) {
+		return "", fmt.Errorf("revision %d out of range: %s has %d revisions", n, date, len(revs))
+	}
+	return revs[n-1].ID, nil
+}
+
+// parseDateArg validates a YYYY-MM-DD command-line argument.
+func parseDateArg(arg string) (string, error) {
+	t, err := time.Parse("2006-01-02", arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: want YYYY-MM-DD", arg)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// revisionStore is implemented by stores that keep a revision log. Only
+// the filesystem store does; others simply get no history browser.
+type revisionStore interface {
+	History(date string) ([]scratchpad.Revision, error)
+	LoadRevision(date, id string) (*scratchpad.Scratchpad, error)
+}
+
+func makeHistoryLoader(mgr revisionStore) tui.HistoryLoader {
+	return func(date string) ([]tui.PageRevision, error) {
+		revs, err := mgr.History(date)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]tui.PageRevision, 0, len(revs))
+		for _, rev := range revs {
+			sp, lerr := mgr.LoadRevision(date, rev.ID)
+			if lerr != nil {
+				continue
+			}
+			out = append(out, tui.PageRevision{ID: rev.ID, Saved: rev.Saved, Content: sp.Content})
+		}
+		return out, nil
+	}
+}
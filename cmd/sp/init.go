@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pders01/sp/internal/gitsync"
+	"github.com/pders01/sp/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Create a project-local scratchpad",
+	Long: `Create a .sp directory in dir (default: the working directory) for
+notes that belong to the project. From anywhere below dir, sp then uses it
+instead of the global store, the way git finds .git; --global goes back to
+the global store.
+
+Settings in an sp.toml next to .sp are merged over the global config.toml.
+The new store gets a .gitignore so history, trash and caches stay out of
+the project's repository while the days themselves can be committed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	store := filepath.Join(root, paths.ProjectDir)
+	if paths.IsGlobal(store) {
+		return fmt.Errorf("%s is the global store; run sp init inside a project", store)
+	}
+	if _, err := os.Stat(store); err == nil {
+		return fmt.Errorf("%s already has a project scratchpad", root)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(store, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", store, err)
+	}
+	if err := gitsync.WriteIgnore(store); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Created project scratchpad in %s\n", store)
+	return nil
+}
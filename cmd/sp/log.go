@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/templates"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logFence   bool
+	logDate    string
+	logSection string
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [--fence] [-- command args...]",
+	Short: "Run a command, or read piped output, into the day as a code block",
+	Long: `Run a command and append its output (stdout and stderr, as the terminal
+would show them) to today's page as a fenced code block, under a bullet
+with the time, the command line and its exit status. The output is also
+passed through to the terminal.
+
+With --fence the output is read from standard input instead; any
+arguments then label the block in place of the command line.
+
+Commands are held to the limits of command templates: output beyond
+1 MiB is cut off, and a command still running after 10 seconds is killed.
+--date and --section place the block like they do for sp add.`,
+	Example: `  sp log -- go test ./...
+  kubectl get pods | sp log --fence kubectl get pods`,
+	RunE: runLog,
+}
+
+func init() {
+	logCmd.Flags().BoolVar(&logFence, "fence", false, "Read the output from standard input")
+	logCmd.Flags().StringVar(&logDate, "date", "", "Add to this day (YYYY-MM-DD) instead of today")
+	logCmd.Flags().StringVarP(&logSection, "section", "s", "", `Add under the "## " section with this title`)
+	rootCmd.AddCommand(logCmd)
+}
+
+// loggedOutput is what sp log appends: a command's output and how the
+// command ended.
+type loggedOutput struct {
+	command   string
+	output    string
+	status    string
+	truncated bool
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	date := scratchpad.Today()
+	if logDate != "" {
+		var err error
+		if date, err = parseDateArg(logDate); err != nil {
+			return err
+		}
+	}
+	if !logFence && len(args) == 0 {
+		return errors.New("name a command after --, or pipe output in with --fence")
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+
+	started := clock.Now()
+	var logged *loggedOutput
+	if logFence {
+		logged, err = readLogged(cmd.InOrStdin(), cmd.OutOrStdout(), args)
+	} else {
+		logged, err = runLogged(args, cmd.OutOrStdout(), templates.CommandTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	entry := scratchpad.Entry{Text: logged.markdown(), Section: logSection, At: started}
+	if _, err := mgr.AddEntry(date, entry); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Logged to %s.\n", date)
+	return nil
+}
+
+// runLogged runs args, passing its output through to echo, and records
+// how it ended. A command that cannot be started is an error; one that
+// fails is logged with its exit status.
+func runLogged(args []string, echo io.Writer, timeout time.Duration) (*loggedOutput, error) {
+	out := templates.NewLimitedBuffer(templates.MaxCommandOutput)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, args[0], args[1:]...) // #nosec G204 -- the user's own command line.
+	both := io.MultiWriter(out, echo)
+	c.Stdout = both
+	c.Stderr = both
+	runErr := c.Run()
+
+	logged := &loggedOutput{command: shellJoin(args), output: out.String(), truncated: out.Overflowed()}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logged.status = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(runErr, &exitErr) && exitErr.ExitCode() >= 0:
+		logged.status = fmt.Sprintf("exit %d", exitErr.ExitCode())
+	case errors.As(runErr, &exitErr):
+		logged.status = exitErr.String()
+	case runErr != nil:
+		return nil, fmt.Errorf("failed to run %s: %w", args[0], runErr)
+	default:
+		logged.status = "exit 0"
+	}
+	return logged, nil
+}
+
+// readLogged reads piped output, passing it through to echo. label, when
+// given, stands in for the command line.
+func readLogged(in io.Reader, echo io.Writer, label []string) (*loggedOutput, error) {
+	out := templates.NewLimitedBuffer(templates.MaxCommandOutput)
+	if _, err := io.Copy(io.MultiWriter(out, echo), in); err != nil {
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return &loggedOutput{command: strings.Join(label, " "), output: out.String(), truncated: out.Overflowed()}, nil
+}
+
+// markdown renders the header line and the fenced output.
+func (l *loggedOutput) markdown() string {
+	var header []string
+	if l.command != "" {
+		header = append(header, inlineCode("$ "+l.command))
+	}
+	if l.status != "" {
+		header = append(header, l.status)
+	}
+	output := strings.TrimRight(l.output, "\r\n")
+	switch {
+	case output == "":
+		header = append(header, "no output")
+	case l.truncated:
+		header = append(header, "output cut off at 1 MiB")
+	}
+	if len(header) == 0 {
+		header = append(header, "piped output")
+	}
+	text := strings.Join(header, " · ")
+	if output != "" {
+		fence := strings.Repeat("`", max(3, longestRun(output, '`')+1))
+		text += "\n\n" + fence + "\n" + output + "\n" + fence
+	}
+	return text
+}
+
+// inlineCode wraps s in a backtick run longer than any inside it.
+func inlineCode(s string) string {
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if len(ticks) > 1 {
+		return ticks + " " + s + " " + ticks
+	}
+	return ticks + s + ticks
+}
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// shellJoin renders args as a shell command line, quoting the arguments
+// that need it.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`&|;<>()*?[]{}#~!") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
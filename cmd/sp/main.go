@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"sort"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pders01/sp/internal/clock"
 	"github.com/pders01/sp/internal/config"
 	"github.com/pders01/sp/internal/editor"
+	"github.com/pders01/sp/internal/paths"
 	"github.com/pders01/sp/internal/scratchpad"
 	"github.com/pders01/sp/internal/templates"
 	"github.com/pders01/sp/internal/tui"
@@ -31,8 +36,11 @@ func VersionInfo() string {
 }
 
 var (
-	calendarFlag bool
-	notebookFlag bool
+	calendarFlag  bool
+	notebookFlag  bool
+	dirFlag       string
+	workspaceFlag string
+	globalFlag    bool
 )
 
 func main() {
@@ -53,12 +61,19 @@ Flow:
   sp -c     calendar; Enter drills into the notebook at that day, then
             Enter again drills into the editor. Press 'e' in the calendar
             to skip the notebook preview and edit immediately.`,
-	RunE: runScratchpad,
+	PersistentPreRunE: checkWorkspace,
+	RunE:              runScratchpad,
 }
 
 func init() {
 	rootCmd.Flags().BoolVarP(&calendarFlag, "calendar", "c", false, "Open calendar view to select a date")
 	rootCmd.Flags().BoolVarP(&notebookFlag, "notebook", "n", false, "Open notebook view to browse all notes")
+	rootCmd.PersistentFlags().StringVar(&dirFlag, "dir", "",
+		"sp home holding config.toml and day files (overrides $SP_HOME and [storage] dir)")
+	rootCmd.PersistentFlags().StringVarP(&workspaceFlag, "workspace", "w", "",
+		"workspace from config.toml to use (overrides $"+config.EnvWorkspace+")")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlag, "global", "g", false,
+		"use the global store even inside a project with a .sp directory")
 }
 
 func Execute() {
@@ -68,20 +83,138 @@ func Execute() {
 	}
 }
 
-func runScratchpad(_ *cobra.Command, _ []string) error {
-	mgr, err := scratchpad.NewManager()
+// configPath locates config.toml: inside --dir when given, else the
+// default sp home.
+func configPath() (string, error) {
+	if dirFlag != "" {
+		dir, err := paths.Expand(dirFlag)
+		if err != nil {
+			return "", err
+		}
+		return config.PathIn(dir), nil
+	}
+	return config.DefaultPath()
+}
+
+// loadConfig reads config.toml like loadBaseConfig and applies the
+// workspace selected with -w or $SP_WORKSPACE, which checkWorkspace has
+// already validated.
+func loadConfig() *config.Config {
+	cfg := loadBaseConfig()
+	if name := workspaceName(); name != "" {
+		if err := cfg.UseWorkspace(name); err != nil {
+			fmt.Fprintf(os.Stderr, "sp: %v\n", err)
+		}
+	}
+	return cfg
+}
+
+// workspaceName is the workspace selected with -w, else $SP_WORKSPACE.
+func workspaceName() string {
+	if workspaceFlag != "" {
+		return workspaceFlag
+	}
+	return os.Getenv(config.EnvWorkspace)
+}
+
+// checkWorkspace stops every command when the selected workspace is not
+// defined, rather than quietly using the default store and writing notes
+// into the wrong notebook.
+func checkWorkspace(*cobra.Command, []string) error {
+	name := workspaceName()
+	if name == "" {
+		return nil
+	}
+	path, err := configPath()
 	if err != nil {
-		return fmt.Errorf("failed to initialize scratchpad manager: %w", err)
+		return err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if root, ok := projectRoot(); ok {
+		if err := cfg.UseProject(root); err != nil {
+			return err
+		}
 	}
+	return cfg.UseWorkspace(name)
+}
 
+// projectRoot finds the project-local store above the working directory.
+// --global and --dir, which name a store themselves, skip the search.
+func projectRoot() (string, bool) {
+	if globalFlag || dirFlag != "" {
+		return "", false
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	return paths.FindProject(wd)
+}
+
+// loadBaseConfig reads config.toml and, inside a project, its sp.toml,
+// falling back to defaults with a warning when a file cannot be parsed.
+func loadBaseConfig() *config.Config {
 	cfg := config.Default()
-	if path, perr := config.DefaultPath(); perr == nil {
+	if path, perr := configPath(); perr == nil {
 		if loaded, lerr := config.Load(path); lerr == nil {
 			cfg = loaded
 		} else {
 			fmt.Fprintf(os.Stderr, "sp: %v\n", lerr)
 		}
 	}
+	if root, ok := projectRoot(); ok {
+		if err := cfg.UseProject(root); err != nil {
+			fmt.Fprintf(os.Stderr, "sp: %v\n", err)
+		}
+	}
+	clock.Set(cfg.DayBoundary())
+	return cfg
+}
+
+// openManager builds the scratchpad store configured by cfg, in the
+// directory picked by config.StorageDir. Unless interactive is set, an
+// encrypted store is opened only when that needs no prompt.
+func openManager(cfg *config.Config, interactive bool) (*scratchpad.Manager, error) {
+	dir, err := cfg.StorageDir(dirFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+	mgr, err := scratchpad.NewManagerAt(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize scratchpad manager: %w", err)
+	}
+	format, err := scratchpad.ParseFormat(cfg.Storage.Format)
+	if err != nil {
+		return nil, fmt.Errorf("storage.format: %w", err)
+	}
+	mgr.SetFormat(format)
+	layout, err := scratchpad.ParseLayout(cfg.Storage.Layout)
+	if err != nil {
+		return nil, fmt.Errorf("storage.layout: %w", err)
+	}
+	mgr.SetLayout(layout)
+	mgr.SetHistoryPolicy(scratchpad.HistoryPolicy{
+		Keep:   cfg.History.Keep,
+		MaxAge: time.Duration(cfg.History.MaxAge),
+	})
+	if err := unlockStore(mgr, cfg, interactive); err != nil {
+		return nil, err
+	}
+	if err := enableGit(mgr, cfg); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+func runScratchpad(_ *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
 	icons := tui.NewIconSet(cfg.UI.Icons)
 
 	ed, eerr := editor.NewEditor()
@@ -95,27 +228,83 @@ func runScratchpad(_ *cobra.Command, _ []string) error {
 	return runApp(mgr, ed, icons, cfg)
 }
 
-func runApp(mgr *scratchpad.Manager, ed *editor.Editor, icons tui.IconSet, cfg *config.Config) error {
-	dates, contents, applied, err := loadAll(mgr)
+func runApp(store scratchpad.Store, ed *editor.Editor, icons tui.IconSet, cfg *config.Config) error {
+	days, err := loadSummaries(store)
 	if err != nil {
 		return err
 	}
-	if len(dates) == 0 && notebookFlag && !calendarFlag {
+	if len(days.dates) == 0 && notebookFlag && !calendarFlag {
 		fmt.Println("No scratchpad pages found.")
 		return nil
 	}
+	current, err := newSession(store, days, ed, icons, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { current.close() }()
+	app := current.app
+	if names := cfg.WorkspaceNames(); len(names) > 0 {
+		app.SetWorkspaces(names, cfg.Workspace(), func(name string) (*tui.App, error) {
+			next, err := openWorkspace(name, ed, icons)
+			if err != nil {
+				return nil, err
+			}
+			current.close()
+			current = next
+			return next.app, nil
+		})
+	}
+
+	if _, rerr := tea.NewProgram(app, tea.WithAltScreen()).Run(); rerr != nil {
+		return fmt.Errorf("failed to run TUI: %w", rerr)
+	}
+
+	// Fallback path for unwired editor: app exits with calendar's
+	// directEdit/selected set or notebook's selected set, expecting
+	// main to run the editor.
+	if current.cal.GetSelectedDate() != "" && current.cal.IsDirectEdit() {
+		return editAndSave(current.store, ed, current.cal.GetSelectedDate())
+	}
+	if d := current.nb.GetSelectedDate(); d != "" {
+		return editAndSave(current.store, ed, d)
+	}
+	return nil
+}
+
+// session is the views of one workspace and the store behind them.
+type session struct {
+	store scratchpad.Store
+	app   *tui.App
+	cal   *tui.Calendar
+	nb    *tui.Notebook
+	// stop ends the session's live reload.
+	stop func()
+}
+
+// close ends the session: its live reload stops and its views let go of
+// their watchers.
+func (s *session) close() {
+	s.stop()
+	s.app.Close()
+}
 
-	cal := tui.NewCalendar(dates)
+// newSession builds the calendar and notebook for store.
+func newSession(store scratchpad.Store, days *dayListing, ed *editor.Editor, icons tui.IconSet, cfg *config.Config) (*session, error) {
+	cal := tui.NewCalendar(days.dates)
 	cal.SetIcons(icons)
 	cal.SetThemePref(cfg.UI.Theme)
-	cal.SetContents(contents)
-	cal.SetEditor(ed, makeSaver(mgr), makeLoader(mgr))
+	cal.SetPreviews(days.previews)
+	cal.SetConflicts(days.conflicts)
+	cal.SetEditor(ed, makeSaver(store), makeLoader(store))
 
-	nb := tui.NewNotebook(dates)
+	nb := tui.NewNotebook(days.dates)
 	nb.SetIcons(icons)
 	nb.SetThemePref(cfg.UI.Theme)
-	nb.SetContents(contents)
-	nb.SetEditor(ed, makeSaver(mgr))
+	nb.SetLoader(makeLoader(store))
+	nb.SetEditor(ed, makeSaver(store))
+	if revisions, ok := store.(revisionStore); ok {
+		nb.SetHistory(makeHistoryLoader(revisions))
+	}
 
 	mode := tui.ModeCalendar
 	if notebookFlag && !calendarFlag {
@@ -124,45 +313,70 @@ func runApp(mgr *scratchpad.Manager, ed *editor.Editor, icons tui.IconSet, cfg *
 	app := tui.NewApp(cal, nb, mode)
 	definitions, err := templateDefinitions(cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	options := make([]tui.DayTemplate, 0, len(definitions))
 	for _, definition := range definitions {
 		options = append(options, tui.DayTemplate{ID: definition.ID, Name: definition.Name})
 	}
-	app.SetTemplates(options, applied, makeTemplateApplier(mgr, definitions))
-	defer app.Close()
+	app.SetTemplates(options, days.applied, makeTemplateApplier(store, definitions))
+	app.SetDeleter(store.Delete)
 
-	if _, rerr := tea.NewProgram(app, tea.WithAltScreen()).Run(); rerr != nil {
-		return fmt.Errorf("failed to run TUI: %w", rerr)
+	s := &session{store: store, app: app, cal: cal, nb: nb, stop: func() {}}
+	if mgr, ok := store.(*scratchpad.Manager); ok {
+		nb.SetAttachments(makeAttachmentLoader(mgr))
+		if pages, perr := mgr.ListPages(); perr == nil {
+			app.SetPages(pages, makePageLoader(mgr), makePageSaver(mgr))
+		}
+		if copies, cerr := mgr.ConflictCopies(); cerr == nil {
+			app.SetSyncConflicts(conflictDates(copies), makeConflictLoader(mgr), makeConflictResolver(mgr))
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stop = cancel
+		// Live reload is a convenience: without a watcher the TUI simply
+		// shows the store as it was at startup.
+		if changes, werr := mgr.Watch(ctx); werr == nil {
+			app.SetLiveReload(changes, makeDayReloader(mgr))
+		}
 	}
+	return s, nil
+}
 
-	// Fallback path for unwired editor: app exits with calendar's
-	// directEdit/selected set or notebook's selected set, expecting
-	// main to run the editor.
-	if cal.GetSelectedDate() != "" && cal.IsDirectEdit() {
-		return editAndSave(mgr, ed, cal.GetSelectedDate())
+// openWorkspace opens the named workspace ("" for the top-level store)
+// for the running TUI. An encrypted workspace must be unlockable without
+// a prompt, since the TUI owns the terminal.
+func openWorkspace(name string, ed *editor.Editor, icons tui.IconSet) (*session, error) {
+	cfg := loadBaseConfig()
+	if name != "" {
+		if err := cfg.UseWorkspace(name); err != nil {
+			return nil, err
+		}
 	}
-	if d := nb.GetSelectedDate(); d != "" {
-		return editAndSave(mgr, ed, d)
+	mgr, err := openManager(cfg, false)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	days, err := loadSummaries(mgr)
+	if err != nil {
+		return nil, err
+	}
+	return newSession(mgr, days, ed, icons, cfg)
 }
 
-func makeSaver(mgr *scratchpad.Manager) tui.Saver {
+func makeSaver(store scratchpad.Store) tui.Saver {
 	return func(date, content string) error {
-		sp, err := mgr.GetByDate(date)
+		sp, err := store.GetByDate(date)
 		if err != nil {
 			return err
 		}
 		sp.Content = content
-		return mgr.Save(sp)
+		return store.Save(sp)
 	}
 }
 
-func makeLoader(mgr *scratchpad.Manager) func(string) (string, error) {
+func makeLoader(store scratchpad.Store) func(string) (string, error) {
 	return func(date string) (string, error) {
-		sp, err := mgr.GetByDate(date)
+		sp, err := store.GetByDate(date)
 		if err != nil {
 			return "", err
 		}
@@ -170,6 +384,27 @@ func makeLoader(mgr *scratchpad.Manager) func(string) (string, error) {
 	}
 }
 
+func makeDayReloader(mgr *scratchpad.Manager) tui.DayReloader {
+	return func(date string) (*tui.StoredDay, error) {
+		sp, err := mgr.Lookup(date)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		copies, err := conflictCopiesOf(mgr, date)
+		if err != nil {
+			return nil, err
+		}
+		return &tui.StoredDay{
+			Content:          sp.Content,
+			AppliedTemplates: sp.AppliedTemplates,
+			SyncConflict:     len(copies) > 0,
+		}, nil
+	}
+}
+
 func templateDefinitions(cfg *config.Config) ([]templates.Definition, error) {
 	definitions := templates.Builtins()
 	for _, configured := range cfg.Templates.Items {
@@ -189,7 +424,7 @@ func templateDefinitions(cfg *config.Config) ([]templates.Definition, error) {
 	return templates.Normalize(definitions)
 }
 
-func makeTemplateApplier(mgr *scratchpad.Manager, definitions []templates.Definition) tui.TemplateApplier {
+func makeTemplateApplier(store scratchpad.Store, definitions []templates.Definition) tui.TemplateApplier {
 	byID := make(map[string]templates.Definition, len(definitions))
 	for _, definition := range definitions {
 		byID[definition.ID] = definition
@@ -211,7 +446,7 @@ func makeTemplateApplier(mgr *scratchpad.Manager, definitions []templates.Defini
 		if err := ctx.Err(); err != nil {
 			return tui.TemplateApplyResult{}, err
 		}
-		sp, err := mgr.ApplyTemplateSections(date, sections)
+		sp, err := store.ApplyTemplateSections(date, sections)
 		if err != nil {
 			return tui.TemplateApplyResult{}, err
 		}
@@ -219,41 +454,86 @@ func makeTemplateApplier(mgr *scratchpad.Manager, definitions []templates.Defini
 	}
 }
 
-// loadAll reads every saved scratchpad and returns dates (descending), their
-// contents, and template metadata used by the chooser.
-func loadAll(mgr *scratchpad.Manager) (dates []string, contents map[string]string, applied map[string][]string, err error) {
-	dates, err = mgr.ListDates()
+// dayListing is what the views start from: dates (descending), calendar
+// previews, template metadata used by the chooser, and days holding an
+// unresolved sync conflict.
+type dayListing struct {
+	dates     []string
+	previews  map[string]string
+	applied   map[string][]string
+	conflicts []string
+}
+
+// summarizer is implemented by stores that summarize their days without
+// handing out content. The filesystem store answers from a cache kept
+// beside the day files; other stores get their days loaded one by one.
+type summarizer interface {
+	Summaries() ([]scratchpad.DaySummary, error)
+}
+
+// loadSummaries lists every saved day, without reading its content when
+// the store can summarize it. The views load a day's content when it is
+// first shown.
+func loadSummaries(store scratchpad.Store) (*dayListing, error) {
+	summaries, err := summarize(store)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to list dates: %w", err)
+		return nil, fmt.Errorf("failed to list dates: %w", err)
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
 
-	contents = make(map[string]string, len(dates))
-	applied = make(map[string][]string, len(dates))
-	for _, d := range dates {
-		sp, gerr := mgr.GetByDate(d)
-		if gerr != nil {
-			contents[d] = fmt.Sprintf("Error loading: %v", gerr)
+	days := &dayListing{
+		dates:    make([]string, 0, len(summaries)),
+		previews: make(map[string]string, len(summaries)),
+		applied:  make(map[string][]string, len(summaries)),
+	}
+	for i := len(summaries) - 1; i >= 0; i-- {
+		summary := summaries[i]
+		days.dates = append(days.dates, summary.Date)
+		if summary.Err != nil {
+			days.previews[summary.Date] = fmt.Sprintf("Error loading: %v", summary.Err)
 			continue
 		}
-		contents[d] = sp.Content
-		applied[d] = append([]string(nil), sp.AppliedTemplates...)
+		days.previews[summary.Date] = summary.Preview
+		days.applied[summary.Date] = summary.AppliedTemplates
+		if summary.Conflict {
+			days.conflicts = append(days.conflicts, summary.Date)
+		}
+	}
+	return days, nil
+}
+
+// summarize returns a summary of every day in store in date order.
+func summarize(store scratchpad.Store) ([]scratchpad.DaySummary, error) {
+	if s, ok := store.(summarizer); ok {
+		return s.Summaries()
+	}
+	dates, err := store.ListDates()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dates)
+	summaries := make([]scratchpad.DaySummary, 0, len(dates))
+	for _, date := range dates {
+		summary := scratchpad.DaySummary{Date: date}
+		if sp, err := store.GetByDate(date); err != nil {
+			summary.Err = err
+		} else {
+			summary = scratchpad.Summarize(sp)
+			summary.Date = date
+		}
+		summaries = append(summaries, summary)
 	}
-	return dates, contents, applied, nil
+	return summaries, nil
 }
 
 // editAndSave opens the picked date (or today, when empty) in $EDITOR
 // and persists changes when the user actually edited something. Used
 // for the bare `sp` flow and as a fallback when the TUI couldn't wire
 // the editor inline.
-func editAndSave(mgr *scratchpad.Manager, ed *editor.Editor, pickedDate string) error {
-	var sp *scratchpad.Scratchpad
-	var err error
+func editAndSave(store scratchpad.Store, ed *editor.Editor, pickedDate string) error {
 	if pickedDate == "" {
-		sp, err = mgr.GetToday()
-	} else {
-		sp, err = mgr.GetByDate(pickedDate)
+		pickedDate = scratchpad.Today()
 	}
+	sp, err := store.GetByDate(pickedDate)
 	if err != nil {
 		return fmt.Errorf("failed to load scratchpad: %w", err)
 	}
@@ -265,7 +545,7 @@ func editAndSave(mgr *scratchpad.Manager, ed *editor.Editor, pickedDate string)
 
 	if newContent != sp.Content {
 		sp.Content = newContent
-		if err := mgr.Save(sp); err != nil {
+		if err := store.Save(sp); err != nil {
 			return fmt.Errorf("failed to save scratchpad: %w", err)
 		}
 		fmt.Println("Scratchpad saved!")
@@ -1,9 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pders01/sp/internal/clock"
 	"github.com/pders01/sp/internal/config"
+	"github.com/pders01/sp/internal/paths"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/vault"
 )
 
 func TestTemplateDefinitionsRequireCommandOptIn(t *testing.T) {
@@ -22,3 +36,783 @@ func TestTemplateDefinitionsRequireCommandOptIn(t *testing.T) {
 		t.Errorf("definitions = %+v", definitions)
 	}
 }
+
+func TestResolveRevisionAcceptsListNumbersAndIDs(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	mgr, err := openManager(config.Default(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"one", "two"} {
+		sp, gerr := mgr.GetByDate("2024-01-01")
+		if gerr != nil {
+			t.Fatal(gerr)
+		}
+		sp.Content = content
+		if serr := mgr.Save(sp); serr != nil {
+			t.Fatal(serr)
+		}
+	}
+	revs, err := mgr.History("2024-01-01")
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("History = %v, %v", revs, err)
+	}
+
+	if id, rerr := resolveRevision(mgr, "2024-01-01", "2"); rerr != nil || id != revs[1].ID {
+		t.Errorf("resolveRevision(2) = %q, %v; want %q", id, rerr, revs[1].ID)
+	}
+	if id, rerr := resolveRevision(mgr, "2024-01-01", revs[0].ID); rerr != nil || id != revs[0].ID {
+		t.Errorf("resolveRevision(id) = %q, %v", id, rerr)
+	}
+	if _, rerr := resolveRevision(mgr, "2024-01-01", "3"); rerr == nil {
+		t.Error("expected out-of-range error")
+	}
+}
+
+func TestLoadSummariesFromStore(t *testing.T) {
+	store := scratchpad.NewMemoryStore()
+	for _, sp := range []*scratchpad.Scratchpad{
+		{Date: "2024-01-01", Content: "# first\n\nbody"},
+		{Date: "2024-01-03", Content: "third", AppliedTemplates: []string{"tasks"}},
+	} {
+		if err := store.Save(sp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	days, err := loadSummaries(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days.dates) != 2 || days.dates[0] != "2024-01-03" {
+		t.Errorf("dates = %v, want descending", days.dates)
+	}
+	if days.previews["2024-01-01"] != "first" {
+		t.Errorf("previews = %v", days.previews)
+	}
+	if len(days.applied["2024-01-03"]) != 1 {
+		t.Errorf("applied = %v", days.applied)
+	}
+	// A store that cannot summarize has its days loaded instead.
+	plain, err := loadSummaries(struct{ scratchpad.Store }{store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(plain.dates, days.dates) || plain.previews["2024-01-01"] != "first" || len(plain.applied["2024-01-03"]) != 1 {
+		t.Errorf("listing without Summaries = %+v, want %+v", plain, days)
+	}
+
+	if err := makeSaver(store)("2024-01-01", "edited"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := makeLoader(store)("2024-01-01"); got != "edited" {
+		t.Errorf("loader after saver = %q", got)
+	}
+}
+
+func TestMigrateWritesOnlyAfterConfirmation(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	legacy := `{"date": "2023-05-01", "content": "old"}`
+	path := filepath.Join(home, "2023-05-01.json")
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(input string, args ...string) string {
+		t.Helper()
+		migrateDryRun, migrateYes = false, false
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetIn(strings.NewReader(input))
+		rootCmd.SetArgs(append([]string{"migrate"}, args...))
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	if out := run("", "--dry-run"); !strings.Contains(out, "2023-05-01") {
+		t.Errorf("dry run output = %q", out)
+	}
+	run("n\n")
+	if data, _ := os.ReadFile(path); string(data) != legacy {
+		t.Fatal("file rewritten without confirmation")
+	}
+	run("y\n")
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"schema_version"`) {
+		t.Errorf("file not upgraded after confirmation:\n%s", data)
+	}
+}
+
+func TestEncryptAndDecryptStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	t.Setenv(envPassphrase, "correct horse")
+	t.Setenv(vault.EnvAgentSocket, filepath.Join(home, "no-agent.sock"))
+	path := filepath.Join(home, "2023-05-01.json")
+	if err := os.WriteFile(path, []byte(`{"date": "2023-05-01", "content": "private"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+		encryptKeyFile = ""
+		rootCmd.SetOut(io.Discard)
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("encrypt")
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "private") {
+		t.Fatalf("content still readable after encrypt:\n%s", data)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp, err := mgr.GetByDate("2023-05-01"); err != nil || sp.Content != "private" {
+		t.Errorf("unlocked read = %+v, %v", sp, err)
+	}
+
+	t.Setenv(envPassphrase, "wrong horse")
+	if _, err := openManager(loadConfig(), true); err == nil {
+		t.Error("expected error for a wrong passphrase")
+	}
+
+	t.Setenv(envPassphrase, "correct horse")
+	run("decrypt")
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "private") {
+		t.Errorf("content not restored after decrypt:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(home, vault.FileName)); err == nil {
+		t.Error("vault parameters left behind after decrypt")
+	}
+}
+
+func TestUnlockVaultUsesTheAgentOnlyWhenEnabled(t *testing.T) {
+	// Unix socket paths are short; t.TempDir can exceed the limit.
+	dir, err := os.MkdirTemp("", "sp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := vault.Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- vault.NewAgent(time.Hour).Serve(l) }()
+	t.Cleanup(func() {
+		l.Close()
+		<-done
+	})
+	t.Setenv(vault.EnvAgentSocket, socket)
+	t.Setenv(envPassphrase, "correct horse")
+
+	params, err := vault.NewParams(vault.KDFPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Iterations = 1000
+	key, err := params.DeriveKey([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Seal(key); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	if _, err := unlockVault(params, cfg, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vault.AgentKey(socket, params.ID()); err == nil {
+		t.Error("the key was handed to the agent with the agent off")
+	}
+
+	cfg.Encryption.Agent = true
+	if _, err := unlockVault(params, cfg, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := vault.AgentKey(socket, params.ID()); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("agent key = %x, %v", got, err)
+	}
+
+	// Without the agent or $SP_PASSPHRASE, a caller that may not prompt
+	// is refused rather than left reading the terminal.
+	cfg.Encryption.Agent = false
+	t.Setenv(envPassphrase, "")
+	if _, err := unlockVault(params, cfg, dir, false); err == nil || !strings.Contains(err.Error(), "sp agent") {
+		t.Errorf("unlockVault without a prompt = %v", err)
+	}
+}
+
+func TestGitStorageCommitsSaves(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte("[storage.git]\nenabled = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := makeSaver(mgr)("2024-01-01", "versioned"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("git", "-C", home, "log", "--format=%s", "--name-only").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log: %v: %s", err, out)
+	}
+	if !strings.Contains(string(out), "Update 2024-01-01\n\n2024-01-01.json") {
+		t.Errorf("git log:\n%s", out)
+	}
+	if strings.Contains(string(out), "config.toml") {
+		t.Errorf("config committed:\n%s", out)
+	}
+}
+
+func TestSyncMergesPagesChangedOnBothSides(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	config := fmt.Sprintf("[storage.git]\nenabled = true\nurl = %q\n", bare)
+	laptop, desktop := t.TempDir(), t.TempDir()
+	for _, home := range []string{laptop, desktop} {
+		if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func(home string) string {
+		t.Helper()
+		t.Setenv("SP_HOME", home)
+		var out strings.Builder
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"sync"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	savePage := func(home, content string) {
+		t.Helper()
+		t.Setenv("SP_HOME", home)
+		mgr, err := openManager(loadConfig(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := makePageSaver(mgr)("Runbook", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savePage(laptop, "restart the service\n")
+	sync(laptop)
+	sync(desktop)
+	savePage(laptop, "restart the service twice\n")
+	sync(laptop)
+	savePage(desktop, "page the oncall\n")
+	out := sync(desktop)
+	if !strings.Contains(out, "Changed on both sides: pages/runbook.json. Both versions are kept in the page") {
+		t.Errorf("sync output:\n%s", out)
+	}
+
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := mgr.GetPage("runbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<<<<<<< this machine\npage the oncall\n=======\nrestart the service twice\n>>>>>>> remote\n"
+	if page.Content != want || page.Name != "Runbook" {
+		t.Errorf("merged page %q = %q, want %q", page.Name, page.Content, want)
+	}
+}
+
+func TestConflictsResolveTakesOneSide(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(home, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("2024-01-01.json", `{"date": "2024-01-01", "content": "mine\n"}`)
+	write("2024-01-01.sync-conflict-20240101-101112-ABC.json",
+		`{"date": "2024-01-01", "content": "theirs\n", "applied_templates": ["standup"]}`)
+
+	var out strings.Builder
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"conflicts"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Syncthing") || !strings.Contains(out.String(), "(no common revision)") {
+		t.Errorf("conflicts listing:\n%s", out.String())
+	}
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetArgs([]string{"conflicts", "resolve", "2024-01-01", "--take", "remote"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	conflictsTake = "merged"
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := mgr.GetByDate("2024-01-01")
+	if err != nil || sp.Content != "theirs\n" || len(sp.AppliedTemplates) != 1 {
+		t.Errorf("resolved day = %+v, %v", sp, err)
+	}
+	if copies, _ := mgr.ConflictCopies(); len(copies) != 0 {
+		t.Errorf("copies left = %+v", copies)
+	}
+}
+
+func TestWorkspaceSelection(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	t.Setenv(config.EnvWorkspace, "")
+	body := "[workspaces.work]\ndir = \"work\"\n"
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { workspaceFlag = "" }()
+
+	workspaceFlag = "work"
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Dir() != filepath.Join(home, "work") {
+		t.Errorf("-w work stores in %q", mgr.Dir())
+	}
+
+	workspaceFlag = ""
+	t.Setenv(config.EnvWorkspace, "work")
+	if mgr, err = openManager(loadConfig(), true); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Dir() != filepath.Join(home, "work") {
+		t.Errorf("$SP_WORKSPACE stores in %q", mgr.Dir())
+	}
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetArgs([]string{"-w", "play", "conflicts"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), `unknown workspace "play"`) {
+		t.Errorf("unknown workspace: %v", err)
+	}
+}
+
+func TestInitCreatesProjectStore(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	project := t.TempDir()
+	sub := filepath.Join(project, "internal", "pkg")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetArgs([]string{"init", project})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(project, ".sp", ".gitignore")); err != nil {
+		t.Errorf("project store lacks a .gitignore: %v", err)
+	}
+	rootCmd.SetArgs([]string{"init", project})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("second init should fail")
+	}
+
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Dir() != filepath.Join(project, ".sp") {
+		t.Errorf("inside the project sp stores in %q", mgr.Dir())
+	}
+
+	globalFlag = true
+	defer func() { globalFlag = false }()
+	if mgr, err = openManager(loadConfig(), true); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Dir() != os.Getenv("SP_HOME") {
+		t.Errorf("--global stores in %q", mgr.Dir())
+	}
+}
+
+func TestAttachAddsFileToDay(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	src := filepath.Join(t.TempDir(), "trace.log")
+	if err := os.WriteFile(src, []byte("panic: boom\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { attachDate = "" }()
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetArgs([]string{"attach", src, "--date", "2024-01-02"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments, err := makeAttachmentLoader(mgr)("2024-01-02")
+	if err != nil || len(attachments) != 1 || attachments[0].Name != "trace.log" || attachments[0].Size != 12 {
+		t.Errorf("attachments = %+v, %v", attachments, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(home, "attachments", "2024-01-02", "trace.log")); err != nil || string(data) != "panic: boom\n" {
+		t.Errorf("copy = %q, %v", data, err)
+	}
+}
+
+func TestPageListShowsSavedPages(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := makePageSaver(mgr)("Oncall Runbook", "# Runbook\n"); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := makePageLoader(mgr)("oncall-runbook"); err != nil || content != "# Runbook\n" {
+		t.Errorf("loaded page = %q, %v", content, err)
+	}
+
+	var out strings.Builder
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"page", "list"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Oncall Runbook\n" {
+		t.Errorf("page list = %q", out.String())
+	}
+	if dates, _ := mgr.ListDates(); len(dates) != 0 {
+		t.Errorf("a page should not create a day: %v", dates)
+	}
+}
+
+func TestAddAppendsFromArgsAndStdin(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	defer func() { addDate, addSection, addTodo = "", "", false }()
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetArgs([]string{"add", "--date", "2024-01-02", "standup", "moved"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetIn(strings.NewReader("renew the certificate\n"))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"add", "--date", "2024-01-02", "--todo", "--section", "Todo"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day, err := mgr.Lookup("2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(day.Content, "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[0], "- ") || !strings.HasSuffix(lines[0], " standup moved") ||
+		lines[2] != "## Todo" || !strings.HasPrefix(lines[4], "- [ ] ") || !strings.HasSuffix(lines[4], " renew the certificate") {
+		t.Errorf("content = %q", day.Content)
+	}
+}
+
+func TestLogRecordsCommandOutputAndStatus(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not on PATH")
+	}
+	logged, err := runLogged([]string{goBin, "env", "GOOS"}, io.Discard, time.Minute)
+	if err != nil || logged.status != "exit 0" || strings.TrimSpace(logged.output) == "" {
+		t.Errorf("go env = %+v, %v", logged, err)
+	}
+	if logged, err = runLogged([]string{goBin, "no-such-command"}, io.Discard, time.Minute); err != nil || logged.status != "exit 2" {
+		t.Errorf("failing command = %+v, %v", logged, err)
+	}
+	if _, err := runLogged([]string{filepath.Join(t.TempDir(), "missing")}, io.Discard, time.Minute); err == nil {
+		t.Error("a command that cannot start should be an error")
+	}
+}
+
+func TestLogFenceAppendsPipedOutput(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	defer func() { logFence, logDate = false, "" }()
+	var stdout, stderr strings.Builder
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	defer rootCmd.SetErr(nil)
+	piped := "NAME   READY   \nweb-1  1/1\n"
+	rootCmd.SetIn(strings.NewReader(piped))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"log", "--fence", "--date", "2024-01-02", "kubectl", "get", "pods"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	// Standard output passes the input through untouched, so sp log can
+	// sit in the middle of a pipeline.
+	if stdout.String() != piped || stderr.String() != "Logged to 2024-01-02.\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day, err := mgr.Lookup("2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := " `$ kubectl get pods`\n\n  ```\n  NAME   READY   \n  web-1  1/1\n  ```\n"
+	if !strings.HasPrefix(day.Content, "- ") || !strings.HasSuffix(day.Content, want) {
+		t.Errorf("content = %q", day.Content)
+	}
+}
+
+func TestLoggedOutputMarkdown(t *testing.T) {
+	logged := &loggedOutput{command: shellJoin([]string{"echo", "it's `done`"}), output: "```\n", status: "exit 0"}
+	want := "`` $ echo 'it'\\''s `done`' `` · exit 0\n\n````\n```\n````"
+	if got := logged.markdown(); got != want {
+		t.Errorf("markdown() =\n%s\nwant\n%s", got, want)
+	}
+	if got := (&loggedOutput{}).markdown(); got != "no output" {
+		t.Errorf("empty markdown() = %q", got)
+	}
+}
+
+func TestShowPrintsDaysAndRanges(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	defer func() { showRaw, showRender, showJSON = false, false, false }()
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for date, content := range map[string]string{
+		"2024-03-01": "- [ ] TODO call the bank\n",
+		"2024-03-03": "Quiet.\n",
+		"2024-03-09": "Later.\n",
+	} {
+		if err := mgr.Save(&scratchpad.Scratchpad{Date: date, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	show := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs(append([]string{"show"}, args...))
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+	if out, err := show("2024-03-01"); err != nil || out != "- [ ] TODO call the bank\n" {
+		t.Errorf("show day = %q, %v", out, err)
+	}
+	if out, err := show("2024-02-28..2024-03-05"); err != nil || out != "# 2024-03-01\n\n- [ ] TODO call the bank\n\n# 2024-03-03\n\nQuiet.\n" {
+		t.Errorf("show range = %q, %v", out, err)
+	}
+	if _, err := show("2024-03-02"); err == nil {
+		t.Error("showing a day with nothing saved should fail")
+	}
+	if _, err := show("2024-03-05..2024-03-01"); err == nil {
+		t.Error("a backwards range should be refused")
+	}
+	out, err := show("--json", "2024-03-01..2024-03-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []scratchpad.Scratchpad
+	if err := json.Unmarshal([]byte(out), &days); err != nil || len(days) != 2 || days[1].Content != "Quiet.\n" {
+		t.Errorf("show --json = %q, %v", out, err)
+	}
+}
+
+func TestParseDayWord(t *testing.T) {
+	today := clock.Today()
+	for arg, want := range map[string]string{
+		"today":      today.Format("2006-01-02"),
+		"Yesterday":  today.AddDate(0, 0, -1).Format("2006-01-02"),
+		"tomorrow":   today.AddDate(0, 0, 1).Format("2006-01-02"),
+		"2024-02-29": "2024-02-29",
+	} {
+		if got, err := parseDayWord(arg); err != nil || got != want {
+			t.Errorf("parseDayWord(%q) = %q, %v; want %q", arg, got, err, want)
+		}
+	}
+	if _, err := parseDayWord("last week"); err == nil {
+		t.Error("an unknown word should be refused")
+	}
+}
+
+func TestSearchPrintsMatchesUnderDates(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	defer func() { searchContext, searchSince, searchJSON = 2, "", false }()
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for date, content := range map[string]string{
+		"2023-11-02": "Postgres vacuum ran long.\n",
+		"2024-01-15": "# Notes\n\nupgrade postgres\nthen redis\n",
+	} {
+		if err := mgr.Save(&scratchpad.Scratchpad{Date: date, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"search", "-C", "1", "postgres"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := "2023-11-02\n1:Postgres vacuum ran long.\n\n2024-01-15\n2-\n3:upgrade postgres\n4-then redis\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"search", "--json", "--since", "2024-01-01", "postgres"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var results []scratchpad.SearchResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil || len(results) != 1 || results[0].Date != "2024-01-15" {
+		t.Errorf("--json = %s, %v", out.String(), err)
+	}
+}
+
+func TestSearchPatternCase(t *testing.T) {
+	tests := []struct {
+		pattern                          string
+		fixed, ignoreCase, caseSensitive bool
+		line                             string
+		want                             bool
+	}{
+		{pattern: "postgres", line: "Postgres", want: true},
+		{pattern: "Postgres", line: "postgres", want: false},
+		{pattern: "Postgres", ignoreCase: true, line: "POSTGRES", want: true},
+		{pattern: "postgres", caseSensitive: true, line: "Postgres", want: false},
+		{pattern: "[[", fixed: true, line: "see [[runbook]]", want: true},
+	}
+	for _, tt := range tests {
+		re, err := searchPattern(tt.pattern, tt.fixed, tt.ignoreCase, tt.caseSensitive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := re.MatchString(tt.line); got != tt.want {
+			t.Errorf("searchPattern(%+v) on %q = %v, want %v", tt, tt.line, got, tt.want)
+		}
+	}
+	if _, err := searchPattern("[[", false, false, false); err == nil {
+		t.Error("an invalid pattern should be refused")
+	}
+}
+
+func TestSearchWordsUsesTheIndex(t *testing.T) {
+	t.Setenv("SP_HOME", t.TempDir())
+	defer func() { searchWords, searchContext = false, 2 }()
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for date, content := range map[string]string{
+		"2024-01-15": "The release train leaves Monday.\nUnrelated line.\n",
+		"2024-01-16": "Train the release team.\n",
+	} {
+		if err := mgr.Save(&scratchpad.Scratchpad{Date: date, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"reindex"})
+	if err := rootCmd.Execute(); err != nil || out.String() != "Indexed 2 days.\n" {
+		t.Fatalf("reindex = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"search", "--words", "-C", "0", `"Release Train"`})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-01-15\n1:The release train leaves Monday.\n"; out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestSearchReportsSkippedDays(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SP_HOME", home)
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Save(&scratchpad.Scratchpad{Date: "2024-01-15", Content: "deploy notes\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "2024-01-16.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	defer rootCmd.SetErr(nil)
+	rootCmd.SetArgs([]string{"search", "deploy"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "2024-01-15\n") {
+		t.Errorf("output = %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "Skipped 2024-01-16.json: failed to parse") ||
+		!strings.Contains(errOut.String(), "sp doctor") {
+		t.Errorf("stderr = %q", errOut.String())
+	}
+}
+
+func TestWarnLegacyReferencesChecksEveryConfig(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "a", "config.toml")
+	stale := filepath.Join(dir, "b", "config.toml")
+	for path, data := range map[string]string{clean: "[ui]\n", stale: "[encryption]\nkey_file = \"~/.sp/sp.key\"\n"} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var errOut bytes.Buffer
+	rootCmd.SetErr(&errOut)
+	defer rootCmd.SetErr(nil)
+	warnLegacyReferences(rootCmd, []paths.Move{{To: clean}, {To: stale}})
+	if !strings.Contains(errOut.String(), stale) || strings.Contains(errOut.String(), clean) {
+		t.Errorf("warnings = %q, want one for %s only", errOut.String(), stale)
+	}
+}
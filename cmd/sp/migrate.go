@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateDryRun bool
+	migrateYes    bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade day files written under an older schema",
+	Long: fmt.Sprintf(`List the day files written under an older schema (this build writes
+version %d) and, after confirmation, rewrite them in place. Older files are
+always readable — they are upgraded in memory on load — so migrating only
+saves the work on every read and keeps files consistent for other tools.
+
+Content and timestamps are unchanged. When a day has no history yet, the
+original file is kept as its first revision.`, scratchpad.SchemaVersion),
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Report the files that would change without writing")
+	migrateCmd.Flags().BoolVarP(&migrateYes, "yes", "y", false, "Write upgraded files without asking")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+	pending, err := mgr.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	var upgradable []string
+	for _, p := range pending {
+		if p.Err != nil {
+			fmt.Fprintf(out, "%s  skipped: %v\n", p.Date, p.Err)
+			continue
+		}
+		fmt.Fprintf(out, "%s  v%d → v%d  %s\n", p.Date, p.From, scratchpad.SchemaVersion, strings.Join(p.Steps, "; "))
+		upgradable = append(upgradable, p.Date)
+	}
+	if len(upgradable) == 0 {
+		fmt.Fprintln(out, "All day files are up to date.")
+		return nil
+	}
+	fmt.Fprintf(out, "%d files need upgrading.\n", len(upgradable))
+	if migrateDryRun {
+		fmt.Fprintln(out, "Dry run: nothing written.")
+		return nil
+	}
+	if !migrateYes && !confirm(cmd.InOrStdin(), out, "Write upgraded files?") {
+		fmt.Fprintln(out, "Nothing written.")
+		return nil
+	}
+	for i, date := range upgradable {
+		if err := mgr.MigrateDay(date); err != nil {
+			return fmt.Errorf("migrate %s (after %d upgraded): %w", date, i, err)
+		}
+	}
+	fmt.Fprintf(out, "Upgraded %d files.\n", len(upgradable))
+	return commitStore(mgr, cfg, fmt.Sprintf("Upgrade day files to schema v%d", scratchpad.SchemaVersion))
+}
+
+// confirm asks a yes/no question on in, defaulting to no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
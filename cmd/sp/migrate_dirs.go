@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pders01/sp/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+var migrateDirsDryRun bool
+
+var migrateDirsCmd = &cobra.Command{
+	Use:   "migrate-dirs",
+	Short: "Move ~/.sp into the XDG config and data directories",
+	Long: `Split the legacy ~/.sp directory into $XDG_CONFIG_HOME/sp (config.toml
+and templates/) and $XDG_DATA_HOME/sp (day files and history). The spec
+defaults ~/.config and ~/.local/share are used when the variables are unset.
+
+Nothing is moved if any destination already exists. Entries are renamed
+when possible and copied across filesystems otherwise; a source is only
+removed after its copy completes.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateDirs,
+}
+
+func init() {
+	migrateDirsCmd.Flags().BoolVar(&migrateDirsDryRun, "dry-run", false, "Print the planned moves without changing anything")
+	rootCmd.AddCommand(migrateDirsCmd)
+}
+
+func runMigrateDirs(cmd *cobra.Command, _ []string) error {
+	moves, err := paths.PlanLegacyMigration()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(moves) == 0 {
+		fmt.Fprintln(out, "Nothing to migrate: ~/.sp is empty.")
+		return nil
+	}
+	for _, move := range moves {
+		fmt.Fprintf(out, "%s → %s\n", move.From, move.To)
+	}
+	if migrateDirsDryRun {
+		fmt.Fprintln(out, "Dry run: nothing moved.")
+		return nil
+	}
+	if err := paths.ApplyMoves(moves); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Moved %d entries.\n", len(moves))
+	warnLegacyReferences(cmd, moves)
+	return nil
+}
+
+// warnLegacyReferences points out absolute ~/.sp paths left in the moved
+// config, which no longer resolve after migration.
+func warnLegacyReferences(cmd *cobra.Command, moves []paths.Move) {
+	for _, move := range moves {
+		if filepath.Base(move.To) != "config.toml" {
+			continue
+		}
+		data, err := os.ReadFile(move.To)
+		if err != nil || !strings.Contains(string(data), "~/.sp/") {
+			continue
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(),
+			"sp: %s still references ~/.sp/; update those paths (relative paths resolve next to config.toml)\n",
+			move.To)
+	}
+}
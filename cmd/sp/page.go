@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pders01/sp/internal/editor"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var pageCmd = &cobra.Command{
+	Use:   "page",
+	Short: "Edit and list named pages that belong to no day",
+	Long: `Named pages hold notes that outlive a day, such as runbooks, reference
+lists and project notes. They are kept under pages/ in the storage
+directory, out of the calendar and the notebook's days. Link one from any
+day with [[page name]]; press p in the TUI to open pages.`,
+}
+
+var pageEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Open a page in $EDITOR, creating it on first save",
+	Long: `Open the named page in $EDITOR. Names are matched regardless of case and
+punctuation, so "Oncall Runbook" and oncall-runbook are the same page.
+The words of an unquoted name are joined with spaces.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPageEdit,
+}
+
+var pageListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the saved pages",
+	Args:  cobra.NoArgs,
+	RunE:  runPageList,
+}
+
+func init() {
+	pageCmd.AddCommand(pageEditCmd, pageListCmd)
+	rootCmd.AddCommand(pageCmd)
+}
+
+func runPageEdit(cmd *cobra.Command, args []string) error {
+	name := strings.Join(args, " ")
+	if scratchpad.PageSlug(name) == "" {
+		return fmt.Errorf("invalid page name %q: it needs a letter or digit", name)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	page, err := mgr.GetPage(name)
+	if err != nil {
+		return err
+	}
+	ed, err := editor.NewEditor()
+	if err != nil {
+		return fmt.Errorf("failed to initialize editor: %w", err)
+	}
+	content, err := ed.Edit(page.Content, scratchpad.PageSlug(name)+".md")
+	if err != nil {
+		return fmt.Errorf("failed to edit page: %w", err)
+	}
+	if content == page.Content {
+		return nil
+	}
+	page.Content = content
+	if err := mgr.SavePage(page); err != nil {
+		return fmt.Errorf("failed to save page: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved page %s.\n", page.Name)
+	return nil
+}
+
+func runPageList(cmd *cobra.Command, _ []string) error {
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	names, err := mgr.ListPages()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(names) == 0 {
+		fmt.Fprintln(out, "No pages yet. Create one with 'sp page edit <name>'.")
+		return nil
+	}
+	for _, name := range names {
+		fmt.Fprintln(out, name)
+	}
+	return nil
+}
+
+// makePageLoader reads a page's content for the notebook.
+func makePageLoader(mgr *scratchpad.Manager) tui.PageLoader {
+	return func(name string) (string, error) {
+		page, err := mgr.GetPage(name)
+		if err != nil {
+			return "", err
+		}
+		return page.Content, nil
+	}
+}
+
+// makePageSaver stores a page edited in the notebook.
+func makePageSaver(mgr *scratchpad.Manager) tui.PageSaver {
+	return func(name, content string) error {
+		page, err := mgr.GetPage(name)
+		if err != nil {
+			return err
+		}
+		page.Content = content
+		return mgr.SavePage(page)
+	}
+}
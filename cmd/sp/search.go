@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchIgnoreCase bool
+	searchCaseSense  bool
+	searchFixed      bool
+	searchWords      bool
+	searchContext    int
+	searchSince      string
+	searchUntil      string
+	searchJSON       bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <pattern>",
+	Short: "Find lines matching a pattern across all days",
+	Long: `Search every saved day for lines matching a regular expression (Go RE2
+syntax) and print them under the day's date, with line numbers and a
+few lines of context: ":" marks a matching line, "-" a context line and
+"--" a gap between them.
+
+The search ignores case when the pattern is all lower case, like
+ripgrep's smart case; -i ignores case always and --case-sensitive never.
+--since and --until take a date (YYYY-MM-DD, today, yesterday or
+tomorrow) and limit the days searched, inclusive. --json prints the
+matches for tooling. A day that cannot be read is skipped and listed on
+stderr.
+
+With --words the pattern is a word query instead, answered from the
+full-text index kept beside the day files: every word, "quoted phrase"
+and prefix* must appear in a day, regardless of case and punctuation,
+and the lines holding any of them are shown. The index follows saves
+and catches up with files changed by other tools; sp reindex rebuilds
+it from scratch.`,
+	Example: `  sp search postgres
+  sp search -C 0 --since 2024-01-01 'deploy(ed)? to prod'
+  sp search -F '[[' --json
+  sp search --words '"release train" deploy*'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	searchCmd.Flags().BoolVarP(&searchIgnoreCase, "ignore-case", "i", false, "Ignore case")
+	searchCmd.Flags().BoolVar(&searchCaseSense, "case-sensitive", false, "Match case even in an all lower case pattern")
+	searchCmd.Flags().BoolVarP(&searchFixed, "fixed-strings", "F", false, "Treat the pattern as text, not a regular expression")
+	searchCmd.Flags().BoolVar(&searchWords, "words", false, "Match words, phrases and prefixes from the full-text index")
+	searchCmd.Flags().IntVarP(&searchContext, "context", "C", 2, "Show this many lines around each match")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Only search days on or after this date")
+	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Only search days on or before this date")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Print the matches as JSON")
+	searchCmd.MarkFlagsMutuallyExclusive("ignore-case", "case-sensitive")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "fixed-strings")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "case-sensitive")
+	rootCmd.AddCommand(searchCmd, reindexCmd)
+}
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the full-text index used by sp search --words",
+	Long: `Rebuild the full-text index from the day files. The index is kept up to
+date on its own; rebuild it if it was lost or damaged, or to spare the
+next search the work after a large import.`,
+	Args: cobra.NoArgs,
+	RunE: runReindex,
+}
+
+func runReindex(cmd *cobra.Command, _ []string) error {
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	n, skipped, err := mgr.Reindex()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Indexed %d days.\n", n)
+	reportSkipped(cmd.ErrOrStderr(), skipped)
+	return nil
+}
+
+// reportSkipped lists the days a search or reindex could not read.
+func reportSkipped(w io.Writer, skipped []scratchpad.SkippedDay) {
+	for _, s := range skipped {
+		path := s.Path
+		if path == "" {
+			path = s.Date
+		}
+		fmt.Fprintf(w, "Skipped %s: %v\n", path, s.Err)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintln(w, "Run 'sp doctor' to check the skipped days.")
+	}
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	var pattern scratchpad.LineMatcher
+	var err error
+	if searchWords {
+		pattern, err = scratchpad.ParseWordQuery(args[0])
+	} else {
+		pattern, err = searchPattern(args[0], searchFixed, searchIgnoreCase, searchCaseSense)
+	}
+	if err != nil {
+		return err
+	}
+	query := scratchpad.SearchQuery{Pattern: pattern, Context: max(searchContext, 0)}
+	if searchSince != "" {
+		if query.Since, err = parseDayWord(searchSince); err != nil {
+			return err
+		}
+	}
+	if searchUntil != "" {
+		if query.Until, err = parseDayWord(searchUntil); err != nil {
+			return err
+		}
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	results, skipped, err := mgr.Search(query)
+	if err != nil {
+		return err
+	}
+	defer reportSkipped(cmd.ErrOrStderr(), skipped)
+
+	out := cmd.OutOrStdout()
+	if searchJSON {
+		if results == nil {
+			results = []scratchpad.SearchResult{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No matches.")
+		return nil
+	}
+	for i, result := range results {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, result.Date)
+		for j, line := range result.Lines {
+			if j > 0 && line.Number > result.Lines[j-1].Number+1 {
+				fmt.Fprintln(out, "--")
+			}
+			sep := "-"
+			if line.Match {
+				sep = ":"
+			}
+			fmt.Fprintf(out, "%d%s%s\n", line.Number, sep, line.Text)
+		}
+	}
+	return nil
+}
+
+// searchPattern compiles the pattern of sp search. Without either case
+// flag, a pattern with no upper case letters ignores case.
+func searchPattern(pattern string, fixed, ignoreCase, caseSensitive bool) (*regexp.Regexp, error) {
+	expr := pattern
+	if fixed {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase || (!caseSensitive && !strings.ContainsFunc(pattern, unicode.IsUpper)) {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/pders01/sp/internal/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	showRaw    bool
+	showRender bool
+	showJSON   bool
+	showWidth  int
+	showTheme  string
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [date|from..to]",
+	Short: "Print a day, or the days in a range, to standard output",
+	Long: `Print today's page, or the day named by the argument, without opening
+the TUI or an editor. Days are written as YYYY-MM-DD or as today,
+yesterday or tomorrow. A range from..to prints every saved day between
+the two, inclusive, each under a "# YYYY-MM-DD" heading; days with
+nothing saved are left out.
+
+Output is rendered with glamour when standard output is a terminal and
+plain Markdown otherwise; --raw and --render pick one explicitly. --json
+prints the stored days instead, an object for one day and an array for a
+range.`,
+	Example: `  sp show yesterday | grep TODO
+  sp show --render --width 100 --theme light 2024-03-01
+  sp show --json 2024-03-01..2024-03-07`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runShow,
+}
+
+func init() {
+	showCmd.Flags().BoolVar(&showRaw, "raw", false, "Print the Markdown as stored")
+	showCmd.Flags().BoolVar(&showRender, "render", false, "Render the Markdown for the terminal")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "Print the stored days as JSON")
+	showCmd.Flags().IntVar(&showWidth, "width", 80, "Wrap rendered output at this many columns")
+	showCmd.Flags().StringVar(&showTheme, "theme", "", "Render style: auto, light or dark (default from ui.theme)")
+	showCmd.MarkFlagsMutuallyExclusive("raw", "render", "json")
+	rootCmd.AddCommand(showCmd)
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	spec := "today"
+	if len(args) == 1 {
+		spec = args[0]
+	}
+	from, to, isRange, err := parseDaySpan(spec)
+	if err != nil {
+		return err
+	}
+	cfg := loadConfig()
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+
+	var days []*scratchpad.Scratchpad
+	if isRange {
+		if days, err = lookupRange(mgr, from, to); err != nil {
+			return err
+		}
+	} else {
+		day, err := mgr.Lookup(from)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("nothing saved for %s", from)
+		}
+		if err != nil {
+			return err
+		}
+		days = append(days, day)
+	}
+
+	out := cmd.OutOrStdout()
+	if showJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if isRange {
+			return enc.Encode(days)
+		}
+		return enc.Encode(days[0])
+	}
+
+	text := showMarkdown(days, isRange)
+	if !showRender && (showRaw || !isTerminal(out)) {
+		_, err := io.WriteString(out, text)
+		return err
+	}
+	theme := showTheme
+	if theme == "" {
+		theme = cfg.UI.Theme
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(tui.ResolveGlamourStyle(theme)),
+		glamour.WithWordWrap(max(showWidth, 1)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create renderer: %w", err)
+	}
+	rendered, err := renderer.Render(text)
+	if err != nil {
+		return fmt.Errorf("failed to render: %w", err)
+	}
+	_, err = io.WriteString(out, rendered)
+	return err
+}
+
+// parseDaySpan parses the argument of sp show: one day, or two joined by
+// "..". Each day is YYYY-MM-DD or today, yesterday or tomorrow.
+func parseDaySpan(spec string) (from, to string, isRange bool, err error) {
+	first, last, isRange := strings.Cut(spec, "..")
+	if from, err = parseDayWord(first); err != nil {
+		return "", "", false, err
+	}
+	if !isRange {
+		return from, from, false, nil
+	}
+	if to, err = parseDayWord(last); err != nil {
+		return "", "", false, err
+	}
+	if to < from {
+		return "", "", false, fmt.Errorf("invalid range %q: %s is before %s", spec, to, from)
+	}
+	return from, to, true, nil
+}
+
+// parseDayWord is parseDateArg that also takes today, yesterday and
+// tomorrow, counted from the configured start of the day.
+func parseDayWord(arg string) (string, error) {
+	offset := map[string]int{"yesterday": -1, "today": 0, "tomorrow": 1}
+	if n, ok := offset[strings.ToLower(strings.TrimSpace(arg))]; ok {
+		return clock.Today().AddDate(0, 0, n).Format("2006-01-02"), nil
+	}
+	return parseDateArg(arg)
+}
+
+// lookupRange returns the saved days from from to to, inclusive, oldest
+// first.
+func lookupRange(mgr *scratchpad.Manager, from, to string) ([]*scratchpad.Scratchpad, error) {
+	dates, err := mgr.ListDates()
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(dates)
+	days := []*scratchpad.Scratchpad{}
+	for _, date := range dates {
+		if date < from || date > to {
+			continue
+		}
+		day, err := mgr.Lookup(date)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// showMarkdown joins days for printing. Days of a range each get a date
+// heading so they can be told apart.
+func showMarkdown(days []*scratchpad.Scratchpad, isRange bool) string {
+	var b strings.Builder
+	for i, day := range days {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if isRange {
+			b.WriteString("# " + day.Date + "\n\n")
+		}
+		if content := strings.TrimRight(day.Content, "\n"); content != "" {
+			b.WriteString(content + "\n")
+		}
+	}
+	return b.String()
+}
+
+// isTerminal reports whether w is a terminal, as opposed to a pipe, a
+// file or a test buffer.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pders01/sp/internal/config"
+	"github.com/pders01/sp/internal/gitsync"
+	"github.com/pders01/sp/internal/scratchpad"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Pull and push the git-backed store",
+	Long: `Commit anything changed outside sp, rebase local commits onto the
+configured remote ([storage.git] remote, default "origin") and push.
+
+A day changed on both machines is not left half-rebased: both versions are
+kept in the day between conflict markers and flagged in the calendar and
+notebook until you edit one away.`,
+	Args: cobra.NoArgs,
+	RunE: runSync,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
+
+// enableGit commits each change to a day when [storage.git] is on.
+func enableGit(mgr *scratchpad.Manager, cfg *config.Config) error {
+	if !cfg.Storage.Git.Enabled {
+		return nil
+	}
+	repo, err := gitsync.Open(mgr.Dir())
+	if err != nil {
+		return fmt.Errorf("storage.git: %w", err)
+	}
+	mgr.SetChangeHook(func(change scratchpad.Change) error {
+		return repo.Commit(change.Summary, change.Paths...)
+	})
+	return nil
+}
+
+// commitStore records a change made to many days at once, such as a
+// conversion, when [storage.git] is on.
+func commitStore(mgr *scratchpad.Manager, cfg *config.Config, message string) error {
+	if !cfg.Storage.Git.Enabled {
+		return nil
+	}
+	repo, err := gitsync.Open(mgr.Dir())
+	if err != nil {
+		return err
+	}
+	if _, err := repo.CommitAll(message); err != nil {
+		return fmt.Errorf("done, but failed to commit: %w", err)
+	}
+	return nil
+}
+
+func runSync(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig()
+	if !cfg.Storage.Git.Enabled {
+		return errors.New("git storage is off; set enabled = true under [storage.git]")
+	}
+	mgr, err := openManager(cfg, true)
+	if err != nil {
+		return err
+	}
+	repo, err := gitsync.Open(mgr.Dir())
+	if err != nil {
+		return err
+	}
+	result, err := repo.Sync(cfg.Storage.Git.Remote, cfg.Storage.Git.URL, mgr)
+	if err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Synced with %s/%s: pulled %d commits, pushed %d.\n",
+		result.Remote, result.Branch, result.Pulled, result.Pushed)
+	for _, path := range result.Conflicts {
+		switch {
+		case strings.HasPrefix(path, "attachments/"):
+			fmt.Fprintf(out, "Changed on both sides: %s. The remote version is kept beside it under a new name and linked from the day.\n", path)
+		case strings.HasPrefix(path, "pages/"):
+			fmt.Fprintf(out, "Changed on both sides: %s. Both versions are kept in the page; edit it to keep one.\n", path)
+		default:
+			fmt.Fprintf(out, "Changed on both sides: %s. Both versions are kept in the day; edit it to keep one.\n", path)
+		}
+	}
+	return nil
+}
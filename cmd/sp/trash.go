@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/pders01/sp/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trashEmptyOlderThan string
+	trashEmptyYes       bool
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <date>",
+	Short: "Move a day to the trash",
+	Long: `Move a day to the trash. Its revision history is kept; bring it back
+with 'sp trash restore <date>'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRm,
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore or permanently remove deleted days",
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List deleted days, most recently deleted first",
+	Args:  cobra.NoArgs,
+	RunE:  runTrashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <date>",
+	Short: "Put the most recently deleted copy of a day back",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTrashRestore,
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently remove days from the trash",
+	Long: `Permanently remove days from the trash. With --older-than (e.g. "30d",
+"2w", "12h") only days deleted longer ago than that are removed; without
+it the whole trash is emptied after confirmation.`,
+	Args: cobra.NoArgs,
+	RunE: runTrashEmpty,
+}
+
+func init() {
+	trashEmptyCmd.Flags().StringVar(&trashEmptyOlderThan, "older-than", "", "Only remove days deleted longer ago than this")
+	trashEmptyCmd.Flags().BoolVarP(&trashEmptyYes, "yes", "y", false, "Empty the whole trash without asking")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+	rootCmd.AddCommand(rmCmd, trashCmd)
+}
+
+func runRm(cmd *cobra.Command, args []string) error {
+	date, err := parseDateArg(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Delete(date); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no page saved for %s", date)
+		}
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Moved %s to the trash.\n", date)
+	return nil
+}
+
+func runTrashList(cmd *cobra.Command, _ []string) error {
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	trashed, err := mgr.Trash()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(trashed) == 0 {
+		fmt.Fprintln(out, "The trash is empty.")
+		return nil
+	}
+	for _, t := range trashed {
+		fmt.Fprintf(out, "%s  deleted %s\n", t.Date, t.Deleted.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func runTrashRestore(cmd *cobra.Command, args []string) error {
+	date, err := parseDateArg(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	if err := mgr.RestoreTrashed(date); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Restored %s from the trash.\n", date)
+	return nil
+}
+
+func runTrashEmpty(cmd *cobra.Command, _ []string) error {
+	olderThan, err := config.ParseDuration(trashEmptyOlderThan)
+	if err != nil {
+		return fmt.Errorf("--older-than: %w", err)
+	}
+	mgr, err := openManager(loadConfig(), true)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if olderThan == 0 && !trashEmptyYes &&
+		!confirm(cmd.InOrStdin(), out, "Permanently remove everything in the trash?") {
+		fmt.Fprintln(out, "Nothing removed.")
+		return nil
+	}
+	n, err := mgr.EmptyTrash(olderThan)
+	fmt.Fprintf(out, "Removed %d days from the trash.\n", n)
+	return err
+}
@@ -0,0 +1,97 @@
+// Package clock decides which day it is. A day may start later than
+// midnight, for people who work past it, and may be pinned to one time
+// zone, for people who travel.
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// A configured zone must load on systems without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// Boundary says when a day starts and in which zone.
+type Boundary struct {
+	// StartsAt is the wall-clock time of day the new day begins, between
+	// 0 (midnight) and 24h.
+	StartsAt time.Duration
+	// Location is the zone days are counted in; nil means the local zone.
+	Location *time.Location
+}
+
+// Parse builds a Boundary from the [day] settings: startsAt as "HH:MM"
+// (empty for midnight) and an IANA timezone name (empty for the local
+// zone).
+func Parse(startsAt, timezone string) (Boundary, error) {
+	var b Boundary
+	if startsAt != "" {
+		t, err := time.Parse("15:04", strings.TrimSpace(startsAt))
+		if err != nil {
+			return Boundary{}, fmt.Errorf("starts_at %q: want HH:MM", startsAt)
+		}
+		b.StartsAt = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Boundary{}, fmt.Errorf("timezone %q: %w", timezone, err)
+		}
+		b.Location = loc
+	}
+	return b, nil
+}
+
+func (b Boundary) location() *time.Location {
+	if b.Location == nil {
+		return time.Local
+	}
+	return b.Location
+}
+
+// Date returns midnight, in b's zone, of the day t falls on. The boundary
+// is compared with the wall clock, so a day starting at 04:00 still
+// starts at 04:00 on the days clocks change.
+func (b Boundary) Date(t time.Time) time.Time {
+	t = t.In(b.location())
+	year, month, day := t.Date()
+	sinceMidnight := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+	if sinceMidnight < b.StartsAt {
+		day--
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, b.location())
+}
+
+// Zone returns the name of the configured zone, or "" when days follow
+// the local zone.
+func (b Boundary) Zone() string {
+	if b.Location == nil {
+		return ""
+	}
+	return b.Location.String()
+}
+
+var current Boundary
+
+// Set makes b the boundary used by Today. sp calls it once at startup
+// with the configured [day] settings.
+func Set(b Boundary) { current = b }
+
+// Current returns the boundary set with Set; by default days start at
+// midnight in the local zone.
+func Current() Boundary { return current }
+
+// Today returns midnight of the current day under the configured
+// boundary.
+func Today() time.Time {
+	return current.Date(time.Now())
+}
+
+// Now returns the current time in the configured zone, for timestamps
+// written into a day.
+func Now() time.Time {
+	return time.Now().In(current.location())
+}
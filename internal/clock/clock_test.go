@@ -0,0 +1,111 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func mustBoundary(t *testing.T, startsAt, timezone string) Boundary {
+	t.Helper()
+	b, err := Parse(startsAt, timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	b := mustBoundary(t, "04:30", "Europe/Berlin")
+	if b.StartsAt != 4*time.Hour+30*time.Minute || b.Zone() != "Europe/Berlin" {
+		t.Errorf("Parse = %+v", b)
+	}
+	if b := mustBoundary(t, "", ""); b.StartsAt != 0 || b.Location != nil || b.Zone() != "" {
+		t.Errorf("empty settings = %+v, want midnight local", b)
+	}
+	for _, bad := range [][2]string{{"4am", ""}, {"25:00", ""}, {"", "Mars/Olympus"}} {
+		if _, err := Parse(bad[0], bad[1]); err == nil {
+			t.Errorf("Parse(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+}
+
+func TestDateHonoursStartsAt(t *testing.T) {
+	b := mustBoundary(t, "04:00", "UTC")
+	for at, want := range map[string]string{
+		"2025-03-04T03:59:59Z": "2025-03-03",
+		"2025-03-04T04:00:00Z": "2025-03-04",
+		"2025-03-04T23:59:00Z": "2025-03-04",
+		"2025-03-01T01:00:00Z": "2025-02-28", // across a month
+		"2025-01-01T02:00:00Z": "2024-12-31", // and a year
+	} {
+		instant, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := b.Date(instant).Format("2006-01-02"); got != want {
+			t.Errorf("Date(%s) = %s, want %s", at, got, want)
+		}
+	}
+}
+
+func TestDateUsesConfiguredZone(t *testing.T) {
+	b := mustBoundary(t, "", "Asia/Tokyo")
+	// 20:00 UTC is 05:00 the next morning in Tokyo.
+	instant := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	got := b.Date(instant)
+	if got.Format("2006-01-02") != "2025-03-05" || got.Location().String() != "Asia/Tokyo" {
+		t.Errorf("Date = %s", got)
+	}
+	if got.Hour() != 0 || got.Minute() != 0 {
+		t.Errorf("Date should return midnight, got %s", got)
+	}
+}
+
+func TestDateAcrossDSTTransitions(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := Boundary{StartsAt: 2*time.Hour + 30*time.Minute, Location: berlin}
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		// Clocks jump from 02:00 to 03:00 on 2025-03-30; 02:30 never
+		// happens, so the new day starts at the jump.
+		{"before spring gap", time.Date(2025, 3, 30, 0, 59, 0, 0, time.UTC), "2025-03-29"}, // 01:59 CET
+		{"after spring gap", time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC), "2025-03-30"},   // 03:00 CEST
+		// Clocks fall back from 03:00 to 02:00 on 2025-10-26, so 02:10
+		// happens twice. Both are before the boundary by the wall clock.
+		{"first 02:10", time.Date(2025, 10, 26, 0, 10, 0, 0, time.UTC), "2025-10-25"},  // 02:10 CEST
+		{"second 02:10", time.Date(2025, 10, 26, 1, 10, 0, 0, time.UTC), "2025-10-25"}, // 02:10 CET
+		{"second 02:40", time.Date(2025, 10, 26, 1, 40, 0, 0, time.UTC), "2025-10-26"}, // 02:40 CET
+	}
+	for _, tt := range tests {
+		if got := b.Date(tt.at).Format("2006-01-02"); got != tt.want {
+			t.Errorf("%s: Date(%s) = %s, want %s", tt.name, tt.at.In(berlin), got, tt.want)
+		}
+	}
+
+	// A day that lost an hour still ends at the next boundary.
+	midnight := b.Date(time.Date(2025, 3, 30, 12, 0, 0, 0, berlin))
+	if midnight.Day() != 30 || midnight.Hour() != 0 {
+		t.Errorf("midnight of the spring-forward day = %s", midnight)
+	}
+}
+
+func TestTodayFollowsSet(t *testing.T) {
+	defer Set(Current())
+	tokyo := mustBoundary(t, "", "Asia/Tokyo")
+	Set(tokyo)
+	if want := tokyo.Date(time.Now()).Format("2006-01-02"); Today().Format("2006-01-02") != want {
+		t.Errorf("Today = %s, want %s", Today(), want)
+	}
+	if Today().Location().String() != "Asia/Tokyo" {
+		t.Errorf("Today zone = %s", Today().Location())
+	}
+	if Now().Location().String() != "Asia/Tokyo" {
+		t.Errorf("Now zone = %s", Now().Location())
+	}
+}
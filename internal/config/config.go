@@ -9,14 +9,96 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/paths"
 )
 
 // Config is the top-level user configuration loaded from
 // ~/.sp/config.toml. Missing fields fall back to defaults so a fresh
 // install works without writing a file.
 type Config struct {
-	UI        UIConfig        `toml:"ui"`
-	Templates TemplatesConfig `toml:"templates"`
+	UI         UIConfig         `toml:"ui"`
+	Templates  TemplatesConfig  `toml:"templates"`
+	History    HistoryConfig    `toml:"history"`
+	Storage    StorageConfig    `toml:"storage"`
+	Encryption EncryptionConfig `toml:"encryption"`
+	Day        DayConfig        `toml:"day"`
+	// Workspaces are named notebooks with their own directories, picked
+	// with -w or $SP_WORKSPACE.
+	Workspaces map[string]WorkspaceConfig `toml:"workspaces"`
+
+	// boundary is Day, parsed and validated by Load.
+	boundary clock.Boundary
+	// workspace is the name passed to UseWorkspace.
+	workspace string
+	// project is the root passed to UseProject.
+	project string
+}
+
+// DayConfig decides which page "today" is.
+type DayConfig struct {
+	// StartsAt is the time of day ("04:00") the next page begins, for
+	// working past midnight. Default "00:00".
+	StartsAt string `toml:"starts_at"`
+	// Timezone pins days to an IANA zone such as "Europe/Berlin" instead
+	// of the system's local zone, e.g. while travelling.
+	Timezone string `toml:"timezone"`
+}
+
+// EncryptionConfig controls how an encrypted store is unlocked. Stores
+// are encrypted with `sp encrypt`; this section only tells sp where the
+// key comes from.
+type EncryptionConfig struct {
+	// KeyFile holds the secret for stores encrypted with a key file
+	// instead of a passphrase. Relative paths resolve like storage.dir.
+	KeyFile string `toml:"key_file"`
+	// Agent asks a running `sp agent` for the key before prompting, and
+	// hands it the key after a prompt. Off unless set, so no key is sent
+	// to a socket the user did not ask for.
+	Agent bool `toml:"agent"`
+}
+
+// StorageConfig controls where day files live.
+type StorageConfig struct {
+	// Dir relocates the day files, e.g. into a synced folder. Relative
+	// paths resolve from the directory containing config.toml. Ignored
+	// when --dir or $SP_HOME selects a whole sp home instead.
+	Dir string `toml:"dir"`
+	// Format picks the encoding for newly saved days: "json" (default) or
+	// "md" for Markdown with TOML front matter. Existing days are read in
+	// either format; run `sp convert` to rewrite them.
+	Format string `toml:"format"`
+	// Layout arranges day files: "flat" (default) keeps YYYY-MM-DD files
+	// side by side, "nested" files them as YYYY/MM/DD. Both layouts are
+	// read; `sp convert --layout` restructures an existing store.
+	Layout string `toml:"layout"`
+	// Git versions the storage directory with git.
+	Git GitConfig `toml:"git"`
+}
+
+// GitConfig turns the storage directory into a git repository: every
+// change to a day is committed as it is saved, and `sp sync` exchanges
+// commits with a remote.
+type GitConfig struct {
+	// Enabled commits each save, delete and restore. The repository is
+	// created on first use. Default false.
+	Enabled bool `toml:"enabled"`
+	// Remote names the remote `sp sync` pulls from and pushes to.
+	// Default "origin".
+	Remote string `toml:"remote"`
+	// URL adds Remote when the repository does not have it yet.
+	URL string `toml:"url"`
+}
+
+// HistoryConfig bounds the per-day revision log kept under
+// ~/.sp/.history.
+type HistoryConfig struct {
+	// Keep is the number of revisions retained per day. 0 disables
+	// history. Default 50.
+	Keep int `toml:"keep"`
+	// MaxAge drops revisions older than this ("90d", "12h"). Empty keeps
+	// revisions until Keep evicts them.
+	MaxAge Duration `toml:"max_age"`
 }
 
 // TemplatesConfig controls user-defined template sections. Executable
@@ -49,13 +131,46 @@ type UIConfig struct {
 	Theme string `toml:"theme"`
 }
 
-// DefaultPath returns the canonical config path: ~/.sp/config.toml.
+// DefaultPath returns the canonical config path: config.toml inside
+// paths.ConfigDir ($SP_HOME, $XDG_CONFIG_HOME/sp, or ~/.sp).
 func DefaultPath() (string, error) {
-	home, err := os.UserHomeDir()
+	dir, err := paths.ConfigDir()
 	if err != nil {
-		return "", fmt.Errorf("home dir: %w", err)
+		return "", err
+	}
+	return PathIn(dir), nil
+}
+
+// PathIn returns the config path inside an explicit sp home directory,
+// as selected by the --dir flag.
+func PathIn(dir string) string {
+	return filepath.Join(dir, "config.toml")
+}
+
+// StorageDir resolves the directory holding day files. Precedence,
+// highest first:
+//
+//  1. the selected workspace's dir (see UseWorkspace)
+//  2. override — the --dir flag
+//  3. the project's store (see UseProject)
+//  4. $SP_HOME
+//  5. [storage] dir in config.toml
+//  6. paths.DataDir ($XDG_DATA_HOME/sp or ~/.sp)
+//
+// --dir and $SP_HOME also locate config.toml, which is where workspaces
+// are defined, so a workspace's own directory beats them.
+func (c *Config) StorageDir(override string) (string, error) {
+	switch {
+	case c.workspace != "":
+		return c.Storage.Dir, nil
+	case override != "":
+		return paths.Expand(override)
+	case c.project != "":
+		return c.Storage.Dir, nil
+	case os.Getenv(paths.EnvHome) == "" && c.Storage.Dir != "":
+		return c.Storage.Dir, nil
 	}
-	return filepath.Join(home, ".sp", "config.toml"), nil
+	return paths.DataDir()
 }
 
 // Default returns a Config populated with built-in defaults.
@@ -65,6 +180,7 @@ func Default() *Config {
 			Icons: "unicode",
 			Theme: "auto",
 		},
+		History: HistoryConfig{Keep: 50},
 	}
 }
 
@@ -73,27 +189,72 @@ func Default() *Config {
 // users do not need to create a file.
 func Load(path string) (*Config, error) {
 	cfg := Default()
+	if err := cfg.load(path); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// load merges the file at path on top of c, anchoring its relative paths
+// at the file's directory. A missing file changes nothing.
+func (c *Config) load(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return cfg, nil
+			return nil
+		}
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := toml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range c.Templates.Items {
+		c.Templates.Items[i].File = resolveRelative(path, c.Templates.Items[i].File)
+	}
+	if c.Storage.Dir != "" {
+		dir, err := paths.Expand(resolveRelative(path, c.Storage.Dir))
+		if err != nil {
+			return fmt.Errorf("parse %s: storage.dir: %w", path, err)
 		}
-		return nil, fmt.Errorf("read %s: %w", path, err)
+		c.Storage.Dir = dir
 	}
-	if err := toml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parse %s: %w", path, err)
+	if err := c.resolveWorkspaces(path); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
 	}
-	for i := range cfg.Templates.Items {
-		file := cfg.Templates.Items[i].File
-		if file != "" && file != "~" && !filepath.IsAbs(file) && !strings.HasPrefix(file, "~/") {
-			cfg.Templates.Items[i].File = filepath.Join(filepath.Dir(path), file)
+	if c.Encryption.KeyFile != "" {
+		keyFile, err := paths.Expand(resolveRelative(path, c.Encryption.KeyFile))
+		if err != nil {
+			return fmt.Errorf("parse %s: encryption.key_file: %w", path, err)
 		}
+		c.Encryption.KeyFile = keyFile
 	}
-	if cfg.UI.Icons == "" {
-		cfg.UI.Icons = "unicode"
+	if c.UI.Icons == "" {
+		c.UI.Icons = "unicode"
 	}
-	if cfg.UI.Theme == "" {
-		cfg.UI.Theme = "auto"
+	if c.UI.Theme == "" {
+		c.UI.Theme = "auto"
 	}
-	return cfg, nil
+	if c.History.Keep < 0 {
+		return fmt.Errorf("parse %s: history.keep must not be negative", path)
+	}
+	boundary, err := clock.Parse(c.Day.StartsAt, c.Day.Timezone)
+	if err != nil {
+		return fmt.Errorf("parse %s: day.%w", path, err)
+	}
+	c.boundary = boundary
+	return nil
+}
+
+// DayBoundary returns when each day starts, per the [day] settings.
+func (c *Config) DayBoundary() clock.Boundary {
+	return c.boundary
+}
+
+// resolveRelative anchors a relative path from config.toml at the directory
+// containing the config file. Absolute and ~-prefixed paths pass through.
+func resolveRelative(configPath, file string) string {
+	if file == "" || file == "~" || filepath.IsAbs(file) || strings.HasPrefix(file, "~/") {
+		return file
+	}
+	return filepath.Join(filepath.Dir(configPath), file)
 }
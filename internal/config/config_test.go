@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefault(t *testing.T) {
@@ -126,3 +128,128 @@ func TestDefaultPath(t *testing.T) {
 		t.Errorf("DefaultPath base = %q, want config.toml", filepath.Base(path))
 	}
 }
+
+func TestLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[history]\nkeep = 5\nmax_age = \"30d\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.History.Keep != 5 {
+		t.Errorf("Keep = %d, want 5", cfg.History.Keep)
+	}
+	if time.Duration(cfg.History.MaxAge) != 30*24*time.Hour {
+		t.Errorf("MaxAge = %v, want 720h", time.Duration(cfg.History.MaxAge))
+	}
+
+	if err := os.WriteFile(path, []byte("[history]\nmax_age = \"soon\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for invalid max_age")
+	}
+}
+
+func TestLoadDayBoundary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[day]\nstarts_at = \"04:00\"\ntimezone = \"America/New_York\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	boundary := cfg.DayBoundary()
+	if boundary.StartsAt != 4*time.Hour || boundary.Zone() != "America/New_York" {
+		t.Errorf("DayBoundary = %+v", boundary)
+	}
+
+	if err := os.WriteFile(path, []byte("[day]\nstarts_at = \"late\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "day.starts_at") {
+		t.Errorf("invalid starts_at: %v", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":    0,
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for in, want := range tests {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"d", "-1d", "1y", "-5m"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q) accepted invalid input", bad)
+		}
+	}
+}
+
+func TestLoadResolvesStorageDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+
+	for body, want := range map[string]string{
+		"[storage]\ndir = \"notes\"\n":         filepath.Join(dir, "notes"),
+		"[storage]\ndir = \"~/Sync/sp\"\n":     filepath.Join(home, "Sync", "sp"),
+		"[storage]\ndir = \"/srv/notes/sp\"\n": "/srv/notes/sp",
+	} {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Storage.Dir != want {
+			t.Errorf("storage.dir from %q = %q, want %q", body, cfg.Storage.Dir, want)
+		}
+	}
+}
+
+func TestStorageDirPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	cfg := Default()
+
+	t.Setenv("SP_HOME", "")
+	if got, _ := cfg.StorageDir(""); got != filepath.Join(home, ".sp") {
+		t.Errorf("default = %q", got)
+	}
+	cfg.Storage.Dir = "/from/config"
+	if got, _ := cfg.StorageDir(""); got != "/from/config" {
+		t.Errorf("config dir = %q", got)
+	}
+	t.Setenv("SP_HOME", "/from/env")
+	if got, _ := cfg.StorageDir(""); got != "/from/env" {
+		t.Errorf("SP_HOME should beat config, got %q", got)
+	}
+	if got, _ := cfg.StorageDir("/from/flag"); got != "/from/flag" {
+		t.Errorf("--dir should beat SP_HOME, got %q", got)
+	}
+}
+
+func TestDefaultPathHonoursSPHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SP_HOME", dir)
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "config.toml") {
+		t.Errorf("DefaultPath = %q, want inside SP_HOME", path)
+	}
+}
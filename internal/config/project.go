@@ -0,0 +1,26 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/pders01/sp/internal/paths"
+)
+
+// UseProject switches c to the project-local store rooted at root (see
+// paths.FindProject): day files go to root/.sp and root/sp.toml, when
+// present, is merged on top of the global settings. Its relative paths
+// resolve from root. Settings it leaves out keep their global values,
+// except git versioning: the project's own repository already tracks
+// .sp, so sp only commits there when sp.toml asks for it.
+func (c *Config) UseProject(root string) error {
+	c.project = root
+	c.Storage.Dir = filepath.Join(root, paths.ProjectDir)
+	c.Storage.Git = GitConfig{}
+	return c.load(filepath.Join(root, paths.ProjectConfig))
+}
+
+// Project returns the root passed to UseProject, or "" for the global
+// store.
+func (c *Config) Project() string {
+	return c.project
+}
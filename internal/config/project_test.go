@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseProjectOverlaysGlobalConfig(t *testing.T) {
+	global := filepath.Join(t.TempDir(), "config.toml")
+	body := "[ui]\nicons = \"nerd\"\ntheme = \"dark\"\n\n[storage]\ndir = \"/srv/notes\"\n\n[storage.git]\nenabled = true\n"
+	if err := os.WriteFile(global, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(global)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	overlay := "[ui]\ntheme = \"light\"\n\n[[templates.items]]\nname = \"Design notes\"\nfile = \"docs/design.md\"\n"
+	if err := os.WriteFile(filepath.Join(root, "sp.toml"), []byte(overlay), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SP_HOME", "/from/env")
+	if err := cfg.UseProject(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := cfg.StorageDir(""); got != filepath.Join(root, ".sp") {
+		t.Errorf("project store should beat SP_HOME and storage.dir, got %q", got)
+	}
+	if cfg.UI.Theme != "light" || cfg.UI.Icons != "nerd" {
+		t.Errorf("ui = %+v, want the project's theme over the global icons", cfg.UI)
+	}
+	if cfg.Storage.Git.Enabled {
+		t.Error("git versioning should stay off unless sp.toml enables it")
+	}
+	items := cfg.Templates.Items
+	if len(items) != 1 || items[0].File != filepath.Join(root, "docs", "design.md") {
+		t.Errorf("templates = %+v, want files resolved from the project root", items)
+	}
+	if cfg.Project() != root {
+		t.Errorf("Project = %q", cfg.Project())
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pders01/sp/internal/paths"
+)
+
+// EnvWorkspace selects a workspace when the -w flag is not given.
+const EnvWorkspace = "SP_WORKSPACE"
+
+// WorkspaceConfig is a named notebook kept apart from the others, e.g.
+// work and personal notes. Settings left out fall back to the top level.
+type WorkspaceConfig struct {
+	// Dir holds the workspace's day files. Required; relative paths
+	// resolve like storage.dir.
+	Dir string `toml:"dir"`
+	// Theme overrides ui.theme.
+	Theme string `toml:"theme"`
+	// Templates replaces templates.items. Command templates still need
+	// templates.allow_commands.
+	Templates []TemplateConfig `toml:"templates"`
+}
+
+// WorkspaceNames lists the configured workspaces alphabetically.
+func (c *Config) WorkspaceNames() []string {
+	names := make([]string, 0, len(c.Workspaces))
+	for name := range c.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Workspace returns the name of the workspace selected with
+// UseWorkspace, or "" for the top-level store.
+func (c *Config) Workspace() string {
+	return c.workspace
+}
+
+// UseWorkspace applies the named workspace's settings on top of c. The
+// workspace's directory then wins over --dir and $SP_HOME, which only
+// located config.toml.
+func (c *Config) UseWorkspace(name string) error {
+	ws, ok := c.Workspaces[name]
+	if !ok {
+		if len(c.Workspaces) == 0 {
+			return fmt.Errorf("unknown workspace %q: none are configured", name)
+		}
+		return fmt.Errorf("unknown workspace %q (have %s)", name, strings.Join(c.WorkspaceNames(), ", "))
+	}
+	c.workspace = name
+	c.Storage.Dir = ws.Dir
+	if ws.Theme != "" {
+		c.UI.Theme = ws.Theme
+	}
+	if ws.Templates != nil {
+		c.Templates.Items = ws.Templates
+	}
+	return nil
+}
+
+// resolveWorkspaces anchors each workspace's paths like the top-level
+// ones and rejects workspaces without a directory.
+func (c *Config) resolveWorkspaces(configPath string) error {
+	for name, ws := range c.Workspaces {
+		if ws.Dir == "" {
+			return fmt.Errorf("workspaces.%s.dir is required", name)
+		}
+		dir, err := paths.Expand(resolveRelative(configPath, ws.Dir))
+		if err != nil {
+			return fmt.Errorf("workspaces.%s.dir: %w", name, err)
+		}
+		ws.Dir = dir
+		for i := range ws.Templates {
+			ws.Templates[i].File = resolveRelative(configPath, ws.Templates[i].File)
+		}
+		c.Workspaces[name] = ws
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const workspacesTOML = `
+[ui]
+theme = "dark"
+
+[[templates.items]]
+id = "standup"
+file = "standup.md"
+
+[workspaces.work]
+dir = "work"
+theme = "light"
+
+[[workspaces.work.templates]]
+id = "meeting"
+file = "meeting.md"
+
+[workspaces.home]
+dir = "/srv/home-notes"
+`
+
+func TestUseWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(workspacesTOML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := cfg.WorkspaceNames(); len(names) != 2 || names[0] != "home" || names[1] != "work" {
+		t.Errorf("WorkspaceNames = %v", names)
+	}
+
+	if err := cfg.UseWorkspace("work"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workspace() != "work" || cfg.UI.Theme != "light" {
+		t.Errorf("workspace %q, theme %q", cfg.Workspace(), cfg.UI.Theme)
+	}
+	if got, _ := cfg.StorageDir("/from/flag"); got != filepath.Join(dir, "work") {
+		t.Errorf("workspace dir should beat --dir, got %q", got)
+	}
+	items := cfg.Templates.Items
+	if len(items) != 1 || items[0].ID != "meeting" || items[0].File != filepath.Join(dir, "meeting.md") {
+		t.Errorf("templates = %+v, want the workspace's own", items)
+	}
+
+	cfg, _ = Load(path)
+	if err := cfg.UseWorkspace("home"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UI.Theme != "dark" || len(cfg.Templates.Items) != 1 || cfg.Templates.Items[0].ID != "standup" {
+		t.Errorf("unset settings should fall back to the top level: theme %q, templates %+v", cfg.UI.Theme, cfg.Templates.Items)
+	}
+
+	err = cfg.UseWorkspace("play")
+	if err == nil || !strings.Contains(err.Error(), "home, work") {
+		t.Errorf("unknown workspace: %v", err)
+	}
+}
+
+func TestLoadRequiresWorkspaceDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[workspaces.work]\ntheme = \"light\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "workspaces.work.dir") {
+		t.Errorf("Load without dir: %v", err)
+	}
+}
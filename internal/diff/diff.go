@@ -0,0 +1,135 @@
+// Package diff compares scratchpad contents line by line.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op classifies one line of an edit script.
+type Op int
+
+const (
+	// Equal lines appear unchanged in both inputs.
+	Equal Op = iota
+	// Insert lines appear only in the newer input.
+	Insert
+	// Delete lines appear only in the older input.
+	Delete
+)
+
+// Line is one entry of an edit script produced by Lines.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Stat counts inserted and deleted lines between two versions.
+type Stat struct {
+	Added   int
+	Removed int
+}
+
+// String renders the stat as "+3 -1", or "no changes".
+func (s Stat) String() string {
+	if s.Added == 0 && s.Removed == 0 {
+		return "no changes"
+	}
+	return fmt.Sprintf("+%d -%d", s.Added, s.Removed)
+}
+
+// Split breaks text into lines without the trailing newline, treating an
+// empty string as zero lines so empty pages diff cleanly.
+func Split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines returns a minimal line edit script turning a into b. It uses the
+// classic LCS table, which is quadratic but comfortably fast for the size
+// of a day's notes. Common prefix and suffix lines are trimmed first so
+// typical small edits only pay for the changed region.
+func Lines(a, b string) []Line {
+	return edits(Split(a), Split(b))
+}
+
+// Summarize counts the lines added and removed between a and b.
+func Summarize(a, b string) Stat {
+	var s Stat
+	for _, l := range Lines(a, b) {
+		switch l.Op {
+		case Insert:
+			s.Added++
+		case Delete:
+			s.Removed++
+		}
+	}
+	return s
+}
+
+func edits(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		out = append(out, Line{Op: Equal, Text: text})
+	}
+	out = append(out, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		out = append(out, Line{Op: Equal, Text: text})
+	}
+	return out
+}
+
+// middle diffs the region between the common prefix and suffix.
+func middle(a, b []string) []Line {
+	n, m := len(a), len(b)
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			out = append(out, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		out = append(out, Line{Op: Insert, Text: b[j]})
+	}
+	return out
+}
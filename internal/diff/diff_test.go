@@ -0,0 +1,61 @@
+package diff
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want Stat
+	}{
+		{"identical", "one\ntwo\n", "one\ntwo\n", Stat{}},
+		{"from empty", "", "one\ntwo\n", Stat{Added: 2}},
+		{"to empty", "one\n", "", Stat{Removed: 1}},
+		{"edit middle", "a\nb\nc\n", "a\nB\nc\nd\n", Stat{Added: 2, Removed: 1}},
+		{"missing trailing newline", "a\nb", "a\nb\n", Stat{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.a, tt.b); got != tt.want {
+				t.Errorf("Summarize(%q, %q) = %+v, want %+v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesReconstructsBothSides(t *testing.T) {
+	a := "# Day\n- one\n- two\n- three\n"
+	b := "# Day\n- one\n- 2\n- three\n- four\n"
+	var left, right []string
+	for _, l := range Lines(a, b) {
+		if l.Op != Insert {
+			left = append(left, l.Text)
+		}
+		if l.Op != Delete {
+			right = append(right, l.Text)
+		}
+	}
+	if got, want := join(left), join(Split(a)); got != want {
+		t.Errorf("old side = %q, want %q", got, want)
+	}
+	if got, want := join(right), join(Split(b)); got != want {
+		t.Errorf("new side = %q, want %q", got, want)
+	}
+}
+
+func TestStatString(t *testing.T) {
+	if got := (Stat{}).String(); got != "no changes" {
+		t.Errorf("empty stat = %q", got)
+	}
+	if got := (Stat{Added: 3, Removed: 1}).String(); got != "+3 -1" {
+		t.Errorf("stat = %q", got)
+	}
+}
+
+func join(lines []string) string {
+	out := ""
+	for _, l := range lines {
+		out += l + "\n"
+	}
+	return out
+}
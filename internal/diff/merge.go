@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Chunk is one region of a three-way merge: either lines both sides
+// agree on after the merge, or a conflict where they changed the same
+// lines differently.
+type Chunk struct {
+	Lines    []string
+	Conflict bool
+	// Base, Ours and Theirs hold a conflict's three versions.
+	Base, Ours, Theirs []string
+}
+
+// Merge3 combines ours and theirs, two edits of base, line by line. A
+// region changed on one side only takes that side; a region changed the
+// same way on both sides is taken once; anything else is a conflict.
+func Merge3(base, ours, theirs string) []Chunk {
+	b, o, t := Split(base), Split(ours), Split(theirs)
+	mo, mt := matches(b, o), matches(b, t)
+
+	var chunks []Chunk
+	add := func(lines ...string) {
+		if len(lines) == 0 {
+			return
+		}
+		if n := len(chunks); n > 0 && !chunks[n-1].Conflict {
+			chunks[n-1].Lines = append(chunks[n-1].Lines, lines...)
+			return
+		}
+		chunks = append(chunks, Chunk{Lines: append([]string(nil), lines...)})
+	}
+	bi, oi, ti := 0, 0, 0
+	// region settles the lines between two anchors.
+	region := func(bEnd, oEnd, tEnd int) {
+		bs, os, ts := b[bi:bEnd], o[oi:oEnd], t[ti:tEnd]
+		switch {
+		case slices.Equal(os, ts):
+			add(os...)
+		case slices.Equal(bs, os):
+			add(ts...)
+		case slices.Equal(bs, ts):
+			add(os...)
+		default:
+			chunks = append(chunks, Chunk{Conflict: true, Base: bs, Ours: os, Theirs: ts})
+		}
+	}
+	// Base lines kept by both sides anchor the merge.
+	for i := range b {
+		if mo[i] < 0 || mt[i] < 0 {
+			continue
+		}
+		region(i, mo[i], mt[i])
+		add(b[i])
+		bi, oi, ti = i+1, mo[i]+1, mt[i]+1
+	}
+	region(len(b), len(o), len(t))
+	return chunks
+}
+
+// matches maps each line of base to the line of other it survives as, or
+// -1 when other dropped or changed it.
+func matches(base, other []string) []int {
+	m := make([]int, len(base))
+	i, j := 0, 0
+	for _, l := range edits(base, other) {
+		switch l.Op {
+		case Equal:
+			m[i] = j
+			i++
+			j++
+		case Delete:
+			m[i] = -1
+			i++
+		case Insert:
+			j++
+		}
+	}
+	return m
+}
+
+// Conflicts counts the conflicting regions of a merge.
+func Conflicts(chunks []Chunk) int {
+	n := 0
+	for _, c := range chunks {
+		if c.Conflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Text renders a merge, wrapping each conflict's two sides in git-style
+// markers labelled ours and theirs.
+func Text(chunks []Chunk, ours, theirs string) string {
+	var lines []string
+	for _, c := range chunks {
+		if !c.Conflict {
+			lines = append(lines, c.Lines...)
+			continue
+		}
+		lines = append(lines, "<<<<<<< "+ours)
+		lines = append(lines, c.Ours...)
+		lines = append(lines, "=======")
+		lines = append(lines, c.Theirs...)
+		lines = append(lines, ">>>>>>> "+theirs)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
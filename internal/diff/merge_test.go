@@ -0,0 +1,43 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "# Day\n- one\n- two\n- three\n"
+	tests := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		{"one side changed", base, "# Day\n- one\n- 2\n- three\n", "# Day\n- one\n- 2\n- three\n", 0},
+		{"separate regions", "# Day\n- 1\n- two\n- three\n", "# Day\n- one\n- two\n- 3\n", "# Day\n- 1\n- two\n- 3\n", 0},
+		{"both appended the same", base + "- four\n", base + "- four\n", base + "- four\n", 0},
+		{"both appended differently", base + "- laptop\n", base + "- desktop\n",
+			base + "<<<<<<< ours\n- laptop\n=======\n- desktop\n>>>>>>> theirs\n", 1},
+		{"same line changed", "# Day\n- one\n- TWO\n- three\n", "# Day\n- one\n- deux\n- three\n",
+			"# Day\n- one\n<<<<<<< ours\n- TWO\n=======\n- deux\n>>>>>>> theirs\n- three\n", 1},
+		{"deleted and kept", "# Day\n- one\n- three\n", base, "# Day\n- one\n- three\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Merge3(base, tt.ours, tt.theirs)
+			if got := Text(chunks, "ours", "theirs"); got != tt.want {
+				t.Errorf("merge =\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := Conflicts(chunks); got != tt.wantConflicts {
+				t.Errorf("conflicts = %d, want %d", got, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestMerge3WithoutBase(t *testing.T) {
+	chunks := Merge3("", "mine\n", "theirs\n")
+	if Conflicts(chunks) != 1 {
+		t.Errorf("chunks = %+v, want one conflict", chunks)
+	}
+	if got := Text(Merge3("", "", "theirs\n"), "a", "b"); got != "theirs\n" {
+		t.Errorf("one empty side = %q", got)
+	}
+}
@@ -0,0 +1,182 @@
+// Package gitsync versions a storage directory with git. sp commits each
+// change to a day as it happens; Sync rebases those commits onto a remote
+// and pushes them.
+package gitsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRemote is the remote Sync uses when none is configured.
+const DefaultRemote = "origin"
+
+// ErrNoGit is returned when the git executable cannot be found.
+var ErrNoGit = errors.New("git not found on PATH")
+
+// ignored keeps sp's own bookkeeping out of the repository: git is the
+// history now, and the caches, trash and temp files are per machine. The
+// config and key files that share ~/.sp with the days must never be
+// pushed; a key file would undo encryption.
+var ignored = []string{
+	".index",
+	".search",
+	".history/",
+	".trash/",
+	".quarantine/",
+	".*.tmp-*",
+	"config.toml",
+	"*.key",
+}
+
+// Repo is a git working tree rooted at a storage directory.
+type Repo struct {
+	dir string
+	// env supplies a committer identity when git has none configured,
+	// so auto-commits never stop a save on a fresh machine.
+	env []string
+}
+
+// Open returns the repository in dir, initializing one when dir is not
+// yet a working tree of its own. A new repository gets a .gitignore and
+// an initial commit of the days already there.
+func Open(dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, ErrNoGit
+	}
+	r := &Repo{dir: dir}
+	if _, err := r.git("config", "user.email"); err != nil {
+		r.env = []string{
+			"GIT_AUTHOR_NAME=sp", "GIT_AUTHOR_EMAIL=sp@localhost",
+			"GIT_COMMITTER_NAME=sp", "GIT_COMMITTER_EMAIL=sp@localhost",
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return r, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to inspect %s: %w", dir, err)
+	}
+
+	if _, err := r.git("init", "-q"); err != nil {
+		return nil, err
+	}
+	if err := WriteIgnore(dir); err != nil {
+		return nil, err
+	}
+	if _, err := r.CommitAll("Start sp history"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Dir returns the root of the working tree.
+func (r *Repo) Dir() string { return r.dir }
+
+// WriteIgnore gives dir a .gitignore that keeps sp's per-machine files
+// out of git, unless it already has one.
+func WriteIgnore(dir string) error {
+	path := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	data := "# Written by sp. Revisions live in git; these stay on this machine.\n" +
+		strings.Join(ignored, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("failed to write .gitignore: %w", err)
+	}
+	return nil
+}
+
+// Commit records the current state of paths, relative to the repository
+// root, with message. A path may name a directory. Paths that exist
+// neither on disk nor in git are skipped, and nothing is committed when
+// the paths are unchanged. Other changes in the tree are left alone.
+func (r *Repo) Commit(message string, paths ...string) error {
+	tracked, err := r.git(append([]string{"ls-files", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, path := range strings.Split(tracked, "\n") {
+		// A tracked file also makes its directories known.
+		for path != "" {
+			known[path] = true
+			i := strings.LastIndexByte(path, '/')
+			if i < 0 {
+				break
+			}
+			path = path[:i]
+		}
+	}
+	var present []string
+	for _, path := range paths {
+		if _, err := os.Lstat(filepath.Join(r.dir, path)); err == nil || known[filepath.ToSlash(path)] {
+			present = append(present, path)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+	if _, err := r.git(append([]string{"add", "-A", "--"}, present...)...); err != nil {
+		return err
+	}
+	if !r.staged(present...) {
+		return nil
+	}
+	_, err = r.git(append([]string{"commit", "-q", "-m", message, "--"}, present...)...)
+	return err
+}
+
+// CommitAll records every change in the tree, reporting whether there
+// was anything to commit.
+func (r *Repo) CommitAll(message string) (bool, error) {
+	if _, err := r.git("add", "-A"); err != nil {
+		return false, err
+	}
+	if !r.staged() {
+		return false, nil
+	}
+	if _, err := r.git("commit", "-q", "-m", message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// staged reports whether the index differs from HEAD for paths, or
+// anywhere when none are given.
+func (r *Repo) staged(paths ...string) bool {
+	_, err := r.git(append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...)
+	return err != nil
+}
+
+// git runs a git command in the working tree and returns its trimmed
+// output.
+func (r *Repo) git(args ...string) (string, error) {
+	out, err := r.output(args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// output runs a git command in the working tree and returns its standard
+// output unchanged.
+func (r *Repo) output(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), r.env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return stdout.Bytes(), fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,240 @@
+package gitsync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// concatMerger stands in for the store's merge: both versions, local
+// first.
+type concatMerger struct{}
+
+func (concatMerger) MergeConflict(_ string, local, remote []byte) ([]byte, error) {
+	return append(append(append([]byte(nil), local...), "|"...), remote...), nil
+}
+
+// copyMerger keeps the local version and writes the remote one beside
+// it, as the store does for attachments.
+type copyMerger struct{ dir string }
+
+func (m copyMerger) MergeConflict(path string, local, remote []byte) ([]byte, error) {
+	return local, os.WriteFile(filepath.Join(m.dir, path+".remote"), remote, 0o644)
+}
+
+func openRepo(t *testing.T, dir string) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func writeDay(t *testing.T, r *Repo, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(r.Dir(), name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("Update "+name, name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDay(t *testing.T, r *Repo, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(r.Dir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpenInitializesRepository(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-01.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := openRepo(t, dir)
+	if out, _ := r.git("ls-files"); !strings.Contains(out, "2024-03-01.json") || !strings.Contains(out, ".gitignore") {
+		t.Errorf("initial commit = %q, want existing days and .gitignore", out)
+	}
+
+	// sp's own files stay out of the repository.
+	for _, name := range []string{".index", ".search"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if committed, err := r.CommitAll("Everything"); err != nil || committed {
+		t.Errorf("CommitAll = %v, %v; the indexes must be ignored", committed, err)
+	}
+
+	// Reopening keeps the repository and its history.
+	again := openRepo(t, dir)
+	if n := again.count("HEAD"); n != 1 {
+		t.Errorf("commits after reopening = %d, want 1", n)
+	}
+}
+
+func TestCommitRecordsOnlyGivenPaths(t *testing.T) {
+	r := openRepo(t, t.TempDir())
+	if err := os.WriteFile(filepath.Join(r.Dir(), "other.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeDay(t, r, "2024-03-01.json", "one")
+	if out, _ := r.git("log", "-1", "--name-only", "--format=%s"); out != "Update 2024-03-01.json\n\n2024-03-01.json" {
+		t.Errorf("last commit = %q", out)
+	}
+
+	// Unchanged and never-existing paths are not an error and commit nothing.
+	before := r.count("HEAD")
+	if err := r.Commit("Nothing", "2024-03-01.json", "2024/03/01.json"); err != nil {
+		t.Fatal(err)
+	}
+	if r.count("HEAD") != before {
+		t.Error("an unchanged day was committed")
+	}
+
+	// A removed day is committed as a deletion.
+	if err := os.Remove(filepath.Join(r.Dir(), "2024-03-01.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("Delete 2024-03-01", "2024-03-01.json"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := r.git("ls-files", "2024-03-01.json"); out != "" {
+		t.Error("deleted day is still tracked")
+	}
+}
+
+func TestCommitRecordsDirectories(t *testing.T) {
+	r := openRepo(t, t.TempDir())
+	dir := filepath.Join(r.Dir(), "attachments", "2024-03-01")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shot.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("Attach", "attachments/2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := r.git("ls-files", "attachments"); out != "attachments/2024-03-01/shot.png" {
+		t.Errorf("tracked = %q", out)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("Delete", "attachments/2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := r.git("ls-files", "attachments"); out != "" {
+		t.Errorf("removed directory still tracked: %q", out)
+	}
+}
+
+func TestSyncMergesDaysChangedOnBothSides(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+
+	laptop := openRepo(t, t.TempDir())
+	writeDay(t, laptop, "2024-03-01.json", "base")
+	result, err := laptop.Sync("", bare, concatMerger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Remote != DefaultRemote || result.Pushed == 0 {
+		t.Errorf("first sync = %+v", result)
+	}
+
+	desktop := openRepo(t, t.TempDir())
+	if result, err = desktop.Sync("", bare, concatMerger{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDay(t, desktop, "2024-03-01.json"); got != "base" || result.Pulled == 0 {
+		t.Fatalf("pulled day = %q, result %+v", got, result)
+	}
+
+	writeDay(t, laptop, "2024-03-01.json", "laptop")
+	if _, err := laptop.Sync("", "", concatMerger{}); err != nil {
+		t.Fatal(err)
+	}
+	writeDay(t, desktop, "2024-03-01.json", "desktop")
+	writeDay(t, desktop, "2024-03-02.json", "only on desktop")
+	result, err = desktop.Sync("", "", concatMerger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "2024-03-01.json" {
+		t.Errorf("conflicts = %v", result.Conflicts)
+	}
+	if got := readDay(t, desktop, "2024-03-01.json"); got != "desktop|laptop" {
+		t.Errorf("merged day = %q", got)
+	}
+
+	if _, err := laptop.Sync("", "", concatMerger{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDay(t, laptop, "2024-03-01.json"); got != "desktop|laptop" {
+		t.Errorf("laptop after sync = %q", got)
+	}
+	if got := readDay(t, laptop, "2024-03-02.json"); got != "only on desktop" {
+		t.Errorf("laptop after sync = %q", got)
+	}
+}
+
+func TestSyncCommitsFilesWrittenBesideAConflict(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	laptop := openRepo(t, t.TempDir())
+	desktop := openRepo(t, t.TempDir())
+	writeDay(t, laptop, "shot.png", "base")
+	if _, err := laptop.Sync("", bare, copyMerger{laptop.Dir()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := desktop.Sync("", bare, copyMerger{desktop.Dir()}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeDay(t, laptop, "shot.png", "laptop")
+	if _, err := laptop.Sync("", "", copyMerger{laptop.Dir()}); err != nil {
+		t.Fatal(err)
+	}
+	writeDay(t, desktop, "shot.png", "desktop")
+	if _, err := desktop.Sync("", "", copyMerger{desktop.Dir()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := laptop.Sync("", "", copyMerger{laptop.Dir()}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDay(t, laptop, "shot.png"); got != "desktop" {
+		t.Errorf("kept version = %q", got)
+	}
+	if got := readDay(t, laptop, "shot.png.remote"); got != "laptop" {
+		t.Errorf("copy beside it = %q", got)
+	}
+}
+
+func TestSyncNeedsRemote(t *testing.T) {
+	r := openRepo(t, t.TempDir())
+	if _, err := r.Sync("", "", concatMerger{}); err == nil || !strings.Contains(err.Error(), "no git remote") {
+		t.Errorf("err = %v", err)
+	}
+}
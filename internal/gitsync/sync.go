@@ -0,0 +1,184 @@
+package gitsync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Merger combines two versions of a file that changed on both sides of a
+// sync. path is relative to the repository root. The result must be a
+// valid file, e.g. a day holding both versions between conflict markers.
+// Files the Merger writes beside path, such as a copy of a version it
+// could not combine, are committed with the merge.
+type Merger interface {
+	MergeConflict(path string, local, remote []byte) ([]byte, error)
+}
+
+// SyncResult describes a finished Sync.
+type SyncResult struct {
+	Remote string
+	Branch string
+	// Pulled and Pushed count the commits taken from and sent to the
+	// remote.
+	Pulled int
+	Pushed int
+	// Conflicts lists the files, relative to the repository root, that
+	// changed on both sides and were merged by the Merger.
+	Conflicts []string
+}
+
+// Sync commits anything left uncommitted, rebases local commits onto the
+// remote's branch and pushes the result. url adds remote when it is not
+// configured yet. Files changed on both sides are combined with merger
+// instead of stopping the rebase; anything else that cannot be rebased
+// aborts it and leaves the local branch as it was.
+func (r *Repo) Sync(remote, url string, merger Merger) (SyncResult, error) {
+	if remote == "" {
+		remote = DefaultRemote
+	}
+	result := SyncResult{Remote: remote}
+	if _, err := r.CommitAll("Record changes made outside sp"); err != nil {
+		return result, err
+	}
+	if err := r.ensureRemote(remote, url); err != nil {
+		return result, err
+	}
+	branch, err := r.git("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return result, fmt.Errorf("sync needs a checked-out branch: %w", err)
+	}
+	result.Branch = branch
+
+	if _, err := r.git("fetch", "-q", remote); err != nil {
+		return result, err
+	}
+	upstream := remote + "/" + branch
+	if _, err := r.git("rev-parse", "-q", "--verify", "refs/remotes/"+upstream); err == nil {
+		result.Pulled = r.count("HEAD.." + upstream)
+		conflicts, err := r.rebase(upstream, merger)
+		result.Conflicts = conflicts
+		if err != nil {
+			return result, err
+		}
+		result.Pushed = r.count(upstream + "..HEAD")
+	} else {
+		// First push of this branch.
+		result.Pushed = r.count("HEAD")
+	}
+
+	if result.Pushed > 0 {
+		if _, err := r.git("push", "-q", "-u", remote, "HEAD:"+branch); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (r *Repo) ensureRemote(remote, url string) error {
+	if _, err := r.git("remote", "get-url", remote); err == nil {
+		return nil
+	}
+	if url == "" {
+		return fmt.Errorf("no git remote %q: set storage.git.url or run git remote add %s <url> in %s", remote, remote, r.dir)
+	}
+	_, err := r.git("remote", "add", remote, url)
+	return err
+}
+
+// rebase replays local commits onto upstream, merging each conflicting
+// file with merger. It returns the merged files.
+func (r *Repo) rebase(upstream string, merger Merger) ([]string, error) {
+	var merged []string
+	seen := make(map[string]bool)
+	_, err := r.git("rebase", "-q", upstream)
+	for err != nil {
+		paths, uerr := r.git("diff", "--name-only", "--diff-filter=U")
+		if uerr == nil && paths == "" && r.rebasing() && !r.staged() {
+			// The merge left the commit with nothing to replay.
+			_, err = r.git("rebase", "--skip")
+			continue
+		}
+		if uerr != nil || paths == "" {
+			r.abortRebase()
+			return nil, fmt.Errorf("rebase onto %s: %w", upstream, err)
+		}
+		for _, path := range strings.Split(paths, "\n") {
+			if rerr := r.resolve(path, merger); rerr != nil {
+				r.abortRebase()
+				return nil, fmt.Errorf("merge %s: %w", path, rerr)
+			}
+			if !seen[path] {
+				seen[path] = true
+				merged = append(merged, path)
+			}
+		}
+		if _, aerr := r.git("add", "-A"); aerr != nil {
+			r.abortRebase()
+			return nil, fmt.Errorf("stage merged files: %w", aerr)
+		}
+		_, err = r.git("-c", "core.editor=true", "rebase", "--continue")
+	}
+	return merged, nil
+}
+
+// resolve settles one conflicting file mid-rebase. While rebasing, stage
+// 2 holds the upstream version and stage 3 the local commit being
+// replayed. When one side deleted the file, the side that edited it wins.
+func (r *Repo) resolve(path string, merger Merger) error {
+	remote, rerr := r.output("show", ":2:"+path)
+	local, lerr := r.output("show", ":3:"+path)
+	var merged []byte
+	switch {
+	case rerr != nil && lerr != nil:
+		return fmt.Errorf("neither side of the conflict is readable: %w", lerr)
+	case rerr != nil:
+		merged = local
+	case lerr != nil:
+		merged = remote
+	default:
+		var err error
+		if merged, err = merger.MergeConflict(path, local, remote); err != nil {
+			return err
+		}
+	}
+	full := filepath.Join(r.dir, filepath.FromSlash(path))
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(full); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(full, merged, perm); err != nil {
+		return err
+	}
+	_, err := r.git("add", "--", path)
+	return err
+}
+
+func (r *Repo) rebasing() bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(r.dir, ".git", dir)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repo) abortRebase() {
+	_, _ = r.git("rebase", "--abort")
+}
+
+// count returns the number of commits in a revision range, or 0 when it
+// cannot be resolved.
+func (r *Repo) count(revisions string) int {
+	out, err := r.git("rev-list", "--count", revisions)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(out)
+	return n
+}
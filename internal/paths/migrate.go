@@ -0,0 +1,181 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Move relocates one entry out of the legacy ~/.sp directory.
+type Move struct {
+	From string
+	To   string
+}
+
+// configEntries follow config.toml into the config directory when ~/.sp is
+// split; everything else is data. templates/ is where the example config
+// keeps template files, and relative template paths resolve next to
+// config.toml, so it has to move with it.
+var configEntries = map[string]bool{
+	"config.toml": true,
+	"templates":   true,
+}
+
+// PlanLegacyMigration lists the moves that split ~/.sp into the XDG
+// config and data directories. It fails without moving anything when
+// $SP_HOME pins the location, when ~/.sp does not exist, or when any
+// destination is already taken.
+func PlanLegacyMigration() ([]Move, error) {
+	if os.Getenv(EnvHome) != "" {
+		return nil, fmt.Errorf("$%s is set; unset it to migrate to XDG directories", EnvHome)
+	}
+	legacy, err := LegacyDir()
+	if err != nil {
+		return nil, err
+	}
+	configDir, err := XDGConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := XDGDataDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(legacy)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("nothing to migrate: %s does not exist", legacy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", legacy, err)
+	}
+
+	moves := make([]Move, 0, len(entries))
+	var taken []string
+	for _, entry := range entries {
+		dest := dataDir
+		if configEntries[entry.Name()] {
+			dest = configDir
+		}
+		move := Move{
+			From: filepath.Join(legacy, entry.Name()),
+			To:   filepath.Join(dest, entry.Name()),
+		}
+		if _, serr := os.Lstat(move.To); serr == nil {
+			taken = append(taken, move.To)
+		}
+		moves = append(moves, move)
+	}
+	if len(taken) > 0 {
+		return nil, fmt.Errorf("refusing to overwrite existing %v", taken)
+	}
+	return moves, nil
+}
+
+// ApplyMoves performs moves in order. Each entry is renamed when possible
+// and copied then removed when the rename crosses filesystems; a failed
+// copy removes its partial destination and leaves the source intact. When
+// a move fails, the ones already done are moved back, so the data is never
+// left split between both locations. Once every move succeeds, source
+// directories left empty are removed.
+func ApplyMoves(moves []Move) error {
+	var done []Move
+	for _, move := range moves {
+		if err := applyMove(move); err != nil {
+			if rerr := undoMoves(done); rerr != nil {
+				return fmt.Errorf("%w; rolling back failed, some entries are still in the new location: %w", err, rerr)
+			}
+			return err
+		}
+		done = append(done, move)
+	}
+	sources := make(map[string]bool)
+	for _, move := range moves {
+		sources[filepath.Dir(move.From)] = true
+	}
+	for dir := range sources {
+		// Remove only succeeds on empty directories, which is the point.
+		_ = os.Remove(dir)
+	}
+	return nil
+}
+
+func applyMove(move Move) error {
+	if err := os.MkdirAll(filepath.Dir(move.To), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(move.To), err)
+	}
+	if err := os.Rename(move.From, move.To); err == nil {
+		return nil
+	}
+	if err := copyTree(move.From, move.To); err != nil {
+		_ = os.RemoveAll(move.To)
+		return fmt.Errorf("move %s: %w", move.From, err)
+	}
+	if err := os.RemoveAll(move.From); err != nil {
+		return fmt.Errorf("remove %s after copy: %w", move.From, err)
+	}
+	return nil
+}
+
+// undoMoves moves done entries back, the last one first.
+func undoMoves(done []Move) error {
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		move := done[i]
+		if err := applyMove(Move{From: move.To, To: move.From}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// Drop the destination directory again if the move created it.
+		_ = os.Remove(filepath.Dir(move.To))
+	}
+	return errors.Join(errs...)
+}
+
+func copyTree(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("cannot copy %s: unsupported file type", path)
+		}
+	})
+}
+
+func copyFile(from, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
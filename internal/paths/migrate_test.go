@@ -0,0 +1,108 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+	mkdir(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLegacyMigrationSplitsConfigAndData(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	legacy := filepath.Join(home, ".sp")
+	writeFile(t, filepath.Join(legacy, "config.toml"), "[ui]\n")
+	writeFile(t, filepath.Join(legacy, "templates", "meeting.md"), "- agenda\n")
+	writeFile(t, filepath.Join(legacy, "2024-01-01.json"), "{}")
+	writeFile(t, filepath.Join(legacy, ".history", "2024-01-01", "rev.json"), "{}")
+
+	moves, err := PlanLegacyMigration()
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if err := ApplyMoves(moves); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(home, "cfg", "sp", "config.toml"),
+		filepath.Join(home, "cfg", "sp", "templates", "meeting.md"),
+		filepath.Join(home, "data", "sp", "2024-01-01.json"),
+		filepath.Join(home, "data", "sp", ".history", "2024-01-01", "rev.json"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("missing after migration: %v", err)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("empty ~/.sp should be removed, stat err = %v", err)
+	}
+	if got, _ := ConfigDir(); got != filepath.Join(home, "cfg", "sp") {
+		t.Errorf("ConfigDir after migration = %q", got)
+	}
+	if got, _ := DataDir(); got != filepath.Join(home, "data", "sp") {
+		t.Errorf("DataDir after migration = %q", got)
+	}
+}
+
+func TestLegacyMigrationRefusesToOverwrite(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	writeFile(t, filepath.Join(home, ".sp", "2024-01-01.json"), "legacy")
+	writeFile(t, filepath.Join(home, "data", "sp", "2024-01-01.json"), "already there")
+
+	if _, err := PlanLegacyMigration(); err == nil {
+		t.Fatal("expected conflict error")
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".sp", "2024-01-01.json"))
+	if err != nil || string(data) != "legacy" {
+		t.Errorf("legacy file touched: %q, %v", data, err)
+	}
+}
+
+func TestLegacyMigrationRequiresLegacyDir(t *testing.T) {
+	isolate(t)
+	if _, err := PlanLegacyMigration(); err == nil {
+		t.Error("expected error when ~/.sp is missing")
+	}
+}
+
+func TestApplyMovesRollsBackOnFailure(t *testing.T) {
+	home := isolate(t)
+	legacy := filepath.Join(home, ".sp")
+	writeFile(t, filepath.Join(legacy, "config.toml"), "[ui]\n")
+	moves := []Move{
+		{From: filepath.Join(legacy, "config.toml"), To: filepath.Join(home, "cfg", "sp", "config.toml")},
+		{From: filepath.Join(legacy, "vanished.json"), To: filepath.Join(home, "data", "sp", "vanished.json")},
+	}
+	if err := ApplyMoves(moves); err == nil {
+		t.Fatal("expected the missing entry to fail the migration")
+	}
+	if data, err := os.ReadFile(filepath.Join(legacy, "config.toml")); err != nil || string(data) != "[ui]\n" {
+		t.Errorf("config.toml not moved back: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "cfg", "sp")); !os.IsNotExist(err) {
+		t.Errorf("destination left behind, stat err = %v", err)
+	}
+}
+
+func TestCopyTreeFallback(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	writeFile(t, filepath.Join(src, "a", "b.json"), "payload")
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "a", "b.json"))
+	if err != nil || string(data) != "payload" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+}
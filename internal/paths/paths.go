@@ -0,0 +1,127 @@
+// Package paths resolves where sp keeps its configuration and day files.
+//
+// sp follows the XDG Base Directory layout ($XDG_CONFIG_HOME/sp for
+// config.toml, $XDG_DATA_HOME/sp for day files) but keeps working from the
+// legacy ~/.sp until 'sp migrate-dirs' moves the data over. $SP_HOME pins
+// both to a single directory.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvHome overrides sp's home directory, which then holds config.toml
+// and, unless config relocates them, the day files.
+const EnvHome = "SP_HOME"
+
+// ConfigDir returns the directory holding config.toml.
+func ConfigDir() (string, error) {
+	config, _, err := resolve()
+	return config, err
+}
+
+// DataDir returns the default directory holding day files.
+func DataDir() (string, error) {
+	_, data, err := resolve()
+	return data, err
+}
+
+// LegacyDir returns ~/.sp, where sp kept everything before XDG support.
+func LegacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("home dir: %w", err)
+	}
+	return filepath.Join(home, ".sp"), nil
+}
+
+// XDGConfigDir returns $XDG_CONFIG_HOME/sp, using the spec's ~/.config
+// default when the variable is unset.
+func XDGConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// XDGDataDir returns $XDG_DATA_HOME/sp, using the spec's ~/.local/share
+// default when the variable is unset.
+func XDGDataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// resolve picks the config and data directories together, so config.toml
+// and the days it describes never come from different layouts:
+//
+//  1. $SP_HOME for both
+//  2. with ~/.sp present, ~/.sp for both until its days have moved to the
+//     XDG data directory (sp migrate-dirs), then the XDG directories
+//  3. without ~/.sp, the XDG directories when either already exists or
+//     either variable is set (fresh installs on XDG-aware systems)
+//  4. ~/.sp for both
+//
+// Existing ~/.sp installs therefore keep working until migrated.
+func resolve() (config, data string, err error) {
+	if dir := os.Getenv(EnvHome); dir != "" {
+		dir, err := Expand(dir)
+		return dir, dir, err
+	}
+	if config, err = XDGConfigDir(); err != nil {
+		return "", "", err
+	}
+	if data, err = XDGDataDir(); err != nil {
+		return "", "", err
+	}
+	legacy, err := LegacyDir()
+	if err != nil {
+		return "", "", err
+	}
+	xdg := isDir(data)
+	if !exists(legacy) {
+		xdg = xdg || isDir(config) || os.Getenv("XDG_CONFIG_HOME") != "" || os.Getenv("XDG_DATA_HOME") != ""
+	}
+	if !xdg {
+		return legacy, legacy, nil
+	}
+	return config, data, nil
+}
+
+func xdgDir(env, fallback string) (string, error) {
+	// The spec requires absolute paths; relative values are ignored.
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, "sp"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("home dir: %w", err)
+	}
+	return filepath.Join(home, fallback, "sp"), nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// Expand resolves a leading "~" or "~/" against the user's home
+// directory. Other paths are returned unchanged.
+func Expand(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("expand %q: %w", path, err)
+	}
+	if path == "~" {
+		return home, nil
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~/")), nil
+}
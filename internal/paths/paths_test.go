@@ -0,0 +1,120 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolate points HOME at a temp dir and clears every variable the
+// resolver reads, returning the fake home.
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvHome, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	return home
+}
+
+func mkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirsDefaultToDotSp(t *testing.T) {
+	home := isolate(t)
+	for name, fn := range map[string]func() (string, error){"config": ConfigDir, "data": DataDir} {
+		got, err := fn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(home, ".sp"); got != want {
+			t.Errorf("%s dir = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDirsHonourSPHome(t *testing.T) {
+	home := isolate(t)
+	t.Setenv(EnvHome, "~/Sync/notes")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	want := filepath.Join(home, "Sync", "notes")
+	for name, fn := range map[string]func() (string, error){"config": ConfigDir, "data": DataDir} {
+		if got, _ := fn(); got != want {
+			t.Errorf("%s dir = %q, want SP_HOME %q", name, got, want)
+		}
+	}
+}
+
+func TestXDGUsedOnFreshInstall(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	if got, _ := ConfigDir(); got != filepath.Join(home, "cfg", "sp") {
+		t.Errorf("ConfigDir = %q", got)
+	}
+	if got, _ := DataDir(); got != filepath.Join(home, "data", "sp") {
+		t.Errorf("DataDir = %q", got)
+	}
+}
+
+func TestLegacyDirWinsUntilMigrated(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	mkdir(t, filepath.Join(home, ".sp"))
+	if got, _ := DataDir(); got != filepath.Join(home, ".sp") {
+		t.Errorf("DataDir with legacy install = %q, want ~/.sp", got)
+	}
+
+	mkdir(t, filepath.Join(home, "data", "sp"))
+	if got, _ := DataDir(); got != filepath.Join(home, "data", "sp") {
+		t.Errorf("DataDir after migration = %q, want XDG dir", got)
+	}
+}
+
+func TestExistingSpecDefaultDirIsUsedWithoutEnv(t *testing.T) {
+	home := isolate(t)
+	mkdir(t, filepath.Join(home, ".config", "sp"))
+	if got, _ := ConfigDir(); got != filepath.Join(home, ".config", "sp") {
+		t.Errorf("ConfigDir = %q, want ~/.config/sp", got)
+	}
+	if got, _ := DataDir(); got != filepath.Join(home, ".local", "share", "sp") {
+		t.Errorf("DataDir = %q, want ~/.local/share/sp", got)
+	}
+}
+
+func TestConfigAndDataComeFromOneLayout(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	mkdir(t, filepath.Join(home, ".sp"))
+	mkdir(t, filepath.Join(home, "cfg", "sp"))
+
+	// An XDG config directory does not pull config.toml away from the
+	// days still in ~/.sp.
+	for name, fn := range map[string]func() (string, error){"config": ConfigDir, "data": DataDir} {
+		if got, _ := fn(); got != filepath.Join(home, ".sp") {
+			t.Errorf("%s dir = %q, want ~/.sp", name, got)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	home := isolate(t)
+	tests := map[string]string{
+		"~":         home,
+		"~/a/b":     filepath.Join(home, "a", "b"),
+		"/abs/path": "/abs/path",
+		"rel/path":  "rel/path",
+		"~user/x":   "~user/x",
+	}
+	for in, want := range tests {
+		got, err := Expand(in)
+		if err != nil || got != want {
+			t.Errorf("Expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ProjectDir is the directory holding a project's day files, next to the
+// project's sources.
+const ProjectDir = ".sp"
+
+// ProjectConfig is a project's config overlay, read on top of the
+// global config.toml.
+const ProjectConfig = "sp.toml"
+
+// FindProject walks up from start, the way git looks for .git, to the
+// nearest directory containing a .sp directory or an sp.toml file. The
+// global sp home is never taken for a project, so ~/.sp does not turn
+// everything under $HOME into one.
+func FindProject(start string) (string, bool) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if isFile(filepath.Join(dir, ProjectConfig)) {
+			return dir, true
+		}
+		if store := filepath.Join(dir, ProjectDir); isDir(store) && !IsGlobal(store) {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// IsGlobal reports whether dir is one of the global sp directories:
+// ~/.sp, the config directory or the data directory.
+func IsGlobal(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, global := range []func() (string, error){LegacyDir, ConfigDir, DataDir} {
+		if path, err := global(); err == nil && filepath.Clean(path) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
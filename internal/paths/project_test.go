@@ -0,0 +1,32 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	home := isolate(t)
+	mkdir(t, filepath.Join(home, ".sp"))
+	repo := filepath.Join(home, "src", "repo")
+	deep := filepath.Join(repo, "cmd", "tool")
+	mkdir(t, deep)
+
+	if root, ok := FindProject(deep); ok {
+		t.Errorf("~/.sp is global, not a project: found %q", root)
+	}
+
+	mkdir(t, filepath.Join(repo, ProjectDir))
+	if root, ok := FindProject(deep); !ok || root != repo {
+		t.Errorf("FindProject = %q, %v; want %q", root, ok, repo)
+	}
+
+	nested := filepath.Join(repo, "cmd")
+	if err := os.WriteFile(filepath.Join(nested, ProjectConfig), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if root, ok := FindProject(deep); !ok || root != nested {
+		t.Errorf("nearest marker should win: %q, %v", root, ok)
+	}
+}
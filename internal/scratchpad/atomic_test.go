@@ -0,0 +1,91 @@
+package scratchpad
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// failStep swaps one filesystem step for a failing stub and restores it
+// when the test ends.
+func failStep[T any](t *testing.T, step *T, stub T) {
+	t.Helper()
+	orig := *step
+	*step = stub
+	t.Cleanup(func() { *step = orig })
+}
+
+func TestSaveFailurePreservesPreviousContent(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name   string
+		inject func(t *testing.T)
+	}{
+		{"write", func(t *testing.T) {
+			failStep(t, &writeTemp, func(f *os.File, data []byte) error {
+				// Simulate a disk filling up halfway through the write.
+				_, _ = f.Write(data[:len(data)/2])
+				return errBoom
+			})
+		}},
+		{"sync", func(t *testing.T) {
+			failStep(t, &syncFile, func(*os.File) error { return errBoom })
+		}},
+		{"rename", func(t *testing.T) {
+			failStep(t, &renameFile, func(string, string) error { return errBoom })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := setupTestManager(t)
+			date := "2024-05-05"
+			if err := mgr.Save(&Scratchpad{Date: date, Content: "original", Created: time.Now()}); err != nil {
+				t.Fatalf("seed Save: %v", err)
+			}
+
+			tt.inject(t)
+			err := mgr.Save(&Scratchpad{Date: date, Content: "replacement", Created: time.Now()})
+			if !errors.Is(err, errBoom) {
+				t.Fatalf("Save err = %v, want wrapped %v", err, errBoom)
+			}
+
+			loaded, err := mgr.GetByDate(date)
+			if err != nil {
+				t.Fatalf("GetByDate after failed save: %v", err)
+			}
+			if loaded.Content != "original" {
+				t.Errorf("content = %q, want previous content to survive", loaded.Content)
+			}
+			entries, err := os.ReadDir(mgr.storageDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				names := make([]string, 0, len(entries))
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+				t.Errorf("storage dir = %v, want only the day file (temp file leaked)", names)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicCreatesAndReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "2024-06-06.json")
+	if err := writeFileAtomic(path, []byte("one"), 0o600); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("two"), 0o600); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "two" {
+		t.Errorf("content = %q, want %q", data, "two")
+	}
+}
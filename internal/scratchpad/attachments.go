@@ -0,0 +1,321 @@
+package scratchpad
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// attachmentsDirName holds the files attached to days, one directory per
+// date. It is not hidden: attachments belong with the notes, in git and
+// in synced folders alike.
+const attachmentsDirName = "attachments"
+
+// sealedExt marks an attachment sealed by the store's Cipher.
+const sealedExt = ".sealed"
+
+// Attachment is a file kept with a day, such as a screenshot or a log.
+// The file lives under attachments/<date>/<name> in the storage
+// directory; the day only records it.
+type Attachment struct {
+	Name  string    `json:"name" toml:"name"`
+	Size  int64     `json:"size" toml:"size"`
+	Added time.Time `json:"added" toml:"added"`
+}
+
+// AttachmentLink is the Markdown link Attach adds to a day's content.
+// Images are embedded. The target is relative to the storage directory,
+// where flat Markdown days live.
+func AttachmentLink(date string, a Attachment) string {
+	target := (&url.URL{Path: path.Join(attachmentsDirName, date, a.Name)}).EscapedPath()
+	link := "[" + a.Name + "](" + target + ")"
+	if strings.HasPrefix(mime.TypeByExtension(filepath.Ext(a.Name)), "image/") {
+		link = "!" + link
+	}
+	return link
+}
+
+func (m *Manager) attachmentDir(date string) string {
+	return filepath.Join(m.storageDir, attachmentsDirName, date)
+}
+
+// Attach copies r into date's attachments as name, records it in the
+// day's metadata and appends a link to the content. A name the day
+// already uses gets a numbered suffix ("shot-2.png"). In an encrypted
+// store the file is sealed too, which is why names ending in sealedExt
+// are refused: such a file would be taken for a sealed one.
+func (m *Manager) Attach(date, name string, r io.Reader) (*Attachment, error) {
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid attachment name %q", name)
+	}
+	if strings.HasSuffix(strings.ToLower(name), sealedExt) {
+		return nil, fmt.Errorf("invalid attachment name %q: %s marks encrypted attachments; rename the file", name, sealedExt)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	scratchpad, err := m.GetByDate(date)
+	if err != nil {
+		return nil, err
+	}
+	name = m.freeAttachmentName(scratchpad, name)
+	if err := m.writeAttachmentAt(m.attachmentDir(date), date, name, data, m.cipher); err != nil {
+		return nil, err
+	}
+
+	a := Attachment{Name: name, Size: int64(len(data)), Added: time.Now()}
+	addAttachment(scratchpad, a)
+	if err := m.save(scratchpad, fmt.Sprintf("Attach %s to %s", name, date)); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// addAttachment records a in the day's metadata and appends a link to it
+// to the content.
+func addAttachment(scratchpad *Scratchpad, a Attachment) {
+	scratchpad.Attachments = append(scratchpad.Attachments, a)
+	content := scratchpad.Content
+	if content != "" {
+		content = strings.TrimRight(content, "\n") + "\n\n"
+	}
+	scratchpad.Content = content + AttachmentLink(scratchpad.Date, a) + "\n"
+}
+
+// freeAttachmentName returns name, or name with the first free number
+// appended before its extension.
+func (m *Manager) freeAttachmentName(scratchpad *Scratchpad, name string) string {
+	taken := func(candidate string) bool {
+		for _, a := range scratchpad.Attachments {
+			if a.Name == candidate {
+				return true
+			}
+		}
+		plain := filepath.Join(m.attachmentDir(scratchpad.Date), candidate)
+		return exists(plain) || exists(plain+sealedExt)
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 2; taken(name); n++ {
+		name = stem + "-" + strconv.Itoa(n) + ext
+	}
+	return name
+}
+
+// writeAttachmentAt stores data in dir as date's attachment name, sealed
+// under c when it is not nil, and removes the other form of the file.
+func (m *Manager) writeAttachmentAt(dir, date, name string, data []byte, c Cipher) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create attachments directory: %w", err)
+	}
+	plain := filepath.Join(dir, name)
+	target, stale := plain, plain+sealedExt
+	perm := fs.FileMode(0o644)
+	if c != nil {
+		sealed, err := c.Seal(data, attachmentAD(date, name))
+		if err != nil {
+			return fmt.Errorf("seal attachment: %w", err)
+		}
+		data, target, stale, perm = sealed, plain+sealedExt, plain, 0o600
+	}
+	if err := writeFileAtomic(target, data, perm); err != nil {
+		return err
+	}
+	if err := os.Remove(stale); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove stale attachment: %w", err)
+	}
+	return nil
+}
+
+// attachmentAD binds a sealed attachment to its day and name.
+func attachmentAD(date, name string) []byte {
+	return []byte(date + "/" + name)
+}
+
+// ReadAttachment returns the content of date's attachment name, opening
+// it when it is sealed.
+func (m *Manager) ReadAttachment(date, name string) ([]byte, error) {
+	plain := filepath.Join(m.attachmentDir(date), filepath.Base(name))
+	data, err := os.ReadFile(plain)
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	sealed, err := os.ReadFile(plain + sealedExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if m.cipher == nil {
+		return nil, ErrLocked
+	}
+	data, err = m.cipher.Open(sealed, attachmentAD(date, filepath.Base(name)))
+	if err != nil {
+		return nil, fmt.Errorf("open sealed attachment: %w", err)
+	}
+	return data, nil
+}
+
+// addAttachments records the attachments of other that day lacks, as
+// when two versions of a day are merged: the files themselves are shared.
+func addAttachments(day *Scratchpad, other []Attachment) {
+	for _, a := range other {
+		known := false
+		for _, have := range day.Attachments {
+			known = known || have.Name == a.Name
+		}
+		if !known {
+			day.Attachments = append(day.Attachments, a)
+		}
+	}
+}
+
+// trashAttachments moves date's attachments next to the day's trashed
+// file at trashed, into a directory named like it without the extension.
+func (m *Manager) trashAttachments(date, trashed string) error {
+	dir := m.attachmentDir(date)
+	if !exists(dir) {
+		return nil
+	}
+	if err := os.Rename(dir, strings.TrimSuffix(trashed, filepath.Ext(trashed))); err != nil {
+		return fmt.Errorf("move %s attachments to trash: %w", date, err)
+	}
+	m.pruneEmptyParents(dir)
+	return nil
+}
+
+// restoreAttachments moves the attachments trashed with the day file at
+// trashed back into date's attachments directory. Files present there
+// already are kept.
+func (m *Manager) restoreAttachments(date, trashed string) error {
+	from := strings.TrimSuffix(trashed, filepath.Ext(trashed))
+	entries, err := os.ReadDir(from)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read trashed attachments: %w", err)
+	}
+	dir := m.attachmentDir(date)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create attachments directory: %w", err)
+	}
+	for _, entry := range entries {
+		target := filepath.Join(dir, entry.Name())
+		if exists(target) {
+			continue
+		}
+		if err := os.Rename(filepath.Join(from, entry.Name()), target); err != nil {
+			return fmt.Errorf("restore attachment %s: %w", entry.Name(), err)
+		}
+	}
+	_ = os.Remove(from) // left in the trash when a name was taken
+	return nil
+}
+
+// recryptAttachments seals or opens every attachment file in dir, which
+// belongs to date, like Recrypt does for days. It returns the number of
+// files rewritten.
+func (m *Manager) recryptAttachments(dir, date string, to Cipher) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	rewritten := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		name, sealed := strings.CutSuffix(entry.Name(), sealedExt)
+		if !sealed && to == nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if sealed {
+			if to != nil {
+				if _, oerr := to.Open(data, attachmentAD(date, name)); oerr == nil {
+					continue // already sealed under the target key
+				}
+			}
+			if m.cipher == nil {
+				return rewritten, fmt.Errorf("%s: %w", path, ErrLocked)
+			}
+			if data, err = m.cipher.Open(data, attachmentAD(date, name)); err != nil {
+				return rewritten, fmt.Errorf("%s: open sealed attachment: %w", path, err)
+			}
+		}
+		if err := m.writeAttachmentAt(dir, date, name, data, to); err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// recryptAllAttachments runs recryptAttachments over the attachments of
+// every day and every trashed day.
+func (m *Manager) recryptAllAttachments(to Cipher) (int, error) {
+	type attachmentDir struct{ path, date string }
+	var dirs []attachmentDir
+	days, err := os.ReadDir(filepath.Join(m.storageDir, attachmentsDirName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to read attachments: %w", err)
+	}
+	for _, day := range days {
+		if day.IsDir() && isDate(day.Name()) {
+			dirs = append(dirs, attachmentDir{m.attachmentDir(day.Name()), day.Name()})
+		}
+	}
+	trashed, err := os.ReadDir(m.trashDir())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to read trash: %w", err)
+	}
+	for _, day := range trashed {
+		if !day.IsDir() || !isDate(day.Name()) {
+			continue
+		}
+		stamps, err := os.ReadDir(filepath.Join(m.trashDir(), day.Name()))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read trash: %w", err)
+		}
+		for _, stamp := range stamps {
+			if stamp.IsDir() {
+				dirs = append(dirs, attachmentDir{filepath.Join(m.trashDir(), day.Name(), stamp.Name()), day.Name()})
+			}
+		}
+	}
+
+	rewritten := 0
+	for _, dir := range dirs {
+		n, err := m.recryptAttachments(dir.path, dir.date, to)
+		rewritten += n
+		if err != nil {
+			return rewritten, err
+		}
+	}
+	return rewritten, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,148 @@
+package scratchpad
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func attach(t *testing.T, mgr *Manager, date, name, data string) *Attachment {
+	t.Helper()
+	a, err := mgr.Attach(date, name, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	return a
+}
+
+func TestAttachCopiesFileAndLinksIt(t *testing.T) {
+	for _, f := range formats {
+		t.Run(string(f), func(t *testing.T) {
+			mgr := setupTestManager(t)
+			mgr.SetFormat(f)
+			saveContent(t, mgr, "2024-03-01", "# Notes\n")
+			attach(t, mgr, "2024-03-01", "/tmp/build log.txt", "failed")
+			if a := attach(t, mgr, "2024-03-01", "shot.png", "png"); a.Name != "shot.png" || a.Size != 3 {
+				t.Errorf("attachment = %+v", a)
+			}
+			if a := attach(t, mgr, "2024-03-01", "shot.png", "png2"); a.Name != "shot-2.png" {
+				t.Errorf("second shot.png stored as %q", a.Name)
+			}
+
+			day, err := mgr.GetByDate("2024-03-01")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "# Notes\n\n[build log.txt](attachments/2024-03-01/build%20log.txt)\n\n" +
+				"![shot.png](attachments/2024-03-01/shot.png)\n\n![shot-2.png](attachments/2024-03-01/shot-2.png)\n"
+			if day.Content != want {
+				t.Errorf("content = %q", day.Content)
+			}
+			if len(day.Attachments) != 3 || day.Attachments[2].Name != "shot-2.png" || day.Attachments[2].Added.IsZero() {
+				t.Errorf("metadata = %+v", day.Attachments)
+			}
+			if data, err := os.ReadFile(filepath.Join(mgr.Dir(), "attachments", "2024-03-01", "build log.txt")); err != nil || string(data) != "failed" {
+				t.Errorf("stored file = %q, %v", data, err)
+			}
+			if dates, _ := mgr.ListDates(); len(dates) != 1 {
+				t.Errorf("attachments should not be listed as days: %v", dates)
+			}
+		})
+	}
+}
+
+func TestAttachRejectsHiddenNames(t *testing.T) {
+	mgr := setupTestManager(t)
+	if _, err := mgr.Attach("2024-03-01", ".env", strings.NewReader("x")); err == nil {
+		t.Error("a hidden file name should be refused")
+	}
+}
+
+func TestAttachRejectsSealedNames(t *testing.T) {
+	mgr := setupTestManager(t)
+	for _, name := range []string{"backup.sealed", "BACKUP.SEALED"} {
+		if _, err := mgr.Attach("2024-03-01", name, strings.NewReader("x")); err == nil {
+			t.Errorf("%s would be taken for a sealed attachment and should be refused", name)
+		}
+	}
+	if _, err := mgr.Attach("2024-03-01", "backup.sealed.txt", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	for _, to := range []Cipher{testCipher(t, 1), nil} {
+		if _, err := mgr.Recrypt(to); err != nil {
+			t.Fatalf("Recrypt: %v", err)
+		}
+	}
+	if data, err := mgr.ReadAttachment("2024-03-01", "backup.sealed.txt"); err != nil || string(data) != "x" {
+		t.Errorf("ReadAttachment = %q, %v", data, err)
+	}
+}
+
+func TestDeletedDayTakesAttachmentsToTrash(t *testing.T) {
+	mgr := setupTestManager(t)
+	attach(t, mgr, "2024-03-01", "trace.log", "boom")
+	if err := mgr.Delete("2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.Dir(), "attachments")); !os.IsNotExist(err) {
+		t.Error("attachments left behind after delete")
+	}
+
+	if err := mgr.RestoreTrashed("2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := mgr.ReadAttachment("2024-03-01", "trace.log"); err != nil || string(data) != "boom" {
+		t.Errorf("restored attachment = %q, %v", data, err)
+	}
+
+	if err := mgr.Delete("2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := mgr.EmptyTrash(0); err != nil || n != 1 {
+		t.Fatalf("EmptyTrash = %d, %v", n, err)
+	}
+	if entries, _ := os.ReadDir(mgr.trashDir()); len(entries) != 0 {
+		t.Errorf("trash not empty: %v", entries)
+	}
+}
+
+func TestAttachmentsAreSealedInEncryptedStores(t *testing.T) {
+	mgr := setupTestManager(t)
+	attach(t, mgr, "2024-03-01", "plain.txt", "plain secret")
+	attach(t, mgr, "2024-03-02", "gone.txt", "trashed secret")
+	if err := mgr.Delete("2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := testCipher(t, 1)
+	if _, err := mgr.Recrypt(c); err != nil {
+		t.Fatal(err)
+	}
+	attach(t, mgr, "2024-03-01", "new.txt", "sealed secret")
+	if disk := storeBytes(t, mgr); bytes.Contains(disk, []byte("secret")) {
+		t.Errorf("plain text found on disk:\n%s", disk)
+	}
+	for name, want := range map[string]string{"plain.txt": "plain secret", "new.txt": "sealed secret"} {
+		if data, err := mgr.ReadAttachment("2024-03-01", name); err != nil || string(data) != want {
+			t.Errorf("ReadAttachment(%s) = %q, %v", name, data, err)
+		}
+	}
+
+	locked := &Manager{storageDir: mgr.Dir()}
+	if _, err := locked.ReadAttachment("2024-03-01", "new.txt"); !errors.Is(err, ErrLocked) {
+		t.Errorf("reading without the key: %v", err)
+	}
+
+	if _, err := mgr.Recrypt(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.RestoreTrashed("2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(mgr.Dir(), "attachments", "2024-03-02", "gone.txt")); err != nil || string(data) != "trashed secret" {
+		t.Errorf("decrypted trashed attachment = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,61 @@
+package scratchpad
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Change describes a day or page sp just wrote, restored or deleted, for
+// a ChangeHook.
+type Change struct {
+	// Date is the changed day, empty when Page names a page instead.
+	Date string
+	Page string
+	// Summary is a one-line description such as "Update 2024-03-01",
+	// suitable as a commit message.
+	Summary string
+	// Paths lists every location the day may occupy, relative to the
+	// storage directory: a save moves a day between layouts and formats,
+	// so the old file disappears in the same change. The last entry is
+	// the day's attachments directory. A page lists its file in each
+	// format.
+	Paths []string
+}
+
+// ChangeHook is told about each change to a day after it is on disk, e.g.
+// to commit it to git. An error is returned from the operation that made
+// the change, which has nevertheless happened.
+type ChangeHook func(change Change) error
+
+// SetChangeHook installs hook; nil removes it.
+func (m *Manager) SetChangeHook(hook ChangeHook) {
+	m.onChange = hook
+}
+
+// changed runs the change hook for date.
+func (m *Manager) changed(date, summary string) error {
+	if m.onChange == nil {
+		return nil
+	}
+	change := Change{Date: date, Summary: summary}
+	for _, at := range m.candidates() {
+		rel, err := filepath.Rel(m.storageDir, m.dayPath(date, at))
+		if err == nil {
+			change.Paths = append(change.Paths, rel)
+		}
+	}
+	change.Paths = append(change.Paths, filepath.Join(attachmentsDirName, date))
+	return m.notify(change, date)
+}
+
+// notify runs the change hook, naming what changed as subject in the
+// error.
+func (m *Manager) notify(change Change, subject string) error {
+	if m.onChange == nil {
+		return nil
+	}
+	if err := m.onChange(change); err != nil {
+		return fmt.Errorf("%s done, but the change hook failed: %w", subject, err)
+	}
+	return nil
+}
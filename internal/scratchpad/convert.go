@@ -0,0 +1,57 @@
+package scratchpad
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Convert moves every day stored in another format or layout to the given
+// one, keeping content and metadata (including Modified) untouched. A day
+// whose encoding changes is decoded again and compared with the original
+// before the old file is removed, so a lossy round trip aborts instead of
+// destroying data. Revisions are left in their original encoding. It
+// returns the number of days rewritten.
+func (m *Manager) Convert(to Format, layout Layout) (int, error) {
+	dates, err := m.ListDates()
+	if err != nil {
+		return 0, err
+	}
+	target := dayFile{layout: layout, format: to}
+	converted := 0
+	for _, date := range dates {
+		original, raw, from, rerr := m.readDay(date)
+		if rerr != nil {
+			return converted, fmt.Errorf("convert %s: %w", date, rerr)
+		}
+		if m.dayPath(date, from) == m.dayPath(date, target) {
+			continue
+		}
+		data := raw
+		if from.format != to {
+			var eerr error
+			if data, eerr = m.encodeDay(original, to); eerr != nil {
+				return converted, fmt.Errorf("convert %s: %w", date, eerr)
+			}
+			roundTrip, derr := m.decodeDay(data, to)
+			if derr != nil || !sameDay(original, roundTrip) {
+				return converted, fmt.Errorf("convert %s: %s encoding does not round-trip; left unchanged", date, to)
+			}
+		}
+		if werr := m.writeDay(date, target, data); werr != nil {
+			return converted, fmt.Errorf("convert %s: %w", date, werr)
+		}
+		converted++
+	}
+	return converted, nil
+}
+
+func sameDay(a, b *Scratchpad) bool {
+	return a.Date == b.Date &&
+		a.Content == b.Content &&
+		slices.Equal(a.AppliedTemplates, b.AppliedTemplates) &&
+		slices.EqualFunc(a.Attachments, b.Attachments, func(x, y Attachment) bool {
+			return x.Name == y.Name && x.Size == y.Size && x.Added.Equal(y.Added)
+		}) &&
+		a.Created.Equal(b.Created) &&
+		a.Modified.Equal(b.Modified)
+}
@@ -0,0 +1,197 @@
+package scratchpad
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pders01/sp/internal/diff"
+)
+
+// conflictPatterns match the names file-sync tools give the second version
+// of a file changed on two machines at once. The first group is the name
+// of the original file without its extension.
+var conflictPatterns = []struct {
+	tool    string
+	pattern *regexp.Regexp
+}{
+	// 2025-03-04.sync-conflict-20250304-101112-ABCDEFG.json
+	{"Syncthing", regexp.MustCompile(`^(.+)\.sync-conflict-\d{8}-\d{6}(?:-[A-Z0-9]+)?$`)},
+	// 2025-03-04 (conflicted copy 2025-03-04 101112).json
+	{"Nextcloud", regexp.MustCompile(`^(.+) \(conflicted copy \d{4}-\d{2}-\d{2} \d{6}\)$`)},
+	// 2025-03-04 (Laptop's conflicted copy 2025-03-04).json
+	{"Dropbox", regexp.MustCompile(`^(.+) \([^()]*conflicted copy[^()]*\)$`)},
+	// 2025-03-04_conflict-20250304-101112.json (ownCloud before 10.8)
+	{"ownCloud", regexp.MustCompile(`^(.+)_conflict-\d{8}-\d{6}$`)},
+}
+
+// parseConflictName reports whether stem, a file name without its format
+// extension, names a sync tool's conflict copy, returning the original
+// stem and the tool.
+func parseConflictName(stem string) (original, tool string, ok bool) {
+	for _, p := range conflictPatterns {
+		if match := p.pattern.FindStringSubmatch(stem); match != nil {
+			return match[1], p.tool, true
+		}
+	}
+	return "", "", false
+}
+
+// ConflictCopy is a second version of a day left by a file-sync tool such
+// as Syncthing or Dropbox. It is never listed as a day of its own.
+type ConflictCopy struct {
+	Date string
+	// Path is the copy's file, relative to the storage directory.
+	Path string
+	// Tool names the sync tool whose naming scheme matched.
+	Tool   string
+	format Format
+}
+
+// ConflictCopies lists the conflict copies in either layout, by date and
+// then path.
+func (m *Manager) ConflictCopies() ([]ConflictCopy, error) {
+	files, err := m.scanDayFiles()
+	if err != nil {
+		return nil, err
+	}
+	var copies []ConflictCopy
+	for _, file := range files {
+		if c, ok := m.conflictCopy(file); ok {
+			copies = append(copies, c)
+		}
+	}
+	sort.Slice(copies, func(i, j int) bool {
+		if copies[i].Date != copies[j].Date {
+			return copies[i].Date < copies[j].Date
+		}
+		return copies[i].Path < copies[j].Path
+	})
+	return copies, nil
+}
+
+// conflictCopy recognizes a scanned file as a conflict copy. A nested
+// file's date is its directory plus the day the copy's name starts with.
+func (m *Manager) conflictCopy(file storedFile) (ConflictCopy, bool) {
+	if isDate(file.date) {
+		return ConflictCopy{}, false
+	}
+	rel, err := filepath.Rel(m.storageDir, file.path)
+	if err != nil {
+		return ConflictCopy{}, false
+	}
+	stem := strings.TrimSuffix(filepath.Base(file.path), filepath.Ext(file.path))
+	original, tool, ok := parseConflictName(stem)
+	if !ok {
+		return ConflictCopy{}, false
+	}
+	date := original
+	if file.at.layout == LayoutNested {
+		dir := filepath.ToSlash(filepath.Dir(rel))
+		date = strings.ReplaceAll(dir, "/", "-") + "-" + original
+	}
+	if !isDate(date) {
+		return ConflictCopy{}, false
+	}
+	return ConflictCopy{Date: date, Path: rel, Tool: tool, format: file.at.format}, true
+}
+
+// ConflictMerge holds the versions a three-way merge of a conflict copy
+// works from.
+type ConflictMerge struct {
+	Copy ConflictCopy
+	// Local is the day as sp reads it; Remote is the conflict copy.
+	Local  *Scratchpad
+	Remote *Scratchpad
+	// Base is the newest revision in the day's history that predates
+	// both versions, or nil when history has none.
+	Base *Scratchpad
+}
+
+// LoadConflict reads both versions of a conflict copy's day and looks up
+// their common base in history.
+func (m *Manager) LoadConflict(c ConflictCopy) (*ConflictMerge, error) {
+	data, err := os.ReadFile(filepath.Join(m.storageDir, c.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conflict copy: %w", err)
+	}
+	remote, err := m.decodeDay(data, c.format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", c.Path, err)
+	}
+	local, err := m.GetByDate(c.Date)
+	if err != nil {
+		return nil, err
+	}
+	merge := &ConflictMerge{Copy: c, Local: local, Remote: remote}
+
+	cutoff := local.Modified
+	if remote.Modified.Before(cutoff) {
+		cutoff = remote.Modified
+	}
+	revs, err := m.History(c.Date)
+	if err != nil {
+		return nil, err
+	}
+	for _, rev := range revs {
+		// The older side's own save is not a base: merging against it
+		// would read that side as unchanged and drop its edit.
+		if !rev.Saved.Before(cutoff) {
+			continue
+		}
+		if base, err := m.LoadRevision(c.Date, rev.ID); err == nil {
+			merge.Base = base
+		}
+		break
+	}
+	return merge, nil
+}
+
+// Merged combines both versions against their base, returning the result
+// and its number of conflicting regions. Regions changed on both sides
+// keep both versions between conflict markers; without a base the whole
+// day is one such region unless the versions agree.
+func (c *ConflictMerge) Merged() (string, int) {
+	base := ""
+	if c.Base != nil {
+		base = c.Base.Content
+	}
+	chunks := diff.Merge3(base, c.Local.Content, c.Remote.Content)
+	return diff.Text(chunks, "this machine", c.Copy.Tool+" copy"), diff.Conflicts(chunks)
+}
+
+// ResolveConflict saves content as the conflict copy's day, with the
+// templates applied on either side, and moves the copy to the trash,
+// apart from the deleted days.
+func (m *Manager) ResolveConflict(c ConflictCopy, content string) error {
+	merge, err := m.LoadConflict(c)
+	if err != nil {
+		return err
+	}
+	day := merge.Local
+	day.Content = content
+	for _, id := range merge.Remote.AppliedTemplates {
+		if !slices.Contains(day.AppliedTemplates, id) {
+			day.AppliedTemplates = append(day.AppliedTemplates, id)
+		}
+	}
+	addAttachments(day, merge.Remote.Attachments)
+	if err := m.save(day, fmt.Sprintf("Merge %s conflict in %s", c.Tool, c.Date)); err != nil {
+		return err
+	}
+	dir := filepath.Join(m.trashDir(), resolvedDirName, c.Date)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create trash directory: %w", err)
+	}
+	path := filepath.Join(m.storageDir, c.Path)
+	if err := os.Rename(path, stampedPath(dir, time.Now(), c.format)); err != nil {
+		return fmt.Errorf("move %s to trash: %w", c.Path, err)
+	}
+	m.pruneEmptyParents(path)
+	return nil
+}
@@ -0,0 +1,239 @@
+package scratchpad
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseConflictName(t *testing.T) {
+	tests := []struct {
+		stem     string
+		original string
+		tool     string
+	}{
+		{"2025-03-04.sync-conflict-20250304-101112-ABCDEFG", "2025-03-04", "Syncthing"},
+		{"04.sync-conflict-20250304-101112", "04", "Syncthing"},
+		{"2025-03-04 (conflicted copy 2025-03-04 101112)", "2025-03-04", "Nextcloud"},
+		{"2025-03-04 (Laptop's conflicted copy 2025-03-04)", "2025-03-04", "Dropbox"},
+		{"2025-03-04 (conflicted copy)", "2025-03-04", "Dropbox"},
+		{"2025-03-04_conflict-20250304-101112", "2025-03-04", "ownCloud"},
+		{"2025-03-04", "", ""},
+		{"README", "", ""},
+		{"notes (copy)", "", ""},
+	}
+	for _, tt := range tests {
+		original, tool, ok := parseConflictName(tt.stem)
+		if ok != (tt.tool != "") || original != tt.original || tool != tt.tool {
+			t.Errorf("parseConflictName(%q) = %q, %q, %v; want %q, %q", tt.stem, original, tool, ok, tt.original, tt.tool)
+		}
+	}
+}
+
+// writeCopy stores a conflict copy of a day at rel.
+func writeCopy(t *testing.T, mgr *Manager, rel string, day Scratchpad) {
+	t.Helper()
+	data, err := json.Marshal(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(mgr.Dir(), rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConflictCopiesAreNotDays(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2025-03-04", "mine")
+	writeCopy(t, mgr, "2025-03-04.sync-conflict-20250304-101112-ABCDEFG.json", Scratchpad{Date: "2025-03-04", Content: "theirs"})
+	writeCopy(t, mgr, filepath.Join("2025", "03", "05 (Laptop's conflicted copy 2025-03-05).json"), Scratchpad{Date: "2025-03-05"})
+
+	dates, err := mgr.ListDates()
+	if err != nil || len(dates) != 1 || dates[0] != "2025-03-04" {
+		t.Errorf("ListDates = %v, %v", dates, err)
+	}
+	copies, err := mgr.ConflictCopies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != 2 {
+		t.Fatalf("ConflictCopies = %+v", copies)
+	}
+	if copies[0].Date != "2025-03-04" || copies[0].Tool != "Syncthing" {
+		t.Errorf("flat copy = %+v", copies[0])
+	}
+	if copies[1].Date != "2025-03-05" || copies[1].Tool != "Dropbox" {
+		t.Errorf("nested copy = %+v", copies[1])
+	}
+
+	report, err := mgr.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range report.Problems {
+		if p.Check != "conflict" || p.Severity != SeverityWarning || p.Fix != FixNone {
+			t.Errorf("doctor problem = %+v, want an unfixable conflict warning", p)
+		}
+	}
+	if len(report.Problems) != 2 {
+		t.Errorf("doctor reported %d problems, want 2", len(report.Problems))
+	}
+}
+
+func TestLoadConflictMergesAgainstHistory(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	saveContent(t, mgr, "2025-03-04", "a\nb\nc\nd\n")
+	base, err := mgr.GetByDate("2025-03-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := base.Modified.Add(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	saveContent(t, mgr, "2025-03-04", "a\nB\nc\nd\n")
+	writeCopy(t, mgr, "2025-03-04.sync-conflict-20250304-101112.json", Scratchpad{
+		Date: "2025-03-04", Content: "a\nb\nc\nD\n", Created: base.Created, Modified: copied,
+	})
+
+	copies, err := mgr.ConflictCopies()
+	if err != nil || len(copies) != 1 {
+		t.Fatalf("ConflictCopies = %+v, %v", copies, err)
+	}
+	merge, err := mgr.LoadConflict(copies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.Base == nil || merge.Base.Content != "a\nb\nc\nd\n" {
+		t.Fatalf("base = %+v, want the revision both versions started from", merge.Base)
+	}
+	if merged, conflicts := merge.Merged(); merged != "a\nB\nc\nD\n" || conflicts != 0 {
+		t.Errorf("Merged = %q, %d", merged, conflicts)
+	}
+}
+
+func TestLoadConflictKeepsTheOlderSidesEdit(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	saveContent(t, mgr, "2025-03-05", "a\nb\nc\nd\n")
+	time.Sleep(5 * time.Millisecond)
+	saveContent(t, mgr, "2025-03-05", "a\nB\nc\nd\n")
+	local, err := mgr.GetByDate("2025-03-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The copy was saved after the local edit, from the earlier version.
+	writeCopy(t, mgr, "2025-03-05.sync-conflict-20250305-101112.json", Scratchpad{
+		Date: "2025-03-05", Content: "a\nb\nc\nD\n", Created: local.Created, Modified: local.Modified.Add(time.Second),
+	})
+
+	copies, err := mgr.ConflictCopies()
+	if err != nil || len(copies) != 1 {
+		t.Fatalf("ConflictCopies = %+v, %v", copies, err)
+	}
+	merge, err := mgr.LoadConflict(copies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.Base == nil || merge.Base.Content != "a\nb\nc\nd\n" {
+		t.Fatalf("base = %+v, want the revision before the local edit", merge.Base)
+	}
+	if merged, conflicts := merge.Merged(); merged != "a\nB\nc\nD\n" || conflicts != 0 {
+		t.Errorf("Merged = %q, %d", merged, conflicts)
+	}
+}
+
+func TestResolveConflictUnionsTemplatesAndTrashesCopy(t *testing.T) {
+	mgr := setupTestManager(t)
+	mine := &Scratchpad{Date: "2025-03-04", Content: "mine", AppliedTemplates: []string{"standup"}}
+	if err := mgr.Save(mine); err != nil {
+		t.Fatal(err)
+	}
+	rel := "2025-03-04 (conflicted copy 2025-03-04 101112).json"
+	writeCopy(t, mgr, rel, Scratchpad{
+		Date: "2025-03-04", Content: "theirs", AppliedTemplates: []string{"retro", "standup"},
+	})
+
+	copies, err := mgr.ConflictCopies()
+	if err != nil || len(copies) != 1 {
+		t.Fatalf("ConflictCopies = %+v, %v", copies, err)
+	}
+	merge, err := mgr.LoadConflict(copies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, conflicts := merge.Merged()
+	if conflicts != 1 || !HasConflict(merged) {
+		t.Fatalf("merge without a common revision = %q, %d", merged, conflicts)
+	}
+	if err := mgr.ResolveConflict(copies[0], "both"); err != nil {
+		t.Fatal(err)
+	}
+
+	day, err := mgr.GetByDate("2025-03-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day.Content != "both" {
+		t.Errorf("content = %q", day.Content)
+	}
+	if len(day.AppliedTemplates) != 2 || day.AppliedTemplates[0] != "standup" || day.AppliedTemplates[1] != "retro" {
+		t.Errorf("applied templates = %v, want [standup retro]", day.AppliedTemplates)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.Dir(), rel)); !os.IsNotExist(err) {
+		t.Error("conflict copy left in place")
+	}
+	if copies, _ := mgr.ConflictCopies(); len(copies) != 0 {
+		t.Errorf("copies after resolving = %+v", copies)
+	}
+	if trashed, _ := mgr.Trash(); len(trashed) != 0 {
+		t.Errorf("trash = %+v, want no days", trashed)
+	}
+	resolved, _ := filepath.Glob(filepath.Join(mgr.Dir(), trashDirName, resolvedDirName, "2025-03-04", "*.json"))
+	if len(resolved) != 1 {
+		t.Errorf("resolved copies in the trash = %v, want one", resolved)
+	}
+}
+
+func TestRestoreTrashAfterResolvingConflict(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := mgr.Save(&Scratchpad{Date: "2025-03-04", Content: "mine"}); err != nil {
+		t.Fatal(err)
+	}
+	writeCopy(t, mgr, "2025-03-04 (conflicted copy 2025-03-04 101112).json", Scratchpad{Date: "2025-03-04", Content: "theirs"})
+	copies, err := mgr.ConflictCopies()
+	if err != nil || len(copies) != 1 {
+		t.Fatalf("ConflictCopies = %+v, %v", copies, err)
+	}
+	if err := mgr.ResolveConflict(copies[0], "both"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Delete("2025-03-04"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.RestoreTrashed("2025-03-04"); err != nil {
+		t.Fatal(err)
+	}
+	day, err := mgr.GetByDate("2025-03-04")
+	if err != nil || day.Content != "both" {
+		t.Fatalf("restored day = %+v, %v; want the merged day", day, err)
+	}
+	if trashed, _ := mgr.Trash(); len(trashed) != 0 {
+		t.Errorf("trash after restoring = %+v, want the resolved copy left out", trashed)
+	}
+	if err := mgr.RestoreTrashed("2025-03-04"); err == nil {
+		t.Error("restoring again succeeded; the resolved copy was offered as the day")
+	}
+
+	removed, err := mgr.EmptyTrash(0)
+	if err != nil || removed != 0 {
+		t.Errorf("EmptyTrash = %d, %v; want no days", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.Dir(), trashDirName)); !os.IsNotExist(err) {
+		t.Error("resolved copy left in the trash after emptying it")
+	}
+}
@@ -0,0 +1,207 @@
+package scratchpad
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+// quarantineDirName holds files `sp doctor --fix` moved out of the store.
+const quarantineDirName = ".quarantine"
+
+// Severity ranks a Problem.
+type Severity int
+
+const (
+	// SeverityWarning marks files that load but look suspicious.
+	SeverityWarning Severity = iota
+	// SeverityError marks files that cannot be loaded as the day they
+	// claim to be.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Fix is the repair Repair applies to a Problem.
+type Fix int
+
+const (
+	// FixNone means the problem needs a human decision.
+	FixNone Fix = iota
+	// FixQuarantine moves the file into .quarantine/ in the storage
+	// directory, where it no longer affects sp but can still be inspected.
+	FixQuarantine
+	// FixPermissions makes the file readable and writable by its owner and
+	// not writable by anyone else.
+	FixPermissions
+)
+
+// Problem is one finding of Check.
+type Problem struct {
+	// Path is relative to the storage directory.
+	Path     string
+	Check    string
+	Detail   string
+	Severity Severity
+	Fix      Fix
+}
+
+// Report is the result of Check.
+type Report struct {
+	Files    int
+	Problems []Problem
+}
+
+// Check validates every day file in the store: names must be real dates,
+// files must parse, the date field must match the name, created must not
+// be later than modified, and permissions must let the owner (and only the
+// owner) write. A day stored more than once is reported too, as are the
+// conflict copies sync tools leave (see ConflictCopies). Nothing is
+// changed.
+func (m *Manager) Check() (Report, error) {
+	files, err := m.scanDayFiles()
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Files: len(files)}
+	copies := make(map[string][]string)
+	for _, file := range files {
+		rel, _ := filepath.Rel(m.storageDir, file.path)
+		problems := m.checkFile(file, rel)
+		report.Problems = append(report.Problems, problems...)
+		if isDate(file.date) {
+			copies[file.date] = append(copies[file.date], rel)
+		}
+	}
+	for date, paths := range copies {
+		if len(paths) < 2 {
+			continue
+		}
+		used, _, _ := m.locate(date)
+		usedRel, _ := filepath.Rel(m.storageDir, used)
+		for _, rel := range paths {
+			if rel == usedRel {
+				continue
+			}
+			report.Problems = append(report.Problems, Problem{
+				Path:     rel,
+				Check:    "duplicate",
+				Detail:   fmt.Sprintf("%s is also stored as %s, which sp reads; this copy is deleted on the next save", date, usedRel),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return report.Problems[i].Path < report.Problems[j].Path
+	})
+	return report, nil
+}
+
+func (m *Manager) checkFile(file storedFile, rel string) []Problem {
+	var problems []Problem
+	add := func(check, detail string, severity Severity, fix Fix) {
+		problems = append(problems, Problem{Path: rel, Check: check, Detail: detail, Severity: severity, Fix: fix})
+	}
+
+	if !isDate(file.date) {
+		if c, ok := m.conflictCopy(file); ok {
+			add("conflict", fmt.Sprintf("%s conflict copy of %s; merge it with sp conflicts", c.Tool, c.Date), SeverityWarning, FixNone)
+			return problems
+		}
+		if file.at.layout == LayoutFlat && file.at.format == FormatMarkdown {
+			// Markdown notes beside the days (README.md and the like)
+			// are expected and never listed as days.
+			return nil
+		}
+		add("filename", fmt.Sprintf("%q is not a valid YYYY-MM-DD date", file.date), SeverityError, FixQuarantine)
+		return problems
+	}
+
+	permsOK := true
+	if info, err := os.Stat(file.path); err == nil && runtime.GOOS != "windows" {
+		perm := info.Mode().Perm()
+		switch {
+		case perm&0o600 != 0o600:
+			add("permissions", fmt.Sprintf("mode %s is not readable and writable by you", perm), SeverityError, FixPermissions)
+			permsOK = false
+		case perm&0o022 != 0:
+			add("permissions", fmt.Sprintf("mode %s is writable by others", perm), SeverityWarning, FixPermissions)
+		}
+	}
+
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		if permsOK {
+			add("read", err.Error(), SeverityError, FixNone)
+		}
+		return problems
+	}
+	scratchpad, err := m.decodeDay(data, file.at.format)
+	switch {
+	case errors.Is(err, ErrLocked):
+		add("encrypted", "content is sealed; unlock the store to check it", SeverityWarning, FixNone)
+		return problems
+	case errors.Is(err, ErrNewerSchema):
+		add("schema", err.Error()+"; upgrade sp to read it", SeverityWarning, FixNone)
+		return problems
+	case err != nil:
+		add("parse", err.Error(), SeverityError, FixQuarantine)
+		return problems
+	}
+	if scratchpad.Date != "" && scratchpad.Date != file.date {
+		add("date", fmt.Sprintf("date field %q does not match the file name", scratchpad.Date), SeverityError, FixQuarantine)
+	}
+	if !scratchpad.Created.IsZero() && !scratchpad.Modified.IsZero() && scratchpad.Created.After(scratchpad.Modified) {
+		add("timestamps", fmt.Sprintf("created %s is later than modified %s",
+			scratchpad.Created.Format("2006-01-02 15:04:05"), scratchpad.Modified.Format("2006-01-02 15:04:05")),
+			SeverityWarning, FixNone)
+	}
+	return problems
+}
+
+// Repair applies p.Fix and describes what it did. Quarantined files keep
+// their path below .quarantine/; a numeric suffix avoids overwriting an
+// earlier quarantined copy.
+func (m *Manager) Repair(p Problem) (string, error) {
+	path := filepath.Join(m.storageDir, p.Path)
+	switch p.Fix {
+	case FixQuarantine:
+		target := filepath.Join(m.storageDir, quarantineDirName, p.Path)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return "", fmt.Errorf("create quarantine directory: %w", err)
+		}
+		for n := 1; ; n++ {
+			if _, err := os.Lstat(target); errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			target = filepath.Join(m.storageDir, quarantineDirName, p.Path+"."+strconv.Itoa(n))
+		}
+		if err := os.Rename(path, target); err != nil {
+			return "", fmt.Errorf("quarantine %s: %w", p.Path, err)
+		}
+		m.pruneEmptyParents(path)
+		rel, _ := filepath.Rel(m.storageDir, target)
+		return "moved to " + rel, nil
+	case FixPermissions:
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		perm := info.Mode().Perm()&^0o022 | 0o600
+		if err := os.Chmod(path, perm); err != nil {
+			return "", fmt.Errorf("chmod %s: %w", p.Path, err)
+		}
+		return "mode set to " + perm.String(), nil
+	}
+	return "", fmt.Errorf("%s: no automatic fix", p.Path)
+}
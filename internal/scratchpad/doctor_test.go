@@ -0,0 +1,117 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeStoreFile(t *testing.T, mgr *Manager, rel, data string, perm os.FileMode) {
+	t.Helper()
+	path := filepath.Join(mgr.storageDir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func problemsByPath(report Report) map[string]Problem {
+	byPath := make(map[string]Problem, len(report.Problems))
+	for _, p := range report.Problems {
+		byPath[p.Path] = p
+	}
+	return byPath
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-01-01", "healthy")
+	writeStoreFile(t, mgr, "notes.json", `{}`, 0o644)
+	writeStoreFile(t, mgr, "2024-02-30.json", `{}`, 0o644)
+	writeStoreFile(t, mgr, "2024-01-02.json", `{broken`, 0o644)
+	writeStoreFile(t, mgr, "2024-01-03.json", `{"schema_version":1,"date":"2024-01-04"}`, 0o644)
+	writeStoreFile(t, mgr, "2024-01-05.json",
+		`{"schema_version":1,"date":"2024-01-05","created":"2024-01-06T00:00:00Z","modified":"2024-01-05T00:00:00Z"}`, 0o644)
+	writeStoreFile(t, mgr, "2024/01/01.md", "duplicate", 0o644)
+	writeStoreFile(t, mgr, "README.md", "not a day", 0o644)
+
+	report, err := mgr.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"notes.json":      "filename",
+		"2024-02-30.json": "filename",
+		"2024-01-02.json": "parse",
+		"2024-01-03.json": "date",
+		"2024-01-05.json": "timestamps",
+		"2024/01/01.md":   "duplicate",
+	}
+	got := problemsByPath(report)
+	for path, check := range want {
+		if got[path].Check != check {
+			t.Errorf("%s: check = %q, want %q", path, got[path].Check, check)
+		}
+	}
+	if len(report.Problems) != len(want) {
+		t.Errorf("problems = %+v", report.Problems)
+	}
+	if report.Files != 8 {
+		t.Errorf("files = %d, want 8 (README.md is scanned but not flagged)", report.Files)
+	}
+}
+
+func TestListDatesSkipsStrayJSON(t *testing.T) {
+	mgr := setupTestManager(t)
+	writeStoreFile(t, mgr, "settings.json", `{}`, 0o644)
+	writeStoreFile(t, mgr, "2024-01-01.json", `{}`, 0o644)
+	dates, err := mgr.ListDates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 1 || dates[0] != "2024-01-01" {
+		t.Errorf("dates = %v", dates)
+	}
+}
+
+func TestRepairQuarantinesAndFixesPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not enforced on Windows")
+	}
+	mgr := setupTestManager(t)
+	writeStoreFile(t, mgr, "2024/01/02.json", `{broken`, 0o644)
+	writeStoreFile(t, mgr, ".quarantine/2024/01/02.json", `older`, 0o644)
+	writeStoreFile(t, mgr, "2024-01-03.json", `{"schema_version":1,"date":"2024-01-03"}`, 0o666)
+
+	report, err := mgr.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range report.Problems {
+		if _, err := mgr.Repair(p); err != nil {
+			t.Fatalf("Repair(%+v): %v", p, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, ".quarantine", "2024", "01", "02.json.1")); err != nil {
+		t.Errorf("quarantined copy missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2024")); !os.IsNotExist(err) {
+		t.Error("emptied year directory should be pruned")
+	}
+	info, err := os.Stat(filepath.Join(mgr.storageDir, "2024-01-03.json"))
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("mode after repair = %v, %v", info.Mode().Perm(), err)
+	}
+
+	report, err = mgr.Check()
+	if err != nil || len(report.Problems) != 0 {
+		t.Errorf("after repair: %+v, %v", report.Problems, err)
+	}
+}
@@ -0,0 +1,162 @@
+package scratchpad
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Entry is a line of quick capture for AddEntry.
+type Entry struct {
+	Text string
+	// Section is the title of the "## " section the entry goes under,
+	// created at the end of the day when missing. Empty appends to the
+	// end of the day.
+	Section string
+	// Todo makes the entry a "- [ ]" task instead of a plain bullet.
+	Todo bool
+	// At is the time the entry is stamped with.
+	At time.Time
+}
+
+// markdown renders e as a list item. Further lines of a multi-line text
+// are indented so they stay part of the item. Trailing blanks are trimmed
+// except inside fenced code blocks, which are kept as written.
+func (e Entry) markdown() string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(e.Text, "\r\n", "\n")), "\n")
+	marker := "- "
+	if e.Todo {
+		marker = "- [ ] "
+	}
+	item := marker + e.At.Format("15:04") + " " + strings.TrimRight(lines[0], " \t")
+	var open string // the fence of the code block the line is in
+	for _, line := range lines[1:] {
+		item += "\n"
+		switch f := codeFence(line); {
+		case open == "" && f != "":
+			open = f
+		case open != "" && strings.HasPrefix(f, open) && strings.TrimSpace(line) == f:
+			open = ""
+		case open != "":
+			if line != "" {
+				item += "  " + line
+			}
+			continue
+		}
+		if line = strings.TrimRight(line, " \t"); line != "" {
+			item += "  " + line
+		}
+	}
+	return item
+}
+
+// codeFence returns the run of backticks or tildes line opens or closes a
+// fenced code block with, or "" when it is not a fence.
+func codeFence(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// AddEntry appends e to date, under its section when it names one, and
+// saves the day. Consecutive entries form one list.
+func (m *Manager) AddEntry(date string, e Entry) (*Scratchpad, error) {
+	if strings.TrimSpace(e.Text) == "" {
+		return nil, fmt.Errorf("nothing to add")
+	}
+	scratchpad, err := m.GetByDate(date)
+	if err != nil {
+		return nil, err
+	}
+	scratchpad.Content = addEntry(scratchpad.Content, e)
+	summary := "Add to " + date
+	if e.Section != "" {
+		summary += " (" + strings.TrimSpace(e.Section) + ")"
+	}
+	if err := m.save(scratchpad, summary); err != nil {
+		return nil, err
+	}
+	return scratchpad, nil
+}
+
+// addEntry is the Store-independent part of AddEntry: it returns content
+// with e added.
+func addEntry(content string, e Entry) string {
+	item := strings.Split(e.markdown(), "\n")
+	var lines []string
+	if strings.TrimSpace(content) != "" {
+		lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+	}
+	start, end := 0, len(lines)
+	if title := strings.TrimSpace(e.Section); title != "" {
+		heading := findSection(lines, title)
+		if heading < 0 {
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "## "+title, "")
+			return strings.Join(append(lines, item...), "\n") + "\n"
+		}
+		start, end = heading+1, sectionEnd(lines, heading)
+	}
+
+	// Insert after the section's last non-blank line, continuing a list
+	// that ends there and leaving a blank line before what follows.
+	at := end
+	for at > start && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	var insert []string
+	if at > 0 && !isListLine(lines[at-1]) {
+		insert = append(insert, "")
+	}
+	insert = append(insert, item...)
+	if at == end && end < len(lines) {
+		insert = append(insert, "")
+	}
+	lines = append(lines[:at], append(insert, lines[at:]...)...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// findSection returns the index of the "## title" heading, compared
+// without regard to case, or -1.
+func findSection(lines []string, title string) int {
+	for i, line := range lines {
+		if text, ok := strings.CutPrefix(strings.TrimSpace(line), "## "); ok && strings.EqualFold(strings.TrimSpace(text), title) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sectionEnd returns the index of the first heading of the same or a
+// higher level after heading, or len(lines).
+func sectionEnd(lines []string, heading int) int {
+	for i := heading + 1; i < len(lines); i++ {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "## ") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// isListLine reports whether line belongs to a Markdown list: an item or
+// an indented continuation of one.
+func isListLine(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed != line {
+		return true
+	}
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(trimmed, marker) {
+			return true
+		}
+	}
+	return false
+}
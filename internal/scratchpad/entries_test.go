@@ -0,0 +1,100 @@
+package scratchpad
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddEntry(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 5, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content string
+		entry   Entry
+		want    string
+	}{
+		{
+			name:  "empty day",
+			entry: Entry{Text: "standup moved"},
+			want:  "- 09:05 standup moved\n",
+		},
+		{
+			name:    "continues a trailing list",
+			content: "# Notes\n\n- 08:00 coffee\n\n",
+			entry:   Entry{Text: "standup moved"},
+			want:    "# Notes\n\n- 08:00 coffee\n- 09:05 standup moved\n",
+		},
+		{
+			name:    "starts a list after a paragraph",
+			content: "Slow morning.",
+			entry:   Entry{Text: "ship it", Todo: true},
+			want:    "Slow morning.\n\n- [ ] 09:05 ship it\n",
+		},
+		{
+			name:    "indents further lines",
+			content: "",
+			entry:   Entry{Text: "panic: boom\r\n\r\ngoroutine 1\n"},
+			want:    "- 09:05 panic: boom\n\n  goroutine 1\n",
+		},
+		{
+			name:  "keeps fenced blocks as written",
+			entry: Entry{Text: "`$ ls` · exit 0  \n\n```\nNAME   \n\n\tREADY  \n```"},
+			want:  "- 09:05 `$ ls` · exit 0\n\n  ```\n  NAME   \n\n  \tREADY  \n  ```\n",
+		},
+		{
+			name:    "into an existing section",
+			content: "# Day\n\n## Todo\n\n- [ ] 08:00 review\n\n## Notes\n\nQuiet.\n",
+			entry:   Entry{Text: "deploy", Section: "todo", Todo: true},
+			want:    "# Day\n\n## Todo\n\n- [ ] 08:00 review\n- [ ] 09:05 deploy\n\n## Notes\n\nQuiet.\n",
+		},
+		{
+			name:    "into an empty section followed by another",
+			content: "## Ideas\n## Notes\n",
+			entry:   Entry{Text: "pages", Section: "Ideas"},
+			want:    "## Ideas\n\n- 09:05 pages\n\n## Notes\n",
+		},
+		{
+			name:    "keeps subsections in the section",
+			content: "## Work\n\n### Meetings\n\n- 08:00 sync\n\n# Later\n",
+			entry:   Entry{Text: "1:1", Section: "Work"},
+			want:    "## Work\n\n### Meetings\n\n- 08:00 sync\n- 09:05 1:1\n\n# Later\n",
+		},
+		{
+			name:    "creates a missing section",
+			content: "# Day\n\nQuiet.\n",
+			entry:   Entry{Text: "pages", Section: "Ideas"},
+			want:    "# Day\n\nQuiet.\n\n## Ideas\n\n- 09:05 pages\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.At = at
+			if got := addEntry(tt.content, tt.entry); got != tt.want {
+				t.Errorf("addEntry() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerAddEntrySaves(t *testing.T) {
+	mgr := setupTestManager(t)
+	var changes []Change
+	mgr.SetChangeHook(func(c Change) error {
+		changes = append(changes, c)
+		return nil
+	})
+	at := time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)
+	if _, err := mgr.AddEntry("2024-03-01", Entry{Text: "call the bank", Todo: true, Section: "Errands", At: at}); err != nil {
+		t.Fatal(err)
+	}
+	day, err := mgr.Lookup("2024-03-01")
+	if err != nil || day.Content != "## Errands\n\n- [ ] 17:30 call the bank\n" {
+		t.Errorf("saved day = %+v, %v", day, err)
+	}
+	if len(changes) != 1 || changes[0].Summary != "Add to 2024-03-01 (Errands)" {
+		t.Errorf("changes = %+v", changes)
+	}
+	if _, err := mgr.AddEntry("2024-03-01", Entry{Text: " \n"}); err == nil {
+		t.Error("an empty entry should be refused")
+	}
+}
@@ -0,0 +1,183 @@
+package scratchpad
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Format selects how day files are encoded on disk.
+type Format string
+
+const (
+	// FormatJSON stores the whole Scratchpad as indented JSON. Default.
+	FormatJSON Format = "json"
+	// FormatMarkdown stores the content verbatim after a TOML front
+	// matter block, so notes stay greppable and editable by other tools.
+	FormatMarkdown Format = "md"
+)
+
+// formats lists every supported encoding. Reads accept any of them, so a
+// store can hold a mix while it is being converted.
+var formats = []Format{FormatJSON, FormatMarkdown}
+
+// ParseFormat maps a config or flag value to a Format. Empty selects
+// FormatJSON.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "json":
+		return FormatJSON, nil
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("unknown storage format %q (want json or md)", s)
+}
+
+func (f Format) ext() string { return "." + string(f) }
+
+func isFormatExt(ext string) bool {
+	for _, f := range formats {
+		if f.ext() == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// frontMatterDelim opens and closes the TOML front matter block, following
+// the Hugo/Zola convention for TOML (YAML uses "---").
+const frontMatterDelim = "+++"
+
+// frontMatter is the metadata block of a Markdown day file.
+type frontMatter struct {
+	SchemaVersion    int       `toml:"schema_version"`
+	Date             string    `toml:"date"`
+	Name             string    `toml:"name,omitempty"`
+	Created          time.Time `toml:"created"`
+	Modified         time.Time `toml:"modified"`
+	AppliedTemplates []string  `toml:"applied_templates,omitempty"`
+	Sealed           string    `toml:"sealed,omitempty"`
+	// Attachments is an array of tables, which TOML writes after the
+	// plain keys.
+	Attachments []Attachment `toml:"attachments,omitempty"`
+}
+
+// encode marshals scratchpad in format f with its content in plain text.
+func encode(scratchpad *Scratchpad, f Format) ([]byte, error) {
+	return encodeStored(&storedDay{Scratchpad: *scratchpad}, f)
+}
+
+func encodeStored(day *storedDay, f Format) ([]byte, error) {
+	scratchpad := &day.Scratchpad
+	if f == FormatMarkdown {
+		var buf bytes.Buffer
+		buf.WriteString(frontMatterDelim + "\n")
+		if err := toml.NewEncoder(&buf).Encode(frontMatter{
+			SchemaVersion:    scratchpad.SchemaVersion,
+			Date:             scratchpad.Date,
+			Name:             scratchpad.Name,
+			Created:          scratchpad.Created,
+			Modified:         scratchpad.Modified,
+			AppliedTemplates: scratchpad.AppliedTemplates,
+			Sealed:           day.Sealed,
+			Attachments:      scratchpad.Attachments,
+		}); err != nil {
+			return nil, err
+		}
+		buf.WriteString(frontMatterDelim + "\n")
+		buf.WriteString(scratchpad.Content)
+		return buf.Bytes(), nil
+	}
+	return json.MarshalIndent(day, "", "  ")
+}
+
+// decodeStored parses data as written, without applying migrations.
+func decodeStored(data []byte, f Format) (*storedDay, error) {
+	var day storedDay
+	if f == FormatMarkdown {
+		meta, content, err := splitFrontMatter(string(data))
+		if err != nil {
+			return nil, err
+		}
+		var fm frontMatter
+		if _, err := toml.Decode(meta, &fm); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+		day = storedDay{
+			Scratchpad: Scratchpad{
+				SchemaVersion:    fm.SchemaVersion,
+				Date:             fm.Date,
+				Name:             fm.Name,
+				Content:          content,
+				AppliedTemplates: fm.AppliedTemplates,
+				Attachments:      fm.Attachments,
+				Created:          fm.Created,
+				Modified:         fm.Modified,
+			},
+			Sealed: fm.Sealed,
+		}
+		return &day, nil
+	}
+	if err := json.Unmarshal(data, &day); err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// splitFrontMatter separates the TOML block from the Markdown body. A file
+// without front matter (for example one created by another editor) is all
+// content. The body is returned byte for byte.
+func splitFrontMatter(text string) (meta, content string, err error) {
+	rest, ok := cutLine(text, frontMatterDelim)
+	if !ok {
+		return "", text, nil
+	}
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end+1]
+		}
+		if strings.TrimRight(line, "\r\n") == frontMatterDelim {
+			return rest[:offset], rest[offset+len(line):], nil
+		}
+		offset += len(line)
+	}
+	return "", "", errors.New("front matter is not closed")
+}
+
+// cutLine strips a first line equal to want (ignoring its line ending).
+func cutLine(text, want string) (string, bool) {
+	for _, eol := range []string{"\n", "\r\n"} {
+		if rest, ok := strings.CutPrefix(text, want+eol); ok {
+			return rest, true
+		}
+	}
+	return text, false
+}
+
+// decodeDocument parses data into a generic document keyed like the JSON
+// encoding, for migrations that need fields the current struct lacks.
+func decodeDocument(data []byte, f Format) (map[string]any, error) {
+	doc := map[string]any{}
+	if f == FormatMarkdown {
+		meta, content, err := splitFrontMatter(string(data))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := toml.Decode(meta, &doc); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+		doc["content"] = content
+		return doc, nil
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
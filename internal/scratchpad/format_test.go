@@ -0,0 +1,169 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 4, 9, 30, 0, 123, time.FixedZone("CET", 3600))
+	original := &Scratchpad{
+		Date:             "2024-03-04",
+		Content:          "# Title\n\n+++\nnot front matter\n---\n- [ ] todo\n",
+		AppliedTemplates: []string{"workday-timebox"},
+		Created:          created,
+		Modified:         created.Add(time.Hour),
+	}
+	data, err := encode(original, FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "+++\n") || !strings.HasSuffix(string(data), original.Content) {
+		t.Errorf("unexpected layout:\n%s", data)
+	}
+	decoded, err := decode(data, FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameDay(original, &decoded.Scratchpad) {
+		t.Errorf("round trip changed day:\n got %+v\nwant %+v", decoded, original)
+	}
+}
+
+func TestDecodeMarkdownWithoutFrontMatter(t *testing.T) {
+	sp, err := decode([]byte("just notes\n"), FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Content != "just notes\n" {
+		t.Errorf("content = %q", sp.Content)
+	}
+	if _, err := decode([]byte("+++\ndate = \"x\"\nno closing"), FormatMarkdown); err == nil {
+		t.Error("expected error for unclosed front matter")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatJSON, "JSON": FormatJSON, "md": FormatMarkdown, "markdown": FormatMarkdown} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestMarkdownManagerSavesMdFiles(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetFormat(FormatMarkdown)
+	if err := mgr.Save(&Scratchpad{Date: "2024-03-05", Content: "grep me\n"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(mgr.storageDir, "2024-03-05.md"))
+	if err != nil {
+		t.Fatalf("md file not written: %v", err)
+	}
+	if !strings.Contains(string(data), "\n+++\ngrep me\n") {
+		t.Errorf("content not stored verbatim:\n%s", data)
+	}
+	loaded, err := mgr.GetByDate("2024-03-05")
+	if err != nil || loaded.Content != "grep me\n" {
+		t.Errorf("GetByDate = %+v, %v", loaded, err)
+	}
+}
+
+func TestSaveReplacesOtherFormat(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := mgr.Save(&Scratchpad{Date: "2024-03-06", Content: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetFormat(FormatMarkdown)
+	loaded, err := mgr.GetByDate("2024-03-06")
+	if err != nil || loaded.Content != "json" {
+		t.Fatalf("json day unreadable after switching format: %+v, %v", loaded, err)
+	}
+	loaded.Content = "markdown"
+	if err := mgr.Save(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2024-03-06.json")); !os.IsNotExist(err) {
+		t.Error("stale json copy should be removed after saving as md")
+	}
+	dates, err := mgr.ListDates()
+	if err != nil || len(dates) != 1 {
+		t.Errorf("ListDates = %v, %v", dates, err)
+	}
+}
+
+func TestListDatesSkipsNonDateMarkdown(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "README.md"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-07.md"), []byte("day"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dates, err := mgr.ListDates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 1 || dates[0] != "2024-03-07" {
+		t.Errorf("dates = %v, want [2024-03-07]", dates)
+	}
+}
+
+func TestConvertBothWaysIsLossless(t *testing.T) {
+	mgr := setupTestManager(t)
+	originals := map[string]*Scratchpad{}
+	for _, date := range []string{"2024-03-08", "2024-03-09"} {
+		sp := &Scratchpad{Date: date, Content: "notes for " + date + "\n", AppliedTemplates: []string{"t"}, Created: time.Now()}
+		if err := mgr.Save(sp); err != nil {
+			t.Fatal(err)
+		}
+		originals[date] = sp
+	}
+
+	for _, to := range []Format{FormatMarkdown, FormatJSON} {
+		n, err := mgr.Convert(to, LayoutFlat)
+		if err != nil {
+			t.Fatalf("Convert(%s): %v", to, err)
+		}
+		if n != 2 {
+			t.Errorf("Convert(%s) converted %d, want 2", to, n)
+		}
+		for date, want := range originals {
+			if _, err := os.Stat(mgr.dayPath(date, dayFile{layout: LayoutFlat, format: to})); err != nil {
+				t.Errorf("%s not stored as %s: %v", date, to, err)
+			}
+			got, _, _, err := mgr.readDay(date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameDay(want, got) {
+				t.Errorf("Convert(%s) changed %s:\n got %+v\nwant %+v", to, date, got, want)
+			}
+		}
+	}
+	if n, err := mgr.Convert(FormatJSON, LayoutFlat); err != nil || n != 0 {
+		t.Errorf("converting to the current format = %d, %v; want no-op", n, err)
+	}
+}
+
+func TestHistoryReadsMarkdownRevisions(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	mgr.SetFormat(FormatMarkdown)
+	saveContent(t, mgr, "2024-03-10", "first")
+	saveContent(t, mgr, "2024-03-10", "second")
+	revs, err := mgr.History("2024-03-10")
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("History = %v, %v", revs, err)
+	}
+	rev, err := mgr.LoadRevision("2024-03-10", revs[1].ID)
+	if err != nil || rev.Content != "first" {
+		t.Errorf("LoadRevision = %+v, %v", rev, err)
+	}
+}
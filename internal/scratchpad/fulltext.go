@@ -0,0 +1,810 @@
+package scratchpad
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchIndexFileName holds the full-text index in the storage directory.
+// Like indexFileName it has no format extension and is never mistaken for
+// a day.
+const searchIndexFileName = ".search"
+
+// searchIndexVersion is bumped whenever tokenizing or the stored layout
+// changes; older indexes are then rebuilt from the day files.
+const searchIndexVersion = 3
+
+// searchIndex is an inverted index over day content. Every day keeps its
+// own term list, with the positions of each term counted in terms from
+// the start of the day, and every term the list of days it appears in.
+// Re-indexing a day then decodes and rewrites only that day's terms, and
+// a query reads the positions of the days its terms have in common. Both
+// stay encoded as stored until they are needed.
+type searchIndex struct {
+	days  map[string]indexedDay
+	terms map[string][]byte
+	// positions caches decoded term lists by day.
+	positions map[string]map[string][]int
+	// dirs and skipped are what the last look at the day files found.
+	dirs    *dirStamps
+	skipped []SkippedDay
+}
+
+// indexedFile records which file a day was indexed from. A day whose file
+// no longer matches is indexed again before the next query.
+type indexedFile struct {
+	File    string    `json:"file"`
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+}
+
+// indexedDay is one day of the index: its file and its terms, encoded by
+// encodeDayTerms.
+type indexedDay struct {
+	indexedFile
+	Terms []byte `json:"terms"`
+}
+
+// dirStamps holds the modification time of every directory day files
+// live in, taken at Taken. Adding, removing or replacing a day file
+// changes its directory's time, so while none has changed the days need
+// not be looked at one by one. A file rewritten in place changes no
+// directory; sp reindex picks that up.
+type dirStamps struct {
+	Taken time.Time            `json:"taken"`
+	Dirs  map[string]time.Time `json:"dirs"`
+}
+
+// skippedEntry is a SkippedDay as written to disk.
+type skippedEntry struct {
+	Date string `json:"date"`
+	Path string `json:"path,omitempty"`
+	Err  string `json:"err"`
+}
+
+// searchIndexData is a searchIndex as written to disk, every term's days
+// encoded by encodeDates.
+type searchIndexData struct {
+	Days    map[string]indexedDay `json:"days,omitempty"`
+	Terms   map[string][]byte     `json:"terms,omitempty"`
+	Dirs    *dirStamps            `json:"dirs,omitempty"`
+	Skipped []skippedEntry        `json:"skipped,omitempty"`
+}
+
+// searchIndexRecord is a change appended to the index after it was
+// written: Day is Date indexed again by a save, or Dirs and Skipped are
+// what a query found when it looked at the day files.
+type searchIndexRecord struct {
+	Date    string         `json:"date,omitempty"`
+	Day     *indexedDay    `json:"day,omitempty"`
+	Dirs    *dirStamps     `json:"dirs,omitempty"`
+	Skipped []skippedEntry `json:"skipped,omitempty"`
+}
+
+// storedSearchIndex is the on-disk index. In an encrypted store the index
+// is sealed into Sealed, since its terms are content.
+type storedSearchIndex struct {
+	Version int `json:"version"`
+	searchIndexData
+	Sealed string `json:"sealed,omitempty"`
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{days: map[string]indexedDay{}, terms: map[string][]byte{}, positions: map[string]map[string][]int{}}
+}
+
+// tokenize splits s into lower-case terms: runs of letters and digits.
+func tokenize(s string) []string {
+	terms := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, term := range terms {
+		terms[i] = strings.ToLower(term)
+	}
+	return terms
+}
+
+// termPositions returns the terms of content with their positions.
+func termPositions(content string) map[string][]int {
+	terms := map[string][]int{}
+	for pos, term := range tokenize(content) {
+		terms[term] = append(terms[term], pos)
+	}
+	return terms
+}
+
+// dayTerms returns the terms of date with their positions, decoding them
+// on first use. The result belongs to the index.
+func (ix *searchIndex) dayTerms(date string) map[string][]int {
+	if terms, ok := ix.positions[date]; ok {
+		return terms
+	}
+	day, ok := ix.days[date]
+	if !ok {
+		return nil
+	}
+	terms, err := decodeDayTerms(day.Terms)
+	if err != nil {
+		// A damaged term list loses the day until sp reindex.
+		return nil
+	}
+	ix.positions[date] = terms
+	return terms
+}
+
+// dates returns the days term appears in, as day numbers in order.
+func (ix *searchIndex) dates(term string) []int64 {
+	days, err := decodeDates(ix.terms[term])
+	if err != nil {
+		return nil
+	}
+	return days
+}
+
+// add indexes content as date, replacing what was indexed for it before.
+func (ix *searchIndex) add(date string, file indexedFile, content string) {
+	terms := termPositions(content)
+	ix.put(date, indexedDay{indexedFile: file, Terms: encodeDayTerms(terms)}, terms)
+}
+
+// put stores day as what is indexed for date; terms is its term list
+// decoded.
+func (ix *searchIndex) put(date string, entry indexedDay, terms map[string][]int) {
+	ix.remove(date)
+	ix.days[date] = entry
+	ix.positions[date] = terms
+	day := dayNumber(date)
+	for term := range terms {
+		days := ix.dates(term)
+		if i, found := slices.BinarySearch(days, day); !found {
+			ix.terms[term] = encodeDates(slices.Insert(days, i, day))
+		}
+	}
+}
+
+// remove drops date from the index.
+func (ix *searchIndex) remove(date string) {
+	if _, ok := ix.days[date]; !ok {
+		return
+	}
+	terms := ix.dayTerms(date)
+	delete(ix.days, date)
+	delete(ix.positions, date)
+	day := dayNumber(date)
+	drop := func(term string) {
+		days := ix.dates(term)
+		i, found := slices.BinarySearch(days, day)
+		switch {
+		case !found:
+		case len(days) == 1:
+			delete(ix.terms, term)
+		default:
+			ix.terms[term] = encodeDates(slices.Delete(days, i, i+1))
+		}
+	}
+	if terms == nil {
+		// Without the day's own list, every term may hold it.
+		for term := range ix.terms {
+			drop(term)
+		}
+		return
+	}
+	for term := range terms {
+		drop(term)
+	}
+}
+
+// data returns ix for writing; everything in it is already encoded.
+func (ix *searchIndex) data() searchIndexData {
+	return searchIndexData{Days: ix.days, Terms: ix.terms, Dirs: ix.dirs, Skipped: skippedEntries(ix.skipped)}
+}
+
+func skippedEntries(skipped []SkippedDay) []skippedEntry {
+	var entries []skippedEntry
+	for _, s := range skipped {
+		entries = append(entries, skippedEntry{Date: s.Date, Path: s.Path, Err: s.Err.Error()})
+	}
+	return entries
+}
+
+func skippedDays(entries []skippedEntry) []SkippedDay {
+	var skipped []SkippedDay
+	for _, e := range entries {
+		skipped = append(skipped, SkippedDay{Date: e.Date, Path: e.Path, Err: errors.New(e.Err)})
+	}
+	return skipped
+}
+
+// dayNumber converts a date to days since 1970-01-01.
+func dayNumber(date string) int64 {
+	t, _ := time.Parse(dateLayout, date)
+	return t.Unix() / 86400
+}
+
+// dayDate reverses dayNumber.
+func dayDate(day int64) string {
+	return time.Unix(day*86400, 0).UTC().Format(dateLayout)
+}
+
+// errDamagedIndex reports an encoded term list or date list that cannot
+// be read.
+var errDamagedIndex = errors.New("damaged search index entry")
+
+// encodeDayTerms packs the terms of a day, in order, as varints: each
+// term's length and bytes, the number of its positions and the gaps
+// between them.
+func encodeDayTerms(terms map[string][]int) []byte {
+	sorted := make([]string, 0, len(terms))
+	for term := range terms {
+		sorted = append(sorted, term)
+	}
+	slices.Sort(sorted)
+	var buf []byte
+	for _, term := range sorted {
+		buf = binary.AppendUvarint(buf, uint64(len(term)))
+		buf = append(buf, term...)
+		positions := terms[term]
+		buf = binary.AppendUvarint(buf, uint64(len(positions)))
+		last := 0
+		for _, pos := range positions {
+			buf = binary.AppendUvarint(buf, uint64(pos-last))
+			last = pos
+		}
+	}
+	return buf
+}
+
+// decodeDayTerms reverses encodeDayTerms.
+func decodeDayTerms(buf []byte) (map[string][]int, error) {
+	terms := map[string][]int{}
+	for len(buf) > 0 {
+		size, n := binary.Uvarint(buf)
+		if n <= 0 || size > uint64(len(buf)-n) {
+			return nil, errDamagedIndex
+		}
+		term := string(buf[n : n+int(size)])
+		buf = buf[n+int(size):]
+		count, n := binary.Uvarint(buf)
+		if n <= 0 || count > uint64(len(buf)) {
+			return nil, errDamagedIndex
+		}
+		buf = buf[n:]
+		positions := make([]int, count)
+		last := 0
+		for i := range positions {
+			gap, n := binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, errDamagedIndex
+			}
+			buf = buf[n:]
+			last += int(gap)
+			positions[i] = last
+		}
+		terms[term] = positions
+	}
+	return terms, nil
+}
+
+// encodeDates packs ordered day numbers as varint gaps, the first from
+// day zero.
+func encodeDates(days []int64) []byte {
+	buf := make([]byte, 0, len(days)+4)
+	var prev int64
+	for _, day := range days {
+		buf = binary.AppendVarint(buf, day-prev)
+		prev = day
+	}
+	return buf
+}
+
+// decodeDates reverses encodeDates.
+func decodeDates(buf []byte) ([]int64, error) {
+	days := make([]int64, 0, len(buf))
+	var day int64
+	for len(buf) > 0 {
+		delta, n := binary.Varint(buf)
+		if n <= 0 {
+			return nil, errDamagedIndex
+		}
+		buf = buf[n:]
+		day += delta
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// Reindex rebuilds the full-text index from the day files, for when it is
+// lost or damaged, and returns the number of days indexed. Queries keep
+// the index current on their own; this only saves the first one the work.
+// Days that cannot be read are left out and returned.
+func (m *Manager) Reindex() (int, []SkippedDay, error) {
+	ix := newSearchIndex()
+	_, skipped, err := m.refreshSearchIndex(ix)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := m.saveSearchIndex(ix); err != nil {
+		return 0, skipped, err
+	}
+	return len(ix.days), skipped, nil
+}
+
+// currentSearchIndex loads the index and brings it up to date with the
+// day files: days added, changed or removed outside the Manager, by a
+// sync tool or by an older sp, are indexed again or dropped. While no
+// directory holding days has changed since the index last looked, the
+// days are not looked at. Days that cannot be read are left out of the
+// index and returned.
+func (m *Manager) currentSearchIndex() (*searchIndex, []SkippedDay, error) {
+	ix, records := m.readSearchIndex()
+	if ix != nil && m.unchangedSince(ix.dirs) {
+		if records >= journalCompactAt {
+			_ = m.saveSearchIndex(ix)
+		}
+		return ix, ix.skipped, nil
+	}
+	fresh := ix == nil
+	if fresh {
+		ix = newSearchIndex()
+	}
+	changed, skipped, err := m.refreshSearchIndex(ix)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The index only saves work; a failed write costs a slower query.
+	if fresh || changed || records >= journalCompactAt {
+		_ = m.saveSearchIndex(ix)
+	} else {
+		m.appendSearchIndex(searchIndexRecord{Dirs: ix.dirs, Skipped: skippedEntries(skipped)})
+	}
+	return ix, skipped, nil
+}
+
+// refreshSearchIndex indexes every day whose file does not match ix and
+// drops the days that are gone, reporting whether anything changed. A
+// day that cannot be read is dropped too and returned, so one damaged
+// file does not stop the rest from being indexed.
+func (m *Manager) refreshSearchIndex(ix *searchIndex) (bool, []SkippedDay, error) {
+	// Stamped first, so a change made during the scan shows next time.
+	dirs, err := m.stampDayDirs()
+	if err != nil {
+		return false, nil, err
+	}
+	files, err := m.scanDayFiles()
+	if err != nil {
+		return false, nil, err
+	}
+	byDate := make(map[string][]storedFile, len(files))
+	for _, file := range files {
+		if isDate(file.date) {
+			byDate[file.date] = append(byDate[file.date], file)
+		}
+	}
+
+	changed := false
+	for date := range ix.days {
+		if _, ok := byDate[date]; !ok {
+			ix.remove(date)
+			changed = true
+		}
+	}
+	var skipped []SkippedDay
+	skip := func(file storedFile, err error) {
+		skipped = append(skipped, m.skippedDay(file.date, file.path, err))
+		if _, ok := ix.days[file.date]; ok {
+			ix.remove(file.date)
+			changed = true
+		}
+	}
+	for date, copies := range byDate {
+		file := copies[0]
+		if len(copies) > 1 {
+			// The same day stored twice: index the copy sp reads.
+			if path, at, lerr := m.locate(date); lerr == nil {
+				file = storedFile{path: path, date: date, at: at}
+			}
+		}
+		info, err := os.Stat(file.path)
+		if err != nil {
+			skip(file, fmt.Errorf("failed to read scratchpad file: %w", err))
+			continue
+		}
+		rel, _ := filepath.Rel(m.storageDir, file.path)
+		stamp := indexedFile{File: rel, ModTime: info.ModTime(), Size: info.Size()}
+		if indexed, ok := ix.days[date]; ok && indexed.File == stamp.File && indexed.Size == stamp.Size && indexed.ModTime.Equal(stamp.ModTime) {
+			continue
+		}
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			skip(file, fmt.Errorf("failed to read scratchpad file: %w", err))
+			continue
+		}
+		scratchpad, err := m.decodeDay(data, file.at.format)
+		if err != nil {
+			skip(file, fmt.Errorf("failed to parse scratchpad file: %w", err))
+			continue
+		}
+		ix.add(date, stamp, scratchpad.Content)
+		changed = true
+	}
+	sortSkipped(skipped)
+	ix.dirs, ix.skipped = dirs, skipped
+	return changed, skipped, nil
+}
+
+// stampDayDirs stamps the storage directory and the year and month
+// directories below it.
+func (m *Manager) stampDayDirs() (*dirStamps, error) {
+	stamps := &dirStamps{Taken: time.Now(), Dirs: map[string]time.Time{}}
+	stamp := func(rel string) ([]os.DirEntry, error) {
+		dir := filepath.Join(m.storageDir, rel)
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read storage directory: %w", err)
+		}
+		stamps.Dirs[filepath.ToSlash(rel)] = info.ModTime()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read storage directory: %w", err)
+		}
+		return entries, nil
+	}
+	years, err := stamp(".")
+	if err != nil {
+		return nil, err
+	}
+	for _, year := range years {
+		if !year.IsDir() || !isDigits(year.Name(), 4) {
+			continue
+		}
+		months, err := stamp(year.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, month := range months {
+			if month.IsDir() && isDigits(month.Name(), 2) {
+				if _, err := stamp(filepath.Join(year.Name(), month.Name())); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return stamps, nil
+}
+
+// unchangedSince reports whether every directory in stamps still has the
+// time it was stamped with, and had it long enough before they were
+// taken that a later change could not have left it the same. File systems
+// keeping whole seconds are allowed two, for FAT; the rest a few clock
+// ticks.
+func (m *Manager) unchangedSince(stamps *dirStamps) bool {
+	if stamps == nil {
+		return false
+	}
+	for rel, modTime := range stamps.Dirs {
+		slack := 50 * time.Millisecond
+		if modTime.Nanosecond() == 0 {
+			slack = 2 * time.Second
+		}
+		if !modTime.Before(stamps.Taken.Add(-slack)) {
+			return false
+		}
+		info, err := os.Stat(filepath.Join(m.storageDir, filepath.FromSlash(rel)))
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchIndexSaved appends a day Save just wrote to the index, so the
+// next query does not have to read it.
+func (m *Manager) searchIndexSaved(scratchpad *Scratchpad, at dayFile) {
+	path := m.dayPath(scratchpad.Date, at)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	rel, _ := filepath.Rel(m.storageDir, path)
+	entry := indexedDay{
+		indexedFile: indexedFile{File: rel, ModTime: info.ModTime(), Size: info.Size()},
+		Terms:       encodeDayTerms(termPositions(scratchpad.Content)),
+	}
+	m.appendSearchIndex(searchIndexRecord{Date: scratchpad.Date, Day: &entry})
+}
+
+// appendSearchIndex appends record to the index, writing the index afresh
+// once enough records have piled up. Without an index there is nothing
+// to append to; the first query builds it in full.
+func (m *Manager) appendSearchIndex(record searchIndexRecord) {
+	line, err := m.encodeRecord(record, searchIndexFileName)
+	if err != nil {
+		return
+	}
+	records, err := appendJournal(filepath.Join(m.storageDir, searchIndexFileName), line)
+	if err != nil || records < journalCompactAt {
+		return
+	}
+	if ix := m.loadSearchIndex(); ix != nil {
+		_ = m.saveSearchIndex(ix)
+	}
+}
+
+// loadSearchIndex reads the index, returning nil when there is none or it
+// cannot be used.
+func (m *Manager) loadSearchIndex() *searchIndex {
+	ix, _ := m.readSearchIndex()
+	return ix
+}
+
+// readSearchIndex is loadSearchIndex, also returning the number of records
+// appended to the index since it was written.
+func (m *Manager) readSearchIndex() (*searchIndex, int) {
+	snapshot, records, err := readJournal(filepath.Join(m.storageDir, searchIndexFileName))
+	if err != nil {
+		return nil, 0
+	}
+	var stored storedSearchIndex
+	if json.Unmarshal(snapshot, &stored) != nil || stored.Version != searchIndexVersion {
+		return nil, 0
+	}
+	index := stored.searchIndexData
+	if stored.Sealed != "" {
+		if m.cipher == nil {
+			return nil, 0
+		}
+		sealed, err := base64.StdEncoding.DecodeString(stored.Sealed)
+		if err != nil {
+			return nil, 0
+		}
+		plain, err := m.cipher.Open(sealed, []byte(searchIndexFileName))
+		if err != nil {
+			return nil, 0
+		}
+		if json.Unmarshal(plain, &index) != nil {
+			return nil, 0
+		}
+	} else if m.cipher != nil {
+		return nil, 0 // written before the store was encrypted
+	}
+	ix := newSearchIndex()
+	if index.Days != nil {
+		ix.days = index.Days
+	}
+	if index.Terms != nil {
+		ix.terms = index.Terms
+	}
+	ix.dirs, ix.skipped = index.Dirs, skippedDays(index.Skipped)
+	for _, line := range records {
+		var record searchIndexRecord
+		if m.decodeRecord(line, searchIndexFileName, &record) != nil {
+			return nil, 0
+		}
+		if record.Day != nil {
+			terms, err := decodeDayTerms(record.Day.Terms)
+			if err != nil {
+				return nil, 0
+			}
+			ix.put(record.Date, *record.Day, terms)
+		}
+		if record.Dirs != nil {
+			ix.dirs, ix.skipped = record.Dirs, skippedDays(record.Skipped)
+		}
+	}
+	return ix, len(records)
+}
+
+func (m *Manager) saveSearchIndex(ix *searchIndex) error {
+	index := ix.data()
+	stored := storedSearchIndex{Version: searchIndexVersion, searchIndexData: index}
+	if m.cipher != nil {
+		plain, err := json.Marshal(index)
+		if err != nil {
+			return err
+		}
+		sealed, err := m.cipher.Seal(plain, []byte(searchIndexFileName))
+		if err != nil {
+			return err
+		}
+		stored = storedSearchIndex{Version: searchIndexVersion, Sealed: base64.StdEncoding.EncodeToString(sealed)}
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	return writeJournal(filepath.Join(m.storageDir, searchIndexFileName), data, m.filePerm())
+}
+
+// dropSearchIndex removes the full-text index; the next query rebuilds it.
+func (m *Manager) dropSearchIndex() error {
+	err := os.Remove(filepath.Join(m.storageDir, searchIndexFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove search index: %w", err)
+	}
+	return nil
+}
+
+// WordQuery is a query answered from the full-text index. It matches the
+// days holding every one of its words, phrases ("in quotes") and prefixes
+// (ending in *), compared without regard to case or punctuation.
+type WordQuery struct {
+	clauses []wordClause
+}
+
+// wordClause is a run of terms that must appear next to each other. With
+// prefix set, the last term matches any term it starts.
+type wordClause struct {
+	terms  []string
+	prefix bool
+}
+
+// ParseWordQuery parses a word query such as `deploy "release train" post*`.
+// A word holding punctuation, like e-mail, is a phrase of its parts.
+func ParseWordQuery(query string) (*WordQuery, error) {
+	var q WordQuery
+	rest := query
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		var part string
+		if quoted, ok := strings.CutPrefix(rest, `"`); ok {
+			var closed bool
+			if part, rest, closed = strings.Cut(quoted, `"`); !closed {
+				return nil, fmt.Errorf("invalid query %q: unclosed quote", query)
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t\n\"")
+			if end < 0 {
+				end = len(rest)
+			}
+			part, rest = rest[:end], rest[end:]
+		}
+		clause := wordClause{terms: tokenize(part), prefix: strings.HasSuffix(strings.TrimSpace(part), "*")}
+		if len(clause.terms) > 0 {
+			q.clauses = append(q.clauses, clause)
+		}
+	}
+	if len(q.clauses) == 0 {
+		return nil, fmt.Errorf("invalid query %q: no words to search for", query)
+	}
+	return &q, nil
+}
+
+// MatchString reports whether line holds any term of q, so search results
+// can show the lines that made a day match.
+func (q *WordQuery) MatchString(line string) bool {
+	for _, term := range tokenize(line) {
+		for _, clause := range q.clauses {
+			for i, want := range clause.terms {
+				if term == want || (clause.prefix && i == len(clause.terms)-1 && strings.HasPrefix(term, want)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// lookup returns, oldest first, the days matching every clause of q.
+func (ix *searchIndex) lookup(q *WordQuery) []string {
+	var days []int64
+	for i, clause := range q.clauses {
+		found := ix.matchClause(clause)
+		if i == 0 {
+			days = found
+		} else {
+			days = intersectDays(days, found)
+		}
+		if len(days) == 0 {
+			return nil
+		}
+	}
+	out := make([]string, len(days))
+	for i, day := range days {
+		out[i] = dayDate(day)
+	}
+	return out
+}
+
+// matchClause returns, in order, the days holding clause's terms in
+// order, each term directly after the one before. Only the days holding
+// every term are checked for positions.
+func (ix *searchIndex) matchClause(clause wordClause) []int64 {
+	last := len(clause.terms) - 1
+	var prefixed []string
+	if clause.prefix {
+		for term := range ix.terms {
+			if strings.HasPrefix(term, clause.terms[last]) {
+				prefixed = append(prefixed, term)
+			}
+		}
+	}
+	candidates := func(i int) []int64 {
+		if i < last || !clause.prefix {
+			return ix.dates(clause.terms[i])
+		}
+		var days []int64
+		for _, term := range prefixed {
+			days = unionDays(days, ix.dates(term))
+		}
+		return days
+	}
+	days := candidates(0)
+	for i := 1; i <= last && len(days) > 0; i++ {
+		days = intersectDays(days, candidates(i))
+	}
+	if last == 0 {
+		return days
+	}
+
+	var out []int64
+	for _, day := range days {
+		terms := ix.dayTerms(dayDate(day))
+		at := func(i int) []int {
+			if i < last || !clause.prefix {
+				return terms[clause.terms[i]]
+			}
+			var positions []int
+			for _, term := range prefixed {
+				positions = append(positions, terms[term]...)
+			}
+			return positions
+		}
+		starts := at(0)
+		for i := 1; i <= last && len(starts) > 0; i++ {
+			next := at(i)
+			var kept []int
+			for _, start := range starts {
+				if slices.Contains(next, start+i) {
+					kept = append(kept, start)
+				}
+			}
+			starts = kept
+		}
+		if len(starts) > 0 {
+			out = append(out, day)
+		}
+	}
+	return out
+}
+
+// intersectDays returns the days in both ordered lists.
+func intersectDays(a, b []int64) []int64 {
+	var out []int64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			out = append(out, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	return out
+}
+
+// unionDays returns the days in either ordered list.
+func unionDays(a, b []int64) []int64 {
+	out := make([]int64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			out, a = append(out, a[0]), a[1:]
+		case a[0] > b[0]:
+			out, b = append(out, b[0]), b[1:]
+		default:
+			out = append(out, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	out = append(out, a...)
+	return append(out, b...)
+}
@@ -0,0 +1,358 @@
+package scratchpad
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pders01/sp/internal/templates"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Deploy: e-mail Über_2024 (done)")
+	want := []string{"deploy", "e", "mail", "über", "2024", "done"}
+	if !slices.Equal(got, want) {
+		t.Errorf("tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestParseWordQuery(t *testing.T) {
+	q, err := ParseWordQuery(`deploy "Release Train" post* e-mail`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wordClause{
+		{terms: []string{"deploy"}},
+		{terms: []string{"release", "train"}},
+		{terms: []string{"post"}, prefix: true},
+		{terms: []string{"e", "mail"}},
+	}
+	if len(q.clauses) != len(want) {
+		t.Fatalf("clauses = %+v", q.clauses)
+	}
+	for i := range want {
+		if !slices.Equal(q.clauses[i].terms, want[i].terms) || q.clauses[i].prefix != want[i].prefix {
+			t.Errorf("clause %d = %+v, want %+v", i, q.clauses[i], want[i])
+		}
+	}
+	for _, bad := range []string{"", `"unclosed`, `" ... "`} {
+		if _, err := ParseWordQuery(bad); err == nil {
+			t.Errorf("ParseWordQuery(%q) should fail", bad)
+		}
+	}
+}
+
+func TestIndexEncodingRoundTrip(t *testing.T) {
+	terms := map[string][]int{"deploy": {0, 4}, "über": {3}, "x": {1, 2, 300}}
+	got, err := decodeDayTerms(encodeDayTerms(terms))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(terms) {
+		t.Fatalf("decoded = %v, want %v", got, terms)
+	}
+	for term, positions := range terms {
+		if !slices.Equal(got[term], positions) {
+			t.Errorf("decoded[%s] = %v, want %v", term, got[term], positions)
+		}
+	}
+	if _, err := decodeDayTerms([]byte{0x05, 'a'}); err == nil {
+		t.Error("a truncated term list should be refused")
+	}
+
+	days := []int64{dayNumber("1969-07-20"), dayNumber("2024-02-29"), dayNumber("2024-03-01")}
+	if decoded, err := decodeDates(encodeDates(days)); err != nil || !slices.Equal(decoded, days) {
+		t.Errorf("decoded dates = %v, %v, want %v", decoded, err, days)
+	}
+	if dayDate(days[0]) != "1969-07-20" {
+		t.Errorf("dayDate = %s", dayDate(days[0]))
+	}
+	if _, err := decodeDates([]byte{0x80}); err == nil {
+		t.Error("a truncated date list should be refused")
+	}
+}
+
+func TestSearchIndexLookup(t *testing.T) {
+	ix := newSearchIndex()
+	ix.add("2024-01-01", indexedFile{}, "The release train leaves Monday.")
+	ix.add("2024-01-02", indexedFile{}, "Train the release team.")
+	ix.add("2024-01-03", indexedFile{}, "Postgres release notes; trains of thought.")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "release", want: []string{"2024-01-01", "2024-01-02", "2024-01-03"}},
+		{query: `"release train"`, want: []string{"2024-01-01"}},
+		{query: `"release train*"`, want: []string{"2024-01-01"}},
+		{query: "train*", want: []string{"2024-01-01", "2024-01-02", "2024-01-03"}},
+		{query: "post* release", want: []string{"2024-01-03"}},
+		{query: "release missing", want: nil},
+	}
+	for _, tt := range tests {
+		q, err := ParseWordQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ix.lookup(q); !slices.Equal(got, tt.want) {
+			t.Errorf("lookup(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	ix.add("2024-01-01", indexedFile{}, "Rewritten.")
+	q, _ := ParseWordQuery("release")
+	if got := ix.lookup(q); !slices.Equal(got, []string{"2024-01-02", "2024-01-03"}) {
+		t.Errorf("after reindexing a day, lookup = %v", got)
+	}
+	if _, ok := ix.terms["leaves"]; ok {
+		t.Error("a term no day holds any more should be dropped")
+	}
+
+	// Reindexing touches only the day's own terms: a damaged list of an
+	// unrelated term survives it.
+	ix.terms["postgres"] = []byte{0x80}
+	ix.add("2024-01-02", indexedFile{}, "Train the release team again.")
+	if !bytes.Equal(ix.terms["postgres"], []byte{0x80}) {
+		t.Error("reindexing a day rewrote a term it does not hold")
+	}
+}
+
+func TestSearchIndexFollowsSaves(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "kickoff with the platform team")
+	if _, _, err := mgr.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Save and ApplyTemplateSections update the index in place.
+	saveContent(t, mgr, "2024-03-02", "platform retro")
+	if _, err := mgr.ApplyTemplateSections("2024-03-01", []templates.Section{{ID: "standup", Title: "Standup", Body: "blockers"}}); err != nil {
+		t.Fatal(err)
+	}
+	ix := mgr.loadSearchIndex()
+	q, _ := ParseWordQuery("platform")
+	if got := ix.lookup(q); !slices.Equal(got, []string{"2024-03-01", "2024-03-02"}) {
+		t.Errorf("indexed platform days = %v", got)
+	}
+	q, _ = ParseWordQuery("blockers")
+	if got := ix.lookup(q); !slices.Equal(got, []string{"2024-03-01"}) {
+		t.Errorf("indexed template days = %v", got)
+	}
+
+	// Days changed or removed behind the Manager's back are caught up on
+	// by the next query.
+	if err := os.Remove(filepath.Join(mgr.storageDir, "2024-03-02.json")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := encode(&Scratchpad{SchemaVersion: SchemaVersion, Date: "2024-03-03", Content: "synced platform notes"}, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-03.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, _ := ParseWordQuery("platform")
+	results, _, err := mgr.Search(SearchQuery{Pattern: words})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Date != "2024-03-01" || results[1].Date != "2024-03-03" ||
+		results[1].Lines[0].Text != "synced platform notes" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestSavesAppendToTheSearchIndex(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		mgr := setupTestManager(t)
+		if encrypted {
+			mgr.SetCipher(testCipher(t, 1))
+		}
+		saveContent(t, mgr, "2024-03-01", "alpha")
+		if _, _, err := mgr.Reindex(); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(mgr.storageDir, searchIndexFileName)
+		before, _ := os.ReadFile(path)
+
+		saveContent(t, mgr, "2024-03-01", "beta")
+		saveContent(t, mgr, "2024-03-02", "alpha beta")
+		after, _ := os.ReadFile(path)
+		if !bytes.HasPrefix(after, before) || bytes.Count(after, []byte("\n")) != 3 {
+			t.Fatalf("saves should append a record each:\n%s", after)
+		}
+		if encrypted && bytes.Contains(after, []byte("alpha")) {
+			t.Errorf("appended records leak content:\n%s", after)
+		}
+		q, _ := ParseWordQuery("alpha")
+		if got := mgr.loadSearchIndex().lookup(q); !slices.Equal(got, []string{"2024-03-02"}) {
+			t.Errorf("lookup after appended saves = %v", got)
+		}
+
+		for i := 0; i < journalCompactAt; i++ {
+			saveContent(t, mgr, "2024-03-01", "gamma")
+		}
+		if data, _ := os.ReadFile(path); bytes.Count(data, []byte("\n")) > journalCompactAt {
+			t.Errorf("index not written afresh after %d records", journalCompactAt)
+		}
+		q, _ = ParseWordQuery("gamma")
+		if got := mgr.loadSearchIndex().lookup(q); !slices.Equal(got, []string{"2024-03-01"}) {
+			t.Errorf("lookup after compaction = %v", got)
+		}
+	}
+}
+
+func TestSearchSkipsUnchangedStore(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "alpha")
+	day := filepath.Join(mgr.storageDir, "2024-03-01.json")
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-02.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(mgr.storageDir, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewriting a file in place leaves its directory alone, so the index
+	// does not look at it...
+	data, err := encode(&Scratchpad{SchemaVersion: SchemaVersion, Date: "2024-03-01", Content: "omega"}, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(day, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(mgr.storageDir, past, past); err != nil {
+		t.Fatal(err)
+	}
+	words, _ := ParseWordQuery("omega")
+	results, skipped, err := mgr.Search(SearchQuery{Pattern: words})
+	if err != nil || len(results) != 0 {
+		t.Errorf("an unchanged store was looked at: %+v, %v", results, err)
+	}
+	if len(skipped) != 1 || skipped[0].Date != "2024-03-02" {
+		t.Errorf("days skipped when the index was built should still be reported: %+v", skipped)
+	}
+
+	// ...until the directory changes, as it does when a file is added,
+	// removed or replaced.
+	if err := os.Chtimes(mgr.storageDir, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if results, _, err := mgr.Search(SearchQuery{Pattern: words}); err != nil || len(results) != 1 {
+		t.Errorf("results after the directory changed = %+v, %v", results, err)
+	}
+}
+
+func TestReindexRecoversFromDamage(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "alpha")
+	saveContent(t, mgr, "2024-03-02", "beta")
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, searchIndexFileName), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.loadSearchIndex() != nil {
+		t.Fatal("a damaged index must not load")
+	}
+	n, _, err := mgr.Reindex()
+	if err != nil || n != 2 {
+		t.Fatalf("Reindex = %d, %v", n, err)
+	}
+	q, _ := ParseWordQuery("beta")
+	if got := mgr.loadSearchIndex().lookup(q); !slices.Equal(got, []string{"2024-03-02"}) {
+		t.Errorf("lookup after reindex = %v", got)
+	}
+}
+
+func TestEncryptedSearchIndexKeepsTermsSealed(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	saveContent(t, mgr, "2024-03-01", "secret plans")
+	if _, _, err := mgr.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(mgr.storageDir, searchIndexFileName))
+	if len(data) == 0 || bytes.Contains(data, []byte("secret")) {
+		t.Errorf("search index leaks content:\n%s", data)
+	}
+	if mgr.loadSearchIndex() == nil {
+		t.Error("the sealed index should load with the key")
+	}
+	mgr.SetCipher(nil)
+	if mgr.loadSearchIndex() != nil {
+		t.Error("a sealed index must not load without the key")
+	}
+}
+
+// BenchmarkSearchScan is a rare-word search without the index: every day
+// is read and every line tried.
+func BenchmarkSearchScan(b *testing.B) {
+	mgr := syntheticStore(b)
+	pattern := regexp.MustCompile(`2019-06-01`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results, _, err := mgr.Search(SearchQuery{Pattern: pattern}); err != nil || len(results) != 1 {
+			b.Fatal(results, err)
+		}
+	}
+}
+
+// BenchmarkSearchIndexed is the same search answered from a current index.
+func BenchmarkSearchIndexed(b *testing.B) {
+	mgr := syntheticStore(b)
+	if _, _, err := mgr.Reindex(); err != nil {
+		b.Fatal(err)
+	}
+	words, err := ParseWordQuery(`"2019-06-01"`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results, _, err := mgr.Search(SearchQuery{Pattern: words}); err != nil || len(results) != 1 {
+			b.Fatal(results, err)
+		}
+	}
+}
+
+// BenchmarkSearchIndexedPrefix expands a prefix that matches every day.
+func BenchmarkSearchIndexedPrefix(b *testing.B) {
+	mgr := syntheticStore(b)
+	if _, _, err := mgr.Reindex(); err != nil {
+		b.Fatal(err)
+	}
+	words, err := ParseWordQuery(`"2019-06-01" foll*`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results, _, err := mgr.Search(SearchQuery{Pattern: words}); err != nil || len(results) != 1 {
+			b.Fatal(results, err)
+		}
+	}
+}
+
+// BenchmarkSaveWithSearchIndex is the cost the index adds to a save.
+func BenchmarkSaveWithSearchIndex(b *testing.B) {
+	mgr := syntheticStore(b)
+	if _, _, err := mgr.Reindex(); err != nil {
+		b.Fatal(err)
+	}
+	day := &Scratchpad{Date: "2019-06-01", Created: time.Now()}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		day.Content = "follow up " + time.Now().String()
+		if err := mgr.Save(day); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package scratchpad
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyDirName holds one sub-directory of revisions per day.
+const historyDirName = ".history"
+
+// revisionIDLayout names revision files. Fixed-width UTC timestamps sort
+// lexically in save order.
+const revisionIDLayout = "20060102T150405.000000000Z"
+
+// HistoryPolicy bounds the per-day revision log.
+type HistoryPolicy struct {
+	// Keep is the maximum number of revisions retained per day. Zero
+	// disables history entirely.
+	Keep int
+	// MaxAge drops revisions older than this. Zero keeps revisions until
+	// Keep evicts them. The newest revision is never dropped by age.
+	MaxAge time.Duration
+}
+
+// DefaultHistoryPolicy returns the retention used when config is silent.
+func DefaultHistoryPolicy() HistoryPolicy {
+	return HistoryPolicy{Keep: 50}
+}
+
+// Revision is one saved version of a day.
+type Revision struct {
+	ID    string
+	Saved time.Time
+}
+
+// SetHistoryPolicy replaces the retention policy for future saves.
+func (m *Manager) SetHistoryPolicy(policy HistoryPolicy) {
+	m.history = policy
+}
+
+func (m *Manager) historyDir(date string) string {
+	return filepath.Join(m.storageDir, historyDirName, date)
+}
+
+// snapshotPrevious records the on-disk version of date before it is
+// overwritten at now, unless the newest revision already holds it. That
+// seeds an empty history the first time, and keeps a version another
+// tool wrote since the last save. The file is copied byte for byte, even
+// when it no longer parses.
+func (m *Manager) snapshotPrevious(date string, now time.Time) error {
+	if m.history.Keep <= 0 {
+		return nil
+	}
+	previous, data, at, err := m.readDay(date)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if data == nil {
+		return fmt.Errorf("read previous version: %w", err)
+	}
+	revs, err := m.History(date)
+	if err != nil {
+		return err
+	}
+	// The snapshot sorts after the revision it replaced and before the
+	// save about to happen, whatever its own timestamp says.
+	saved := now.Add(-time.Nanosecond)
+	if previous != nil && !previous.Modified.IsZero() && previous.Modified.Before(saved) {
+		saved = previous.Modified
+	}
+	if len(revs) > 0 {
+		same, err := m.sameAsRevision(date, revs[0].ID, previous, data)
+		if err != nil || same {
+			return err
+		}
+		if !saved.After(revs[0].Saved) {
+			saved = revs[0].Saved.Add(time.Nanosecond)
+		}
+	}
+	return m.writeRevision(date, saved, data, at.format)
+}
+
+// sameAsRevision reports whether revision id holds the day read as
+// current from data: the same bytes, or the same content re-encoded by a
+// migration, format change or new key.
+func (m *Manager) sameAsRevision(date, id string, current *Scratchpad, data []byte) (bool, error) {
+	path, f, err := m.revisionPath(date, id)
+	if err != nil {
+		return false, err
+	}
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read revision: %w", err)
+	}
+	if bytes.Equal(stored, data) {
+		return true, nil
+	}
+	if current == nil {
+		return false, nil
+	}
+	rev, err := m.decodeDay(stored, f)
+	if err != nil {
+		return false, nil
+	}
+	return rev.Content == current.Content && slices.Equal(rev.AppliedTemplates, current.AppliedTemplates), nil
+}
+
+// recordRevision appends data to the day's history and applies retention.
+func (m *Manager) recordRevision(date string, saved time.Time, data []byte, f Format) error {
+	if m.history.Keep <= 0 {
+		return nil
+	}
+	if err := m.writeRevision(date, saved, data, f); err != nil {
+		return err
+	}
+	return m.pruneHistory(date)
+}
+
+// writeRevision stores data as a revision of date. Revisions keep the
+// encoding the day had when it was saved.
+func (m *Manager) writeRevision(date string, saved time.Time, data []byte, f Format) error {
+	dir := m.historyDir(date)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create history directory: %w", err)
+	}
+	return writeFileAtomic(stampedPath(dir, saved, f), data, m.filePerm())
+}
+
+// stampedPath names a file in dir after t, in the revision ID layout. Two
+// writes inside the same nanosecond would collide; t is nudged forward
+// until the name is free so neither file is overwritten.
+func stampedPath(dir string, t time.Time, f Format) string {
+	for {
+		path := filepath.Join(dir, t.UTC().Format(revisionIDLayout)+f.ext())
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return path
+		}
+		t = t.Add(time.Nanosecond)
+	}
+}
+
+func (m *Manager) pruneHistory(date string) error {
+	revs, err := m.History(date)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Time{}
+	if m.history.MaxAge > 0 {
+		cutoff = time.Now().Add(-m.history.MaxAge)
+	}
+	for i, rev := range revs {
+		expired := i > 0 && !cutoff.IsZero() && rev.Saved.Before(cutoff)
+		if i < m.history.Keep && !expired {
+			continue
+		}
+		path, _, lerr := m.revisionPath(date, rev.ID)
+		if lerr != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("prune revision %s: %w", rev.ID, err)
+		}
+	}
+	return nil
+}
+
+// History lists the saved revisions of date, newest first. A day without
+// history returns an empty slice.
+func (m *Manager) History(date string) ([]Revision, error) {
+	entries, err := os.ReadDir(m.historyDir(date))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+	revs := make([]Revision, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		id := strings.TrimSuffix(name, filepath.Ext(name))
+		if entry.IsDir() || !isFormatExt(filepath.Ext(name)) {
+			continue
+		}
+		saved, perr := time.Parse(revisionIDLayout, id)
+		if perr != nil {
+			continue
+		}
+		revs = append(revs, Revision{ID: id, Saved: saved.Local()})
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i].ID > revs[j].ID })
+	return revs, nil
+}
+
+// revisionPath finds the file holding revision id of date.
+func (m *Manager) revisionPath(date, id string) (string, Format, error) {
+	if id == "" || filepath.Base(id) != id {
+		return "", "", fmt.Errorf("invalid revision %q", id)
+	}
+	for _, f := range formats {
+		path := filepath.Join(m.historyDir(date), id+f.ext())
+		if _, err := os.Stat(path); err == nil {
+			return path, f, nil
+		}
+	}
+	return "", "", fmt.Errorf("revision %q of %s not found", id, date)
+}
+
+// LoadRevision reads one revision of date.
+func (m *Manager) LoadRevision(date, id string) (*Scratchpad, error) {
+	path, f, err := m.revisionPath(date, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision: %w", err)
+	}
+	scratchpad, err := m.decodeDay(data, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision: %w", err)
+	}
+	return scratchpad, nil
+}
+
+// Restore rolls date back to revision id. The current version stays in
+// history, so a restore can itself be undone.
+func (m *Manager) Restore(date, id string) (*Scratchpad, error) {
+	rev, err := m.LoadRevision(date, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.GetByDate(date)
+	if err != nil {
+		// A day that no longer parses is exactly what restore is for;
+		// rebuild it from the revision instead of giving up.
+		current = &Scratchpad{Date: date, Created: rev.Created}
+	}
+	current.Content = rev.Content
+	current.AppliedTemplates = append([]string(nil), rev.AppliedTemplates...)
+	if err := m.save(current, fmt.Sprintf("Restore %s to revision %s", date, id)); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
@@ -0,0 +1,204 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func setupHistoryManager(t *testing.T, policy HistoryPolicy) *Manager {
+	mgr := setupTestManager(t)
+	mgr.SetHistoryPolicy(policy)
+	return mgr
+}
+
+func saveContent(t *testing.T, mgr *Manager, date, content string) {
+	t.Helper()
+	sp, err := mgr.GetByDate(date)
+	if err != nil {
+		t.Fatalf("GetByDate: %v", err)
+	}
+	sp.Content = content
+	if err := mgr.Save(sp); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+}
+
+func TestSaveRecordsRevisions(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	date := "2024-07-01"
+	for _, content := range []string{"one", "two", "three"} {
+		saveContent(t, mgr, date, content)
+	}
+
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("revisions = %d, want 3", len(revs))
+	}
+	newest, err := mgr.LoadRevision(date, revs[0].ID)
+	if err != nil {
+		t.Fatalf("LoadRevision: %v", err)
+	}
+	if newest.Content != "three" {
+		t.Errorf("newest revision = %q, want %q", newest.Content, "three")
+	}
+	oldest, err := mgr.LoadRevision(date, revs[2].ID)
+	if err != nil {
+		t.Fatalf("LoadRevision: %v", err)
+	}
+	if oldest.Content != "one" {
+		t.Errorf("oldest revision = %q, want %q", oldest.Content, "one")
+	}
+}
+
+func TestHistoryKeepBoundsRevisions(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 2})
+	date := "2024-07-02"
+	for _, content := range []string{"one", "two", "three", "four"} {
+		saveContent(t, mgr, date, content)
+	}
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("revisions = %d, want 2", len(revs))
+	}
+	oldest, err := mgr.LoadRevision(date, revs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest.Content != "three" {
+		t.Errorf("oldest kept = %q, want %q", oldest.Content, "three")
+	}
+}
+
+func TestHistoryMaxAgeKeepsNewest(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10, MaxAge: time.Hour})
+	date := "2024-07-03"
+	old := time.Now().Add(-2 * time.Hour)
+	if err := mgr.writeRevision(date, old, []byte(`{"content":"stale"}`), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	saveContent(t, mgr, date, "fresh")
+
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 1 {
+		t.Fatalf("revisions = %+v, want only the fresh one", revs)
+	}
+}
+
+func TestFirstSaveSnapshotsExistingFile(t *testing.T) {
+	mgr := setupTestManager(t)
+	date := "2024-07-04"
+	saveContent(t, mgr, date, "written before history existed")
+
+	mgr.SetHistoryPolicy(HistoryPolicy{Keep: 10})
+	saveContent(t, mgr, date, "edited")
+
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("revisions = %d, want previous + new", len(revs))
+	}
+	previous, err := mgr.LoadRevision(date, revs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.Content != "written before history existed" {
+		t.Errorf("seeded revision = %q", previous.Content)
+	}
+}
+
+func TestSaveSnapshotsExternalRewrite(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	date := "2024-07-05"
+	saveContent(t, mgr, date, "one")
+
+	// An editor holds the day while another tool rewrites the file with
+	// an older Modified stamp; the editor then saves over it.
+	editing, err := mgr.GetByDate(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encode(&Scratchpad{SchemaVersion: SchemaVersion, Date: date, Content: "from sync"}, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, date+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	editing.Content = "mine"
+	if err := mgr.Save(editing); err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rev := range revs {
+		sp, err := mgr.LoadRevision(date, rev.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, sp.Content)
+	}
+	if !slices.Equal(got, []string{"mine", "from sync", "one"}) {
+		t.Errorf("revisions = %q, want the external version kept between the saves", got)
+	}
+
+	// Saving again over an unchanged file adds no duplicate.
+	saveContent(t, mgr, date, "mine again")
+	if revs, _ := mgr.History(date); len(revs) != 4 {
+		t.Errorf("revisions = %d, want 4", len(revs))
+	}
+}
+
+func TestRestoreRollsBack(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	date := "2024-07-05"
+	saveContent(t, mgr, date, "good")
+	saveContent(t, mgr, date, "oops")
+
+	revs, err := mgr.History(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := mgr.Restore(date, revs[1].ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.Content != "good" {
+		t.Errorf("restored content = %q, want %q", restored.Content, "good")
+	}
+	loaded, err := mgr.GetByDate(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Content != "good" {
+		t.Errorf("persisted content = %q, want %q", loaded.Content, "good")
+	}
+	// The overwritten "oops" version stays available.
+	if revs, _ = mgr.History(date); len(revs) != 3 {
+		t.Errorf("revisions after restore = %d, want 3", len(revs))
+	}
+}
+
+func TestLoadRevisionRejectsPathTraversal(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 10})
+	if _, err := mgr.LoadRevision("2024-07-06", "../../2024-07-06"); err == nil {
+		t.Error("expected error for revision id with path separators")
+	}
+}
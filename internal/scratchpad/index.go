@@ -0,0 +1,249 @@
+package scratchpad
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// indexFileName caches a DaySummary per day in the storage directory so
+// listing views start without reading every day. It has no format
+// extension and is never mistaken for a day.
+const indexFileName = ".index"
+
+// indexVersion is bumped whenever DaySummary changes meaning; older
+// caches are then rebuilt from the day files.
+const indexVersion = 2
+
+// DaySummary is what listing views show for a day without loading its
+// content.
+type DaySummary struct {
+	Date string `json:"date"`
+	// File is the day file, relative to the storage directory. With
+	// ModTime and Size it decides whether a cached summary is current.
+	File             string    `json:"file"`
+	ModTime          time.Time `json:"mtime"`
+	Size             int64     `json:"size"`
+	Preview          string    `json:"preview,omitempty"`
+	Words            int       `json:"words"`
+	AppliedTemplates []string  `json:"applied_templates,omitempty"`
+	// Conflict marks a day holding both sides of a sync conflict.
+	Conflict bool `json:"conflict,omitempty"`
+	// Err is set when the day could not be read. Such summaries are not
+	// cached, so the file is tried again next time.
+	Err error `json:"-"`
+}
+
+// Summarize describes scratchpad's content for listing views.
+func Summarize(scratchpad *Scratchpad) DaySummary {
+	return DaySummary{
+		Date:             scratchpad.Date,
+		Preview:          previewLine(scratchpad.Content),
+		Words:            len(strings.Fields(scratchpad.Content)),
+		AppliedTemplates: append([]string(nil), scratchpad.AppliedTemplates...),
+		Conflict:         HasConflict(scratchpad.Content),
+	}
+}
+
+// previewLine returns the first non-empty line of content without its
+// heading markers. It matches the calendar's own preview of edited days.
+func previewLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// storedIndex is the on-disk cache. In an encrypted store Days is sealed
+// into Sealed, since previews are content.
+type storedIndex struct {
+	Version int                   `json:"version"`
+	Days    map[string]DaySummary `json:"days,omitempty"`
+	Sealed  string                `json:"sealed,omitempty"`
+}
+
+// Summaries returns a summary of every saved day in date order. Days whose
+// file is unchanged since it was cached are not read; the rest are read
+// once and the cache is updated.
+func (m *Manager) Summaries() ([]DaySummary, error) {
+	files, err := m.scanDayFiles()
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string][]storedFile, len(files))
+	for _, file := range files {
+		if isDate(file.date) {
+			byDate[file.date] = append(byDate[file.date], file)
+		}
+	}
+
+	cached, records := m.readIndex()
+	fresh := make(map[string]DaySummary, len(byDate))
+	summaries := make([]DaySummary, 0, len(byDate))
+	changed := false
+	for date, copies := range byDate {
+		file := copies[0]
+		if len(copies) > 1 {
+			// The same day stored twice: summarize the copy sp reads.
+			if path, at, lerr := m.locate(date); lerr == nil {
+				file = storedFile{path: path, date: date, at: at}
+			}
+		}
+		summary, ok := cached[date]
+		info, serr := os.Stat(file.path)
+		rel, _ := filepath.Rel(m.storageDir, file.path)
+		switch {
+		case serr != nil:
+			summary = DaySummary{Date: date, Err: serr}
+		case ok && summary.File == rel && summary.Size == info.Size() && summary.ModTime.Equal(info.ModTime()):
+		default:
+			summary = m.summarizeFile(file, rel, info)
+			changed = true
+		}
+		summaries = append(summaries, summary)
+		if summary.Err == nil {
+			fresh[date] = summary
+		}
+	}
+	if changed || len(fresh) != len(cached) || records >= journalCompactAt {
+		// The cache only saves work; a failed write costs a slower start.
+		_ = m.saveIndex(fresh)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Date < summaries[j].Date })
+	return summaries, nil
+}
+
+func (m *Manager) summarizeFile(file storedFile, rel string, info fs.FileInfo) DaySummary {
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return DaySummary{Date: file.date, Err: err}
+	}
+	scratchpad, err := m.decodeDay(data, file.at.format)
+	if err != nil {
+		return DaySummary{Date: file.date, Err: err}
+	}
+	summary := Summarize(scratchpad)
+	summary.Date = file.date
+	summary.File = rel
+	summary.ModTime = info.ModTime()
+	summary.Size = info.Size()
+	return summary
+}
+
+// indexSaved appends the summary of a day Save just wrote to the cache,
+// so the next start does not have to read it.
+func (m *Manager) indexSaved(scratchpad *Scratchpad, at dayFile) {
+	path := m.dayPath(scratchpad.Date, at)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	summary := Summarize(scratchpad)
+	summary.File, _ = filepath.Rel(m.storageDir, path)
+	summary.ModTime = info.ModTime()
+	summary.Size = info.Size()
+	line, err := m.encodeRecord(summary, indexFileName)
+	if err != nil {
+		return
+	}
+	// Without a cache there is nothing to append to; the first Summaries
+	// call builds it in full.
+	records, err := appendJournal(filepath.Join(m.storageDir, indexFileName), line)
+	if err != nil || records < journalCompactAt {
+		return
+	}
+	if days := m.loadIndex(); days != nil {
+		_ = m.saveIndex(days)
+	}
+}
+
+// loadIndex reads the cache, returning nil when there is none or it cannot
+// be used.
+func (m *Manager) loadIndex() map[string]DaySummary {
+	days, _ := m.readIndex()
+	return days
+}
+
+// readIndex is loadIndex, also returning the number of summaries appended
+// to the cache since it was written.
+func (m *Manager) readIndex() (map[string]DaySummary, int) {
+	snapshot, records, err := readJournal(filepath.Join(m.storageDir, indexFileName))
+	if err != nil {
+		return nil, 0
+	}
+	var stored storedIndex
+	if json.Unmarshal(snapshot, &stored) != nil || stored.Version != indexVersion {
+		return nil, 0
+	}
+	days := stored.Days
+	switch {
+	case stored.Sealed == "" && m.cipher != nil:
+		return nil, 0 // written before the store was encrypted
+	case stored.Sealed != "":
+		if m.cipher == nil {
+			return nil, 0
+		}
+		sealed, err := base64.StdEncoding.DecodeString(stored.Sealed)
+		if err != nil {
+			return nil, 0
+		}
+		plain, err := m.cipher.Open(sealed, []byte(indexFileName))
+		if err != nil {
+			return nil, 0
+		}
+		if json.Unmarshal(plain, &days) != nil {
+			return nil, 0
+		}
+	}
+	if days == nil {
+		days = map[string]DaySummary{}
+	}
+	for _, line := range records {
+		var summary DaySummary
+		if m.decodeRecord(line, indexFileName, &summary) != nil {
+			return nil, 0
+		}
+		days[summary.Date] = summary
+	}
+	return days, len(records)
+}
+
+func (m *Manager) saveIndex(days map[string]DaySummary) error {
+	stored := storedIndex{Version: indexVersion, Days: days}
+	if m.cipher != nil {
+		plain, err := json.Marshal(days)
+		if err != nil {
+			return err
+		}
+		sealed, err := m.cipher.Seal(plain, []byte(indexFileName))
+		if err != nil {
+			return err
+		}
+		stored = storedIndex{Version: indexVersion, Sealed: base64.StdEncoding.EncodeToString(sealed)}
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	return writeJournal(filepath.Join(m.storageDir, indexFileName), data, m.filePerm())
+}
+
+// dropIndex removes the cache and the full-text index, e.g. after
+// Recrypt, so no preview or term outlives the key it was sealed with.
+func (m *Manager) dropIndex() error {
+	err := os.Remove(filepath.Join(m.storageDir, indexFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove index: %w", err)
+	}
+	return m.dropSearchIndex()
+}
@@ -0,0 +1,209 @@
+package scratchpad
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummariesDescribeEveryDay(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-02", "\n## Standup\n\nship the index")
+	saveContent(t, mgr, "2024-03-01", "one two three")
+
+	summaries, err := mgr.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 || summaries[0].Date != "2024-03-01" {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+	if got := summaries[1]; got.Preview != "Standup" || got.Words != 5 || got.File != "2024-03-02.json" {
+		t.Errorf("summary = %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, indexFileName)); err != nil {
+		t.Errorf("index not written: %v", err)
+	}
+	if dates, _ := mgr.ListDates(); len(dates) != 2 {
+		t.Errorf("the index must not show up as a day: %v", dates)
+	}
+}
+
+func TestSummariesReuseUnchangedEntries(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "original")
+	if _, err := mgr.Summaries(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cached entry that still matches the file is trusted as is.
+	days := mgr.loadIndex()
+	entry := days["2024-03-01"]
+	entry.Preview = "from cache"
+	days["2024-03-01"] = entry
+	if err := mgr.saveIndex(days); err != nil {
+		t.Fatal(err)
+	}
+	summaries, _ := mgr.Summaries()
+	if summaries[0].Preview != "from cache" {
+		t.Errorf("preview = %q, want the cached one", summaries[0].Preview)
+	}
+
+	// Once the file changes behind sp's back, it is read again.
+	path := filepath.Join(mgr.storageDir, "2024-03-01.json")
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("original"), []byte("changed outside"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	summaries, _ = mgr.Summaries()
+	if summaries[0].Preview != "changed outside" {
+		t.Errorf("preview = %q after an external edit", summaries[0].Preview)
+	}
+}
+
+func TestSaveAndDeleteKeepIndexCurrent(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "first")
+	saveContent(t, mgr, "2024-03-02", "second")
+	if _, err := mgr.Summaries(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(mgr.storageDir, indexFileName)
+	before, _ := os.ReadFile(path)
+	saveContent(t, mgr, "2024-03-01", "edited")
+	if got := mgr.loadIndex()["2024-03-01"].Preview; got != "edited" {
+		t.Errorf("index after save = %q", got)
+	}
+	// Only the saved day is written: its summary is appended to the cache.
+	if after, _ := os.ReadFile(path); !bytes.HasPrefix(after, before) || bytes.Count(after, []byte("\n")) != 2 {
+		t.Errorf("save should append one summary:\n%s", after)
+	}
+
+	if err := mgr.Delete("2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+	summaries, _ := mgr.Summaries()
+	if len(summaries) != 1 {
+		t.Errorf("deleted day still summarized: %+v", summaries)
+	}
+	if _, ok := mgr.loadIndex()["2024-03-02"]; ok {
+		t.Error("deleted day still cached")
+	}
+}
+
+func TestSummariesReportUnreadableDays(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-01.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	summaries, err := mgr.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || summaries[0].Err == nil {
+		t.Errorf("summaries = %+v", summaries)
+	}
+	if _, ok := mgr.loadIndex()["2024-03-01"]; ok {
+		t.Error("unreadable day must not be cached")
+	}
+}
+
+func TestEncryptedIndexKeepsPreviewsSealed(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	saveContent(t, mgr, "2024-03-01", "secret plans")
+
+	summaries, err := mgr.Summaries()
+	if err != nil || summaries[0].Preview != "secret plans" {
+		t.Fatalf("summaries = %+v, %v", summaries, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(mgr.storageDir, indexFileName))
+	if len(data) == 0 || bytes.Contains(data, []byte("secret")) {
+		t.Errorf("index leaks content:\n%s", data)
+	}
+
+	mgr.SetCipher(nil)
+	if mgr.loadIndex() != nil {
+		t.Error("a sealed index must not load without the key")
+	}
+}
+
+// syntheticStore fills a store with ten years of days, the scale the index
+// exists for.
+func syntheticStore(b *testing.B) *Manager {
+	b.Helper()
+	mgr := &Manager{storageDir: b.TempDir()}
+	day := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := strings.Repeat("- [ ] follow up on the thing from yesterday\n", 20)
+	for i := 0; i < 3650; i++ {
+		date := day.AddDate(0, 0, i).Format(dateLayout)
+		data, err := encode(&Scratchpad{
+			SchemaVersion: SchemaVersion,
+			Date:          date,
+			Content:       fmt.Sprintf("# %s\n\n%s", date, body),
+			Created:       day,
+			Modified:      day,
+		}, FormatJSON)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(mgr.storageDir, date+".json"), data, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return mgr
+}
+
+// BenchmarkLoadEveryDay is what startup cost before the index: read and
+// decode every day.
+func BenchmarkLoadEveryDay(b *testing.B) {
+	mgr := syntheticStore(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dates, err := mgr.ListDates()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, date := range dates {
+			if _, err := mgr.GetByDate(date); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkSummariesCold builds the index from scratch, as on the first
+// start after upgrading.
+func BenchmarkSummariesCold(b *testing.B) {
+	mgr := syntheticStore(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := mgr.dropIndex(); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if _, err := mgr.Summaries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSummariesWarm is a normal start: every entry is current.
+func BenchmarkSummariesWarm(b *testing.B) {
+	mgr := syntheticStore(b)
+	if _, err := mgr.Summaries(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mgr.Summaries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
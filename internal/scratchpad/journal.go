@@ -0,0 +1,112 @@
+package scratchpad
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The caches beside the day files are journals: a snapshot on the first
+// line, then one record per line for each change made since. Updating a
+// single day appends a record instead of rewriting and resealing the
+// whole cache; readers replay the records over the snapshot. A torn or
+// damaged line makes the cache unusable, and it is rebuilt like a lost one.
+
+// journalCompactAt is the number of appended records after which a
+// journal is written afresh as a single snapshot.
+const journalCompactAt = 64
+
+// readJournal returns the snapshot line of the journal at path and the
+// records appended to it since.
+func readJournal(path string) (snapshot []byte, records [][]byte, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	return lines[0], lines[1:], nil
+}
+
+// writeJournal replaces the journal at path with snapshot and no records.
+func writeJournal(path string, snapshot []byte, perm os.FileMode) error {
+	return writeFileAtomic(path, append(snapshot, '\n'), perm)
+}
+
+// appendJournal appends record to the journal at path and returns the
+// number of records it now holds. There must be a journal to append to:
+// without a snapshot the record would be all the cache knows.
+func appendJournal(path string, record []byte) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, err
+	}
+	records := bytes.Count(data, []byte("\n"))
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		// A snapshot written before caches were journals.
+		record = append([]byte("\n"), record...)
+		records++
+	}
+	if _, err := f.Write(append(record, '\n')); err != nil {
+		_ = f.Close()
+		return 0, fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// sealedRecord is a journal record in an encrypted store.
+type sealedRecord struct {
+	Sealed string `json:"sealed"`
+}
+
+// encodeRecord marshals v as a journal record. In an encrypted store it
+// is sealed under additional, since records hold content.
+func (m *Manager) encodeRecord(v any, additional string) ([]byte, error) {
+	plain, err := json.Marshal(v)
+	if err != nil || m.cipher == nil {
+		return plain, err
+	}
+	sealed, err := m.cipher.Seal(plain, []byte(additional))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealedRecord{Sealed: base64.StdEncoding.EncodeToString(sealed)})
+}
+
+// errPlainRecord is a record written before the store was encrypted.
+var errPlainRecord = errors.New("journal record is not sealed")
+
+// decodeRecord reverses encodeRecord.
+func (m *Manager) decodeRecord(record []byte, additional string, v any) error {
+	var sealed sealedRecord
+	if err := json.Unmarshal(record, &sealed); err != nil {
+		return err
+	}
+	if sealed.Sealed == "" {
+		if m.cipher != nil {
+			return errPlainRecord
+		}
+		return json.Unmarshal(record, v)
+	}
+	if m.cipher == nil {
+		return ErrLocked
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed.Sealed)
+	if err != nil {
+		return err
+	}
+	plain, err := m.cipher.Open(data, []byte(additional))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, v)
+}
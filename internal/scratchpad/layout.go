@@ -0,0 +1,184 @@
+package scratchpad
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layout selects how day files are arranged in the storage directory.
+type Layout string
+
+const (
+	// LayoutFlat keeps every day at the top level as YYYY-MM-DD.<ext>.
+	// Default.
+	LayoutFlat Layout = "flat"
+	// LayoutNested files days as YYYY/MM/DD.<ext>, keeping each directory
+	// small once a store spans years.
+	LayoutNested Layout = "nested"
+)
+
+// layouts lists every supported arrangement. Reads search all of them, so
+// a store can be restructured without downtime.
+var layouts = []Layout{LayoutFlat, LayoutNested}
+
+// ParseLayout maps a config or flag value to a Layout. Empty selects
+// LayoutFlat.
+func ParseLayout(s string) (Layout, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "flat":
+		return LayoutFlat, nil
+	case "nested":
+		return LayoutNested, nil
+	}
+	return "", fmt.Errorf("unknown storage layout %q (want flat or nested)", s)
+}
+
+// SetLayout selects where future saves put day files. Days stored in the
+// other layout stay readable and move when next saved.
+func (m *Manager) SetLayout(l Layout) {
+	m.layout = l
+}
+
+// dayFile identifies where and how one day is stored.
+type dayFile struct {
+	layout Layout
+	format Format
+}
+
+// current is the location future saves use.
+func (m *Manager) current() dayFile {
+	at := dayFile{layout: m.layout, format: m.format}
+	if at.layout == "" {
+		at.layout = LayoutFlat
+	}
+	if at.format == "" {
+		at.format = FormatJSON
+	}
+	return at
+}
+
+// candidates lists every location date might be stored at, the current
+// one first.
+func (m *Manager) candidates() []dayFile {
+	cur := m.current()
+	all := []dayFile{cur}
+	for _, l := range layouts {
+		for _, f := range formats {
+			if at := (dayFile{layout: l, format: f}); at != cur {
+				all = append(all, at)
+			}
+		}
+	}
+	return all
+}
+
+// dayPath returns the file for date at the given location. Names that are
+// not dates cannot be nested and always stay flat.
+func (m *Manager) dayPath(date string, at dayFile) string {
+	if at.layout == LayoutNested {
+		if day, err := time.Parse(dateLayout, date); err == nil {
+			return filepath.Join(m.storageDir, day.Format("2006"), day.Format("01"), day.Format("02")+at.format.ext())
+		}
+	}
+	return filepath.Join(m.storageDir, date+at.format.ext())
+}
+
+// removeDayFile deletes path and prunes the month and year directories it
+// leaves empty. A missing file is not an error.
+func (m *Manager) removeDayFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	m.pruneEmptyParents(path)
+	return nil
+}
+
+// pruneEmptyParents removes the directories between path and the storage
+// root that are left empty.
+func (m *Manager) pruneEmptyParents(path string) {
+	for dir := filepath.Dir(path); dir != m.storageDir && strings.HasPrefix(dir, m.storageDir); dir = filepath.Dir(dir) {
+		// Remove fails on non-empty directories, which is exactly when
+		// pruning has to stop.
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+}
+
+// storedFile is a file whose location and extension make it a day file.
+// date is derived from the name and is not necessarily a valid date.
+type storedFile struct {
+	path string
+	date string
+	at   dayFile
+}
+
+// scanDayFiles lists the day files of both layouts. Only directories with
+// year- and month-shaped names are entered, so .history and other
+// housekeeping directories are never scanned.
+func (m *Manager) scanDayFiles() ([]storedFile, error) {
+	entries, err := os.ReadDir(m.storageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+	var files []storedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if !entry.IsDir() && isFormatExt(ext) {
+			files = append(files, storedFile{
+				path: filepath.Join(m.storageDir, name),
+				date: strings.TrimSuffix(name, ext),
+				at:   dayFile{layout: LayoutFlat, format: Format(ext[1:])},
+			})
+		}
+	}
+	for _, year := range entries {
+		if !year.IsDir() || !isDigits(year.Name(), 4) {
+			continue
+		}
+		months, err := os.ReadDir(filepath.Join(m.storageDir, year.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read year directory: %w", err)
+		}
+		for _, month := range months {
+			if !month.IsDir() || !isDigits(month.Name(), 2) {
+				continue
+			}
+			dir := filepath.Join(m.storageDir, year.Name(), month.Name())
+			days, err := os.ReadDir(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read month directory: %w", err)
+			}
+			for _, day := range days {
+				ext := filepath.Ext(day.Name())
+				if day.IsDir() || !isFormatExt(ext) {
+					continue
+				}
+				files = append(files, storedFile{
+					path: filepath.Join(dir, day.Name()),
+					date: year.Name() + "-" + month.Name() + "-" + strings.TrimSuffix(day.Name(), ext),
+					at:   dayFile{layout: LayoutNested, format: Format(ext[1:])},
+				})
+			}
+		}
+	}
+	return files, nil
+}
+
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNestedLayoutPaths(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetLayout(LayoutNested)
+	if err := mgr.Save(&Scratchpad{Date: "2024-03-05", Content: "nested"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2024", "03", "05.json")); err != nil {
+		t.Fatalf("nested file not written: %v", err)
+	}
+	loaded, err := mgr.GetByDate("2024-03-05")
+	if err != nil || loaded.Content != "nested" {
+		t.Errorf("GetByDate = %+v, %v", loaded, err)
+	}
+	if err := mgr.Delete("2024-03-05"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2024")); !os.IsNotExist(err) {
+		t.Error("empty year directory should be pruned after delete")
+	}
+}
+
+func TestLayoutsAreReadTransparently(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := mgr.Save(&Scratchpad{Date: "2023-12-31", Content: "flat"}); err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetLayout(LayoutNested)
+	if err := mgr.Save(&Scratchpad{Date: "2024-01-01", Content: "nested"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dates, err := mgr.ListDates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(dates)
+	if !slices.Equal(dates, []string{"2023-12-31", "2024-01-01"}) {
+		t.Errorf("dates = %v", dates)
+	}
+
+	flat, err := mgr.GetByDate("2023-12-31")
+	if err != nil || flat.Content != "flat" {
+		t.Fatalf("flat day unreadable from nested manager: %+v, %v", flat, err)
+	}
+	flat.Content = "moved"
+	if err := mgr.Save(flat); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2023-12-31.json")); !os.IsNotExist(err) {
+		t.Error("flat copy should be removed once the day is saved nested")
+	}
+	dates, _ = mgr.ListDates()
+	if len(dates) != 2 {
+		t.Errorf("dates after move = %v", dates)
+	}
+}
+
+func TestListDatesIgnoresHousekeepingDirs(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 5})
+	mgr.SetLayout(LayoutNested)
+	saveContent(t, mgr, "2024-02-29", "leap")
+	saveContent(t, mgr, "2024-02-29", "leap again")
+	if err := os.MkdirAll(filepath.Join(mgr.storageDir, "2024", "13"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024", "02", "notes.md"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dates, err := mgr.ListDates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dates, []string{"2024-02-29"}) {
+		t.Errorf("dates = %v", dates)
+	}
+}
+
+func TestConvertRestructuresStore(t *testing.T) {
+	mgr := setupTestManager(t)
+	for _, date := range []string{"2022-06-01", "2024-03-05"} {
+		if err := mgr.Save(&Scratchpad{Date: date, Content: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := mgr.Convert(FormatJSON, LayoutNested)
+	if err != nil || n != 2 {
+		t.Fatalf("Convert to nested = %d, %v", n, err)
+	}
+	for _, path := range []string{"2022/06/01.json", "2024/03/05.json"} {
+		if _, err := os.Stat(filepath.Join(mgr.storageDir, path)); err != nil {
+			t.Errorf("%s missing: %v", path, err)
+		}
+	}
+
+	n, err = mgr.Convert(FormatMarkdown, LayoutFlat)
+	if err != nil || n != 2 {
+		t.Fatalf("Convert back to flat md = %d, %v", n, err)
+	}
+	entries, err := os.ReadDir(mgr.storageDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			t.Errorf("leftover directory %s after flattening", entry.Name())
+		}
+	}
+	day, err := mgr.GetByDate("2022-06-01")
+	if err != nil || day.Content != "2022-06-01" {
+		t.Errorf("GetByDate after round trip = %+v, %v", day, err)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	for in, want := range map[string]Layout{"": LayoutFlat, "flat": LayoutFlat, "Nested": LayoutNested} {
+		if got, err := ParseLayout(in); err != nil || got != want {
+			t.Errorf("ParseLayout(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseLayout("daily"); err == nil {
+		t.Error("expected error for unknown layout")
+	}
+}
@@ -0,0 +1,105 @@
+package scratchpad
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pders01/sp/internal/templates"
+)
+
+// MemoryStore is an in-memory Store. It is safe for concurrent use and
+// hands out copies, so callers cannot mutate stored days behind its back.
+type MemoryStore struct {
+	mu   sync.Mutex
+	days map[string]Scratchpad
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{days: make(map[string]Scratchpad)}
+}
+
+// GetByDate returns a copy of the stored day, or a blank one.
+func (s *MemoryStore) GetByDate(date string) (*Scratchpad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(date), nil
+}
+
+func (s *MemoryStore) get(date string) *Scratchpad {
+	stored, ok := s.days[date]
+	if !ok {
+		now := time.Now()
+		return &Scratchpad{SchemaVersion: SchemaVersion, Date: date, Created: now, Modified: now}
+	}
+	stored.AppliedTemplates = append([]string(nil), stored.AppliedTemplates...)
+	stored.Attachments = append([]Attachment(nil), stored.Attachments...)
+	return &stored
+}
+
+// Save stores a copy of scratchpad.
+func (s *MemoryStore) Save(scratchpad *Scratchpad) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(scratchpad)
+	return nil
+}
+
+func (s *MemoryStore) put(scratchpad *Scratchpad) {
+	scratchpad.Modified = time.Now()
+	scratchpad.SchemaVersion = SchemaVersion
+	stored := *scratchpad
+	stored.AppliedTemplates = append([]string(nil), scratchpad.AppliedTemplates...)
+	stored.Attachments = append([]Attachment(nil), scratchpad.Attachments...)
+	s.days[scratchpad.Date] = stored
+}
+
+// ListDates returns the stored dates in ascending order.
+func (s *MemoryStore) ListDates() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dates := make([]string, 0, len(s.days))
+	for date := range s.days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// Summaries summarizes every stored day in date order.
+func (s *MemoryStore) Summaries() ([]DaySummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summaries := make([]DaySummary, 0, len(s.days))
+	for _, day := range s.days {
+		summaries = append(summaries, Summarize(&day))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Date < summaries[j].Date })
+	return summaries, nil
+}
+
+// Delete removes date. Like the filesystem store, deleting a day that was
+// never saved reports fs.ErrNotExist.
+func (s *MemoryStore) Delete(date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.days[date]; !ok {
+		return fmt.Errorf("delete %s: %w", date, fs.ErrNotExist)
+	}
+	delete(s.days, date)
+	return nil
+}
+
+// ApplyTemplateSections appends previously unused template sections to date.
+func (s *MemoryStore) ApplyTemplateSections(date string, sections []templates.Section) (*Scratchpad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scratchpad := s.get(date)
+	if applyTemplateSections(scratchpad, sections) {
+		s.put(scratchpad)
+	}
+	return scratchpad, nil
+}
@@ -0,0 +1,179 @@
+package scratchpad
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Conflict markers wrap the two versions of a day that changed on two
+// machines, as git does, so the user can resolve them in the editor.
+const (
+	conflictStart  = "<<<<<<< "
+	conflictMiddle = "======="
+	conflictEnd    = ">>>>>>> "
+)
+
+// HasConflict reports whether content still holds both versions of a
+// conflicting sync.
+func HasConflict(content string) bool {
+	var start, middle bool
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, conflictStart):
+			start = true
+		case start && line == conflictMiddle:
+			middle = true
+		case middle && strings.HasPrefix(line, conflictEnd):
+			return true
+		}
+	}
+	return false
+}
+
+// MergeConflict combines two versions of the file at path, relative to
+// the storage directory, that both changed since they last agreed. Days
+// and pages keep differing content twice between conflict markers;
+// applied templates and attachments are the union of both sides, and the
+// result is encoded like the file, sealed in an encrypted store. An
+// attachment cannot be combined: the local version stays and the remote
+// one is written beside it under the next free name, recorded in the day
+// and linked from its content.
+func (m *Manager) MergeConflict(path string, local, remote []byte) ([]byte, error) {
+	f := Format(strings.TrimPrefix(filepath.Ext(path), "."))
+	if date, ok := m.dateOfPath(filepath.Join(m.storageDir, path)); ok {
+		return m.mergeVersions(date, f, local, remote, func(merged *Scratchpad) {
+			merged.Date = date
+		})
+	}
+	if slug, ok := pageOfPath(path); ok {
+		return m.mergeVersions("page "+slug, f, local, remote, func(merged *Scratchpad) {
+			merged.Date = ""
+		})
+	}
+	if date, name, ok := attachmentOfPath(path); ok {
+		if err := m.keepRemoteAttachment(date, name, remote); err != nil {
+			return nil, err
+		}
+		return local, nil
+	}
+	return nil, fmt.Errorf("%s is not a day, page or attachment", path)
+}
+
+// mergeVersions decodes both versions of a day or page, what names it in
+// errors, and encodes their combination after fix has set its identity.
+func (m *Manager) mergeVersions(what string, f Format, local, remote []byte, fix func(*Scratchpad)) ([]byte, error) {
+	mine, err := m.decodeDay(local, f)
+	if err != nil {
+		return nil, fmt.Errorf("read local %s: %w", what, err)
+	}
+	theirs, err := m.decodeDay(remote, f)
+	if err != nil {
+		return nil, fmt.Errorf("read remote %s: %w", what, err)
+	}
+
+	merged := *mine
+	if merged.Name == "" {
+		merged.Name = theirs.Name
+	}
+	fix(&merged)
+	merged.Content = conflictContent(mine.Content, theirs.Content)
+	for _, id := range theirs.AppliedTemplates {
+		if !slices.Contains(merged.AppliedTemplates, id) {
+			merged.AppliedTemplates = append(merged.AppliedTemplates, id)
+		}
+	}
+	addAttachments(&merged, theirs.Attachments)
+	if theirs.Created.Before(merged.Created) {
+		merged.Created = theirs.Created
+	}
+	if theirs.Modified.After(merged.Modified) {
+		merged.Modified = theirs.Modified
+	}
+	return m.encodeDay(&merged, f)
+}
+
+// pageOfPath maps a page file path, relative to the storage directory,
+// to the page's slug.
+func pageOfPath(path string) (string, bool) {
+	dir, file := filepath.Split(filepath.Clean(path))
+	ext := filepath.Ext(file)
+	slug := strings.TrimSuffix(file, ext)
+	if filepath.Clean(dir) != pagesDirName || !isFormatExt(ext) || slug == "" || PageSlug(slug) != slug {
+		return "", false
+	}
+	return slug, true
+}
+
+// attachmentOfPath maps an attachment path, relative to the storage
+// directory, to its day and file name, which may end in sealedExt.
+func attachmentOfPath(path string) (string, string, bool) {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	if len(parts) != 3 || parts[0] != attachmentsDirName || !isDate(parts[1]) || strings.HasPrefix(parts[2], ".") {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// keepRemoteAttachment writes the remote version of date's attachment
+// file beside the local one under a free name, sealed like the store, and
+// records it in the day as Attach would. The day is written directly, not
+// saved: a sync is under way and commits it with the merge.
+func (m *Manager) keepRemoteAttachment(date, file string, remote []byte) error {
+	name := strings.TrimSuffix(file, sealedExt)
+	data := remote
+	if name != file {
+		if m.cipher == nil {
+			return ErrLocked
+		}
+		var err error
+		if data, err = m.cipher.Open(remote, attachmentAD(date, name)); err != nil {
+			return fmt.Errorf("open remote %s: %w", file, err)
+		}
+	}
+	day, err := m.GetByDate(date)
+	if err != nil {
+		return err
+	}
+	at := m.current()
+	if _, found, lerr := m.locate(date); lerr == nil {
+		at = found
+	}
+	copyName := m.freeAttachmentName(day, name)
+	if err := m.writeAttachmentAt(m.attachmentDir(date), date, copyName, data, m.cipher); err != nil {
+		return err
+	}
+	addAttachment(day, Attachment{Name: copyName, Size: int64(len(data)), Added: time.Now()})
+	day.Modified = time.Now()
+	encoded, err := m.encodeDay(day, at.format)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scratchpad: %w", err)
+	}
+	if err := m.writeDay(date, at, encoded); err != nil {
+		return fmt.Errorf("failed to write scratchpad file: %w", err)
+	}
+	m.indexSaved(day, at)
+	m.searchIndexSaved(day, at)
+	return nil
+}
+
+// conflictContent keeps both versions of a day between conflict markers,
+// the local one first.
+func conflictContent(local, remote string) string {
+	if local == remote {
+		return local
+	}
+	withNewline := func(s string) string {
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			s += "\n"
+		}
+		return s
+	}
+	return conflictStart + "this machine\n" +
+		withNewline(local) +
+		conflictMiddle + "\n" +
+		withNewline(remote) +
+		conflictEnd + "remote\n"
+}
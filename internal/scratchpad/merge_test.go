@@ -0,0 +1,197 @@
+package scratchpad
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pders01/sp/internal/templates"
+)
+
+func TestMergeConflictKeepsBothVersions(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	day := func(content string, applied []string, modified time.Time) []byte {
+		t.Helper()
+		data, err := mgr.encodeDay(&Scratchpad{
+			SchemaVersion:    SchemaVersion,
+			Date:             "2024-03-01",
+			Content:          content,
+			AppliedTemplates: applied,
+			Created:          modified,
+			Modified:         modified,
+		}, FormatMarkdown)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	early := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	local := day("written on the laptop", []string{"standup"}, early)
+	remote := day("written on the desktop\n", []string{"retro", "standup"}, late)
+
+	data, err := mgr.MergeConflict("2024/03/01.md", local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := mgr.decodeDay(data, FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<<<<<<< this machine\nwritten on the laptop\n=======\nwritten on the desktop\n>>>>>>> remote\n"
+	if merged.Content != want {
+		t.Errorf("content = %q, want %q", merged.Content, want)
+	}
+	if !HasConflict(merged.Content) {
+		t.Error("merged content should report a conflict")
+	}
+	if !slices.Equal(merged.AppliedTemplates, []string{"standup", "retro"}) {
+		t.Errorf("applied = %v, want the union", merged.AppliedTemplates)
+	}
+	if !merged.Created.Equal(early) || !merged.Modified.Equal(late) {
+		t.Errorf("created %v, modified %v", merged.Created, merged.Modified)
+	}
+
+	if _, err := mgr.MergeConflict(".vault", local, remote); err == nil {
+		t.Error("expected error for a file that is not a day")
+	}
+}
+
+func TestMergeConflictMergesPagesBySlug(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	page := func(name, content string) []byte {
+		t.Helper()
+		data, err := mgr.encodeDay(&Scratchpad{SchemaVersion: SchemaVersion, Name: name, Content: content}, FormatJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	data, err := mgr.MergeConflict("pages/oncall-runbook.json", page("Oncall Runbook", "mine\n"), page("oncall runbook", "theirs\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := mgr.decodeDay(data, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Name != "Oncall Runbook" || merged.Date != "" || !HasConflict(merged.Content) {
+		t.Errorf("merged page = %+v", merged)
+	}
+
+	if _, err := mgr.MergeConflict("pages/Not A Slug.json", data, data); err == nil {
+		t.Error("expected error for a file no page is stored under")
+	}
+}
+
+func TestMergeConflictKeepsBothAttachments(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	if _, err := mgr.Attach("2024-03-01", "shot.png", strings.NewReader("laptop")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(attachmentsDirName, "2024-03-01", "shot.png"+sealedExt)
+	local, err := os.ReadFile(filepath.Join(mgr.Dir(), path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := mgr.cipher.Seal([]byte("desktop"), attachmentAD("2024-03-01", "shot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := mgr.MergeConflict(filepath.ToSlash(path), local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, local) {
+		t.Error("the local attachment should stay in place")
+	}
+	if got, err := mgr.ReadAttachment("2024-03-01", "shot-2.png"); err != nil || string(got) != "desktop" {
+		t.Errorf("remote copy = %q, %v", got, err)
+	}
+	day, err := mgr.GetByDate("2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day.Attachments) != 2 || day.Attachments[1].Name != "shot-2.png" ||
+		!strings.Contains(day.Content, AttachmentLink("2024-03-01", day.Attachments[1])) {
+		t.Errorf("the remote copy should be recorded and linked in the day: %+v", day)
+	}
+}
+
+func TestHasConflict(t *testing.T) {
+	for content, want := range map[string]bool{
+		"":                                      false,
+		"plain notes":                           false,
+		"<<<<<<< a\nx\n=======\ny\n>>>>>>> b\n": true,
+		"<<<<<<< a\nx\n>>>>>>> b\n":             false,
+		"=======\nunderlined heading":           false,
+	} {
+		if got := HasConflict(content); got != want {
+			t.Errorf("HasConflict(%q) = %v", content, got)
+		}
+	}
+}
+
+func TestChangeHookSeesEveryChange(t *testing.T) {
+	mgr := setupTestManager(t)
+	var changes []Change
+	mgr.SetChangeHook(func(c Change) error {
+		changes = append(changes, c)
+		return nil
+	})
+
+	saveContent(t, mgr, "2024-03-01", "first")
+	if _, err := mgr.ApplyTemplateSections("2024-03-01", []templates.Section{{ID: "standup", Title: "Standup", Body: "-"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Delete("2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.RestoreTrashed("2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	var summaries []string
+	for _, c := range changes {
+		summaries = append(summaries, c.Summary)
+	}
+	want := []string{
+		"Update 2024-03-01",
+		"Apply templates to 2024-03-01 (standup)",
+		"Delete 2024-03-01",
+		"Restore 2024-03-01 from the trash",
+	}
+	if !slices.Equal(summaries, want) {
+		t.Errorf("summaries = %q, want %q", summaries, want)
+	}
+	if paths := changes[0].Paths; !slices.Contains(paths, "2024-03-01.json") || !slices.Contains(paths, filepath.Join("2024", "03", "01.md")) {
+		t.Errorf("paths = %v, want every location of the day", paths)
+	}
+}
+
+func TestSummariesFlagConflicts(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", conflictContent("mine", "theirs"))
+	saveContent(t, mgr, "2024-03-02", "settled")
+	summaries, err := mgr.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summaries[0].Conflict || summaries[1].Conflict {
+		t.Errorf("summaries = %+v", summaries)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, indexFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if !mgr.loadIndex()["2024-03-01"].Conflict {
+		t.Error("conflict flag not cached")
+	}
+}
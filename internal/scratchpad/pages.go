@@ -0,0 +1,207 @@
+package scratchpad
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// pagesDirName holds named pages: undated notes such as runbooks and
+// reference lists, one file per page named after its slug. Day scans never
+// enter it, so pages stay out of ListDates and the calendar.
+const pagesDirName = "pages"
+
+// pageLink matches a [[page name]] link in day or page content.
+var pageLink = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// PageSlug is the file name a page is stored under: the name in lower
+// case with every run of other characters than letters and digits turned
+// into a hyphen, so "Oncall Runbook" and "oncall-runbook" are the same
+// page. It is empty for names without letters or digits.
+func PageSlug(name string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(name) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pending = b.Len() > 0
+			continue
+		}
+		if pending {
+			b.WriteByte('-')
+			pending = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// PageLinks returns the pages content links to with [[page name]], in the
+// order they first appear. Links that differ only in spelling (see
+// PageSlug) are listed once.
+func PageLinks(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range pageLink.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimSpace(match[1])
+		slug := PageSlug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m *Manager) pagePath(slug string, f Format) string {
+	return filepath.Join(m.storageDir, pagesDirName, slug+f.ext())
+}
+
+// pageSlug validates name for use as a page.
+func pageSlug(name string) (string, error) {
+	slug := PageSlug(name)
+	if slug == "" {
+		return "", fmt.Errorf("invalid page name %q: it needs a letter or digit", name)
+	}
+	return slug, nil
+}
+
+// GetPage returns the page called name, or a blank one when it has not
+// been saved yet.
+func (m *Manager) GetPage(name string) (*Scratchpad, error) {
+	slug, err := pageSlug(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range m.pageFormats() {
+		data, rerr := os.ReadFile(m.pagePath(slug, f))
+		if errors.Is(rerr, fs.ErrNotExist) {
+			continue
+		}
+		if rerr != nil {
+			return nil, fmt.Errorf("failed to read page: %w", rerr)
+		}
+		page, derr := m.decodeDay(data, f)
+		if derr != nil {
+			return nil, fmt.Errorf("failed to parse page %s: %w", name, derr)
+		}
+		if page.Name == "" {
+			page.Name = name
+		}
+		return page, nil
+	}
+	now := time.Now()
+	return &Scratchpad{SchemaVersion: SchemaVersion, Name: name, Created: now, Modified: now}, nil
+}
+
+// SavePage writes page, found by its Name, atomically in the store's
+// format and removes a copy left in another one. Pages are sealed like
+// days in an encrypted store and reach the change hook, but keep no
+// revision history.
+func (m *Manager) SavePage(page *Scratchpad) error {
+	slug, err := pageSlug(page.Name)
+	if err != nil {
+		return err
+	}
+	page.Date = ""
+	page.Modified = time.Now()
+	page.SchemaVersion = SchemaVersion
+
+	format := m.current().format
+	data, err := m.encodeDay(page, format)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page: %w", err)
+	}
+	path := m.pagePath(slug, format)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create pages directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, m.filePerm()); err != nil {
+		return fmt.Errorf("failed to write page: %w", err)
+	}
+	change := Change{Page: page.Name, Summary: "Update page " + page.Name}
+	for _, f := range formats {
+		if f != format {
+			if err := os.Remove(m.pagePath(slug, f)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("remove stale copy: %w", err)
+			}
+		}
+		change.Paths = append(change.Paths, filepath.Join(pagesDirName, slug+f.ext()))
+	}
+	return m.notify(change, page.Name)
+}
+
+// ListPages returns the names of the saved pages, sorted without regard
+// to case. A page file without a recorded name, e.g. one written by
+// another editor, is listed under its slug.
+func (m *Manager) ListPages() ([]string, error) {
+	files, err := m.scanPageFiles()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		if seen[file.date] {
+			continue
+		}
+		seen[file.date] = true
+		name := file.date
+		if data, rerr := os.ReadFile(file.path); rerr == nil {
+			if stored, derr := decode(data, file.at.format); derr == nil && stored.Name != "" {
+				name = stored.Name
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	return names, nil
+}
+
+// scanPageFiles lists the page files in the pages directory; files not
+// named after a slug cannot be looked up by name and are skipped. The
+// date of each is the page's slug.
+func (m *Manager) scanPageFiles() ([]storedFile, error) {
+	dir := filepath.Join(m.storageDir, pagesDirName)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pages: %w", err)
+	}
+	var files []storedFile
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		slug := strings.TrimSuffix(entry.Name(), ext)
+		if entry.IsDir() || !isFormatExt(ext) || slug == "" || PageSlug(slug) != slug {
+			continue
+		}
+		files = append(files, storedFile{
+			path: filepath.Join(dir, entry.Name()),
+			date: slug,
+			at:   dayFile{layout: LayoutFlat, format: Format(ext[1:])},
+		})
+	}
+	return files, nil
+}
+
+// pageFormats lists the encodings a page is looked up in, the store's own
+// first.
+func (m *Manager) pageFormats() []Format {
+	current := m.current().format
+	all := []Format{current}
+	for _, f := range formats {
+		if f != current {
+			all = append(all, f)
+		}
+	}
+	return all
+}
@@ -0,0 +1,126 @@
+package scratchpad
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPageSlug(t *testing.T) {
+	for name, want := range map[string]string{
+		"oncall runbook":      "oncall-runbook",
+		"  Oncall  Runbook! ": "oncall-runbook",
+		"Café / Menü":         "café-menü",
+		"2024 Q1 goals":       "2024-q1-goals",
+		"../../etc/passwd":    "etc-passwd",
+		"?!":                  "",
+	} {
+		if got := PageSlug(name); got != want {
+			t.Errorf("PageSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	content := "See [[Oncall Runbook]] and [[reading list]].\nAgain: [[oncall-runbook]], not [[ ]] or [link](x)."
+	if got, want := PageLinks(content), []string{"Oncall Runbook", "reading list"}; !slices.Equal(got, want) {
+		t.Errorf("PageLinks = %q, want %q", got, want)
+	}
+}
+
+func TestPagesStayOutOfDays(t *testing.T) {
+	for _, f := range formats {
+		t.Run(string(f), func(t *testing.T) {
+			mgr := setupTestManager(t)
+			mgr.SetFormat(f)
+			saveContent(t, mgr, "2024-03-01", "on call, see [[Oncall Runbook]]")
+			page, err := mgr.GetPage("Oncall Runbook")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Content != "" || page.Name != "Oncall Runbook" {
+				t.Fatalf("new page = %+v", page)
+			}
+			page.Content = "# Runbook\n\n1. Check the dashboards\n"
+			if err := mgr.SavePage(page); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(mgr.Dir(), "pages", "oncall-runbook"+f.ext())); err != nil {
+				t.Errorf("page file: %v", err)
+			}
+
+			got, err := mgr.GetPage("oncall runbook")
+			if err != nil || got.Content != page.Content || got.Name != "Oncall Runbook" {
+				t.Errorf("GetPage = %+v, %v", got, err)
+			}
+			if names, err := mgr.ListPages(); err != nil || !slices.Equal(names, []string{"Oncall Runbook"}) {
+				t.Errorf("ListPages = %q, %v", names, err)
+			}
+			if dates, _ := mgr.ListDates(); !slices.Equal(dates, []string{"2024-03-01"}) {
+				t.Errorf("pages leaked into ListDates: %v", dates)
+			}
+			if summaries, _ := mgr.Summaries(); len(summaries) != 1 {
+				t.Errorf("pages leaked into Summaries: %+v", summaries)
+			}
+		})
+	}
+}
+
+func TestSavePageMovesToCurrentFormatAndNotifies(t *testing.T) {
+	mgr := setupTestManager(t)
+	page := &Scratchpad{Name: "Reading list", Content: "- SICP\n"}
+	if err := mgr.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	var changes []Change
+	mgr.SetChangeHook(func(c Change) error {
+		changes = append(changes, c)
+		return nil
+	})
+	mgr.SetFormat(FormatMarkdown)
+	if err := mgr.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(mgr.Dir(), "pages"))
+	if len(entries) != 1 || entries[0].Name() != "reading-list.md" {
+		t.Errorf("pages directory = %v", entries)
+	}
+	want := []string{filepath.Join("pages", "reading-list.json"), filepath.Join("pages", "reading-list.md")}
+	if len(changes) != 1 || changes[0].Page != "Reading list" || changes[0].Date != "" || !slices.Equal(changes[0].Paths, want) {
+		t.Errorf("changes = %+v", changes)
+	}
+	if err := mgr.SavePage(&Scratchpad{Name: "--"}); err == nil {
+		t.Error("a name without letters or digits should be refused")
+	}
+}
+
+func TestPagesAreSealedAndRecrypted(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := mgr.SavePage(&Scratchpad{Name: "Secrets", Content: "plain secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Recrypt(testCipher(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SavePage(&Scratchpad{Name: "More", Content: "sealed secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if disk := storeBytes(t, mgr); bytes.Contains(disk, []byte("secret")) {
+		t.Errorf("plain text found on disk:\n%s", disk)
+	}
+	for name, want := range map[string]string{"Secrets": "plain secret", "More": "sealed secret"} {
+		if page, err := mgr.GetPage(name); err != nil || page.Content != want {
+			t.Errorf("GetPage(%s) = %+v, %v", name, page, err)
+		}
+	}
+	// The name is listed without the key.
+	locked := &Manager{storageDir: mgr.Dir()}
+	if names, err := locked.ListPages(); err != nil || !slices.Equal(names, []string{"More", "Secrets"}) {
+		t.Errorf("ListPages without the key = %q, %v", names, err)
+	}
+	if _, err := locked.GetPage("Secrets"); err == nil {
+		t.Error("reading a sealed page without the key should fail")
+	}
+}
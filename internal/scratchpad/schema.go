@@ -0,0 +1,176 @@
+package scratchpad
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the layout of Scratchpad written by this build. Files
+// from before versioning carry no schema_version and count as version 0.
+//
+// Version 2 introduced sealed (encrypted) content; older builds must not
+// read such a file as an empty day. Version 3 lists attachments, which an
+// older build would drop on its next save.
+const SchemaVersion = 3
+
+// ErrNewerSchema reports a file written by a newer sp than this one.
+var ErrNewerSchema = errors.New("written by a newer version of sp")
+
+// migration upgrades a raw document from schema version from to from+1.
+// Documents are keyed like the JSON encoding (Markdown front matter uses
+// the same names) so a migration can rename or reshape fields the current
+// struct no longer has.
+type migration struct {
+	from        int
+	description string
+	apply       func(doc map[string]any) error
+}
+
+// migrations is the registry, one entry per version step. Add a step here
+// and bump SchemaVersion whenever the stored shape of a day changes.
+var migrations = []migration{
+	{
+		from:        0,
+		description: "record schema_version",
+		apply:       func(map[string]any) error { return nil },
+	},
+	{
+		from:        1,
+		description: "allow sealed content",
+		apply:       func(map[string]any) error { return nil },
+	},
+	{
+		from:        2,
+		description: "list attachments",
+		apply:       func(map[string]any) error { return nil },
+	},
+}
+
+// upgrade walks doc from version from up to version to through registry.
+// It returns the descriptions of the applied steps.
+func upgrade(doc map[string]any, from, to int, registry []migration) ([]string, error) {
+	var steps []string
+	for version := from; version < to; version++ {
+		step, ok := findMigration(registry, version)
+		if !ok {
+			return steps, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := step.apply(doc); err != nil {
+			return steps, fmt.Errorf("migrate schema %d→%d: %w", version, version+1, err)
+		}
+		doc["schema_version"] = version + 1
+		steps = append(steps, step.description)
+	}
+	return steps, nil
+}
+
+func findMigration(registry []migration, from int) (migration, bool) {
+	for _, step := range registry {
+		if step.from == from {
+			return step, true
+		}
+	}
+	return migration{}, false
+}
+
+// decode parses data and upgrades documents written under an older schema.
+// Files from a newer sp are rejected rather than silently misread. Sealed
+// content is returned as stored; see Manager.decodeDay.
+func decode(data []byte, f Format) (*storedDay, error) {
+	stored, err := decodeStored(data, f)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case stored.SchemaVersion == SchemaVersion:
+		return stored, nil
+	case stored.SchemaVersion > SchemaVersion:
+		return nil, fmt.Errorf("schema version %d > %d: %w", stored.SchemaVersion, SchemaVersion, ErrNewerSchema)
+	}
+	doc, err := decodeDocument(data, f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := upgrade(doc, stored.SchemaVersion, SchemaVersion, migrations); err != nil {
+		return nil, err
+	}
+	return documentToDay(doc)
+}
+
+func documentToDay(doc map[string]any) (*storedDay, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var day storedDay
+	if err := json.Unmarshal(data, &day); err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// PendingMigration describes a stored day whose file predates
+// SchemaVersion, or could not be checked.
+type PendingMigration struct {
+	Date  string
+	From  int
+	Steps []string
+	Err   error
+}
+
+// PendingMigrations lists the days whose files would change under
+// MigrateDay, in ListDates order. Nothing is written.
+func (m *Manager) PendingMigrations() ([]PendingMigration, error) {
+	dates, err := m.ListDates()
+	if err != nil {
+		return nil, err
+	}
+	var pending []PendingMigration
+	for _, date := range dates {
+		_, data, at, rerr := m.readDay(date)
+		if data == nil {
+			pending = append(pending, PendingMigration{Date: date, Err: rerr})
+			continue
+		}
+		stored, derr := decodeStored(data, at.format)
+		if derr != nil {
+			pending = append(pending, PendingMigration{Date: date, Err: derr})
+			continue
+		}
+		if stored.SchemaVersion == SchemaVersion {
+			continue
+		}
+		p := PendingMigration{Date: date, From: stored.SchemaVersion, Err: rerr}
+		if rerr == nil {
+			doc, _ := decodeDocument(data, at.format)
+			p.Steps, p.Err = upgrade(doc, stored.SchemaVersion, SchemaVersion, migrations)
+		}
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
+
+// MigrateDay rewrites date's file under the current schema, in place and
+// in its current format. Content and timestamps are unchanged; the old
+// bytes are kept in history unless the newest revision holds them.
+func (m *Manager) MigrateDay(date string) error {
+	scratchpad, data, at, err := m.readDay(date)
+	if err != nil {
+		return err
+	}
+	stored, err := decodeStored(data, at.format)
+	if err != nil || stored.SchemaVersion == SchemaVersion {
+		return err
+	}
+	scratchpad.SchemaVersion = SchemaVersion
+	upgraded, err := m.encodeDay(scratchpad, at.format)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scratchpad: %w", err)
+	}
+	if err := m.snapshotPrevious(date, time.Now()); err != nil {
+		return fmt.Errorf("failed to record previous revision: %w", err)
+	}
+	return m.writeDay(date, at, upgraded)
+}
@@ -0,0 +1,133 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyDay = `{
+  "date": "2023-05-01",
+  "content": "before versioning",
+  "applied_templates": ["workday-timebox"],
+  "created": "2023-05-01T08:00:00Z",
+  "modified": "2023-05-01T09:00:00Z"
+}`
+
+func writeLegacyDay(t *testing.T, mgr *Manager) string {
+	t.Helper()
+	path := filepath.Join(mgr.storageDir, "2023-05-01.json")
+	if err := os.WriteFile(path, []byte(legacyDay), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrationRegistryCoversEveryVersion(t *testing.T) {
+	for version := 0; version < SchemaVersion; version++ {
+		if _, ok := findMigration(migrations, version); !ok {
+			t.Errorf("no migration registered from schema version %d", version)
+		}
+	}
+}
+
+func TestGetByDateUpgradesLegacyFile(t *testing.T) {
+	mgr := setupTestManager(t)
+	path := writeLegacyDay(t, mgr)
+
+	sp, err := mgr.GetByDate("2023-05-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.SchemaVersion != SchemaVersion || sp.Content != "before versioning" || len(sp.AppliedTemplates) != 1 {
+		t.Errorf("upgraded day = %+v", sp)
+	}
+	if data, _ := os.ReadFile(path); string(data) != legacyDay {
+		t.Error("loading must not rewrite the file")
+	}
+}
+
+func TestUpgradeAppliesStepsInOrder(t *testing.T) {
+	registry := []migration{
+		{from: 1, description: "rename tags", apply: func(doc map[string]any) error {
+			doc["applied_templates"] = doc["tags"]
+			delete(doc, "tags")
+			return nil
+		}},
+		{from: 0, description: "noop", apply: func(map[string]any) error { return nil }},
+	}
+	doc := map[string]any{"date": "2023-05-01", "tags": []any{"a"}}
+	steps, err := upgrade(doc, 0, 2, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(steps, ",") != "noop,rename tags" {
+		t.Errorf("steps = %v", steps)
+	}
+	sp, err := documentToDay(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.SchemaVersion != 2 || len(sp.AppliedTemplates) != 1 || sp.AppliedTemplates[0] != "a" {
+		t.Errorf("migrated day = %+v", sp)
+	}
+
+	if _, err := upgrade(map[string]any{}, 0, 3, registry); err == nil {
+		t.Error("expected error for a missing step")
+	}
+}
+
+func TestDecodeRejectsNewerSchema(t *testing.T) {
+	data := []byte(`{"schema_version": 99, "date": "2023-05-01"}`)
+	if _, err := decode(data, FormatJSON); err == nil {
+		t.Error("expected error for a file from a newer schema")
+	}
+}
+
+func TestMigrateDayRewritesOnlyPendingFiles(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 5})
+	writeLegacyDay(t, mgr)
+	saveContent(t, mgr, "2023-05-02", "current")
+
+	pending, err := mgr.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Date != "2023-05-01" || pending[0].From != 0 || len(pending[0].Steps) != SchemaVersion {
+		t.Fatalf("pending = %+v", pending)
+	}
+
+	before, _ := mgr.GetByDate("2023-05-01")
+	if err := mgr.MigrateDay("2023-05-01"); err != nil {
+		t.Fatal(err)
+	}
+	stored, _, at, err := mgr.readDay("2023-05-01")
+	if err != nil || at.format != FormatJSON {
+		t.Fatalf("readDay = %v, %v", at, err)
+	}
+	if !sameDay(before, stored) || stored.SchemaVersion != SchemaVersion {
+		t.Errorf("migration changed the day: %+v", stored)
+	}
+	revs, _ := mgr.History("2023-05-01")
+	if len(revs) != 1 {
+		t.Errorf("original bytes should be kept in history, got %d revisions", len(revs))
+	}
+	if pending, _ := mgr.PendingMigrations(); len(pending) != 0 {
+		t.Errorf("still pending after migrate: %+v", pending)
+	}
+}
+
+func TestPendingMigrationsReportsUnreadableDays(t *testing.T) {
+	mgr := setupTestManager(t)
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2023-05-03.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := mgr.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Err == nil {
+		t.Errorf("pending = %+v", pending)
+	}
+}
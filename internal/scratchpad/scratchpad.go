@@ -1,93 +1,200 @@
 package scratchpad
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/pders01/sp/internal/paths"
 	"github.com/pders01/sp/internal/templates"
 )
 
-// Scratchpad represents a daily scratchpad entry
+// Scratchpad represents a daily scratchpad entry, or a named page (see
+// SavePage) when Name is set instead of Date.
 type Scratchpad struct {
-	Date             string    `json:"date"`
-	Content          string    `json:"content"`
-	AppliedTemplates []string  `json:"applied_templates,omitempty"`
-	Created          time.Time `json:"created"`
-	Modified         time.Time `json:"modified"`
+	SchemaVersion    int          `json:"schema_version"`
+	Date             string       `json:"date"`
+	Name             string       `json:"name,omitempty"`
+	Content          string       `json:"content"`
+	AppliedTemplates []string     `json:"applied_templates,omitempty"`
+	Attachments      []Attachment `json:"attachments,omitempty"`
+	Created          time.Time    `json:"created"`
+	Modified         time.Time    `json:"modified"`
 }
 
-// Manager handles scratchpad operations
+// Manager is the filesystem Store: one file per day in the storage
+// directory, encoded as JSON or Markdown (see Format) and arranged flat or
+// by year and month (see Layout).
 type Manager struct {
 	storageDir string
+	format     Format
+	layout     Layout
+	history    HistoryPolicy
+	cipher     Cipher
+	onChange   ChangeHook
 }
 
-// NewManager creates a new scratchpad manager
+// NewManager creates a scratchpad manager in the default data directory
+// ($SP_HOME, $XDG_DATA_HOME/sp, or ~/.sp; see paths.DataDir). It agrees
+// with config.DefaultPath on which of those is in use.
 func NewManager() (*Manager, error) {
-	homeDir, err := os.UserHomeDir()
+	storageDir, err := paths.DataDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
 	}
+	return NewManagerAt(storageDir)
+}
 
-	storageDir := filepath.Join(homeDir, ".sp")
+// NewManagerAt creates a scratchpad manager storing day files in
+// storageDir, creating the directory when needed.
+func NewManagerAt(storageDir string) (*Manager, error) {
 	if err := os.MkdirAll(storageDir, 0o755); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
-	return &Manager{storageDir: storageDir}, nil
+	return &Manager{storageDir: storageDir, format: FormatJSON, layout: LayoutFlat, history: DefaultHistoryPolicy()}, nil
+}
+
+// Dir returns the directory holding the day files.
+func (m *Manager) Dir() string {
+	return m.storageDir
+}
+
+// SetFormat selects the encoding used by future saves. Days stored in
+// another format stay readable and are rewritten in f when next saved.
+func (m *Manager) SetFormat(f Format) {
+	m.format = f
+}
+
+// locate finds the file holding date, trying the configured layout and
+// format first. It returns an error wrapping fs.ErrNotExist when the day
+// was never saved.
+func (m *Manager) locate(date string) (string, dayFile, error) {
+	for _, at := range m.candidates() {
+		path := m.dayPath(date, at)
+		if _, err := os.Stat(path); err == nil {
+			return path, at, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", dayFile{}, err
+		}
+	}
+	return "", dayFile{}, fmt.Errorf("%s: %w", date, fs.ErrNotExist)
+}
+
+// readDay loads the stored version of date along with its raw bytes and
+// location.
+func (m *Manager) readDay(date string) (*Scratchpad, []byte, dayFile, error) {
+	path, at, err := m.locate(date)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, dayFile{}, err
+	}
+	if err != nil {
+		return nil, nil, dayFile{}, fmt.Errorf("failed to read scratchpad file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, dayFile{}, fmt.Errorf("failed to read scratchpad file: %w", err)
+	}
+	scratchpad, err := m.decodeDay(data, at.format)
+	if err != nil {
+		return nil, data, at, fmt.Errorf("failed to parse scratchpad file: %w", err)
+	}
+	if scratchpad.Date == "" {
+		scratchpad.Date = date
+	}
+	return scratchpad, data, at, nil
+}
+
+// writeDay atomically stores encoded data for date at the given location
+// and drops any copy left in another layout or format, so each day lives
+// in exactly one file.
+func (m *Manager) writeDay(date string, at dayFile, data []byte) error {
+	path := m.dayPath(date, at)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create day directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, m.filePerm()); err != nil {
+		return err
+	}
+	for _, other := range m.candidates() {
+		if stale := m.dayPath(date, other); stale != path {
+			if err := m.removeDayFile(stale); err != nil {
+				return fmt.Errorf("remove stale copy: %w", err)
+			}
+		}
+	}
+	return nil
 }
 
 // GetToday returns today's scratchpad, creating it if it doesn't exist
 func (m *Manager) GetToday() (*Scratchpad, error) {
-	today := time.Now().Format("2006-01-02")
-	return m.GetByDate(today)
+	return m.GetByDate(Today())
 }
 
 // GetByDate returns a scratchpad for a specific date
 func (m *Manager) GetByDate(date string) (*Scratchpad, error) {
-	filename := filepath.Join(m.storageDir, date+".json")
-
-	data, err := os.ReadFile(filename)
-	if os.IsNotExist(err) {
+	scratchpad, _, _, err := m.readDay(date)
+	if errors.Is(err, fs.ErrNotExist) {
 		// Create new scratchpad for this date
 		scratchpad := &Scratchpad{
-			Date:     date,
-			Content:  "",
-			Created:  time.Now(),
-			Modified: time.Now(),
+			SchemaVersion: SchemaVersion,
+			Date:          date,
+			Content:       "",
+			Created:       time.Now(),
+			Modified:      time.Now(),
 		}
 		return scratchpad, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read scratchpad file: %w", err)
+		return nil, err
 	}
+	return scratchpad, nil
+}
 
-	var scratchpad Scratchpad
-	if err := json.Unmarshal(data, &scratchpad); err != nil {
-		return nil, fmt.Errorf("failed to parse scratchpad file: %w", err)
+// Lookup returns the stored day for date. Unlike GetByDate a missing day
+// is an error wrapping fs.ErrNotExist, not a fresh empty scratchpad.
+func (m *Manager) Lookup(date string) (*Scratchpad, error) {
+	scratchpad, _, _, err := m.readDay(date)
+	if err != nil {
+		return nil, err
 	}
-
-	return &scratchpad, nil
+	return scratchpad, nil
 }
 
-// Save saves a scratchpad to disk
+// Save saves a scratchpad to disk. The write is atomic: a crash or a full
+// disk mid-save leaves the previous version of the day intact. Each save
+// is also appended to the day's revision history.
 func (m *Manager) Save(scratchpad *Scratchpad) error {
+	return m.save(scratchpad, "Update "+scratchpad.Date)
+}
+
+// save is Save with the summary handed to the change hook.
+func (m *Manager) save(scratchpad *Scratchpad, summary string) error {
 	scratchpad.Modified = time.Now()
+	scratchpad.SchemaVersion = SchemaVersion
 
-	data, err := json.MarshalIndent(scratchpad, "", "  ")
+	at := m.current()
+	data, err := m.encodeDay(scratchpad, at.format)
 	if err != nil {
 		return fmt.Errorf("failed to marshal scratchpad: %w", err)
 	}
 
-	filename := filepath.Join(m.storageDir, scratchpad.Date+".json")
-	if err := os.WriteFile(filename, data, 0o644); err != nil {
+	if err := m.snapshotPrevious(scratchpad.Date, scratchpad.Modified); err != nil {
+		return fmt.Errorf("failed to record previous revision: %w", err)
+	}
+	if err := m.writeDay(scratchpad.Date, at, data); err != nil {
 		return fmt.Errorf("failed to write scratchpad file: %w", err)
 	}
-
-	return nil
+	m.indexSaved(scratchpad, at)
+	m.searchIndexSaved(scratchpad, at)
+	if err := m.recordRevision(scratchpad.Date, scratchpad.Modified, data, at.format); err != nil {
+		return fmt.Errorf("saved, but failed to record revision: %w", err)
+	}
+	return m.changed(scratchpad.Date, summary)
 }
 
 // ApplyTemplateSections appends previously unused template sections to date.
@@ -98,62 +205,38 @@ func (m *Manager) ApplyTemplateSections(date string, sections []templates.Sectio
 	if err != nil {
 		return nil, err
 	}
-
-	applied := make(map[string]bool, len(scratchpad.AppliedTemplates))
-	for _, id := range scratchpad.AppliedTemplates {
-		applied[id] = true
-	}
-
-	content := scratchpad.Content
-	changed := false
-	for _, section := range sections {
-		alreadyApplied := applied[section.ID]
-		if section.ID == "" || (alreadyApplied && !section.Force) {
-			continue
-		}
-		if content != "" {
-			trailingNewlines := len(content) - len(strings.TrimRight(content, "\n"))
-			if trailingNewlines < 2 {
-				content += strings.Repeat("\n", 2-trailingNewlines)
-			}
-		}
-		content += "## " + strings.TrimSpace(section.Title) + "\n\n" + strings.TrimSpace(section.Body) + "\n"
-		if !alreadyApplied {
-			scratchpad.AppliedTemplates = append(scratchpad.AppliedTemplates, section.ID)
-			applied[section.ID] = true
-		}
-		changed = true
-	}
-	if !changed {
+	before := len(scratchpad.AppliedTemplates)
+	if !applyTemplateSections(scratchpad, sections) {
 		return scratchpad, nil
 	}
-	scratchpad.Content = content
-	if err := m.Save(scratchpad); err != nil {
+	summary := fmt.Sprintf("Apply templates to %s", date)
+	if added := scratchpad.AppliedTemplates[before:]; len(added) > 0 {
+		summary += " (" + strings.Join(added, ", ") + ")"
+	}
+	if err := m.save(scratchpad, summary); err != nil {
 		return nil, err
 	}
 	return scratchpad, nil
 }
 
-// ListDates returns all available scratchpad dates
+// ListDates returns all available scratchpad dates in either layout. Files
+// whose names are not real dates are skipped: a storage directory commonly
+// holds unrelated files such as a README.md (see `sp doctor` for strays),
+// and the conflict copies sync tools leave are listed by ConflictCopies.
 func (m *Manager) ListDates() ([]string, error) {
-	files, err := os.ReadDir(m.storageDir)
+	files, err := m.scanDayFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+		return nil, err
 	}
 
 	var dates []string
+	seen := make(map[string]bool, len(files))
 	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".json" {
-			date := file.Name()[:len(file.Name())-5] // Remove .json extension
-			dates = append(dates, date)
+		if isDate(file.date) && !seen[file.date] {
+			seen[file.date] = true
+			dates = append(dates, file.date)
 		}
 	}
 
 	return dates, nil
 }
-
-// Delete removes a scratchpad for a specific date
-func (m *Manager) Delete(date string) error {
-	filename := filepath.Join(m.storageDir, date+".json")
-	return os.Remove(filename)
-}
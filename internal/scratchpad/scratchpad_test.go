@@ -221,3 +221,29 @@ func TestDelete(t *testing.T) {
 		t.Errorf("file should be deleted, but exists")
 	}
 }
+
+func TestNewManagerAtCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "synced", "sp")
+	mgr, err := NewManagerAt(dir)
+	if err != nil {
+		t.Fatalf("NewManagerAt: %v", err)
+	}
+	if mgr.storageDir != dir {
+		t.Errorf("storageDir = %q, want %q", mgr.storageDir, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("storage dir not created: %v", err)
+	}
+}
+
+func TestNewManagerHonoursSPHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SP_HOME", dir)
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mgr.storageDir != dir {
+		t.Errorf("storageDir = %q, want SP_HOME %q", mgr.storageDir, dir)
+	}
+}
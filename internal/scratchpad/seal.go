@@ -0,0 +1,307 @@
+package scratchpad
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Cipher seals day content at rest. The store passes the day's date (or
+// the page's name) as additional data, so sealed content cannot be moved
+// to another day unnoticed. See internal/vault for the AES-GCM implementation.
+type Cipher interface {
+	Seal(plaintext, additional []byte) ([]byte, error)
+	Open(sealed, additional []byte) ([]byte, error)
+}
+
+// ErrLocked reports sealed content read by a store without a Cipher.
+var ErrLocked = errors.New("content is encrypted; unlock the store to read it")
+
+// storedDay is a day as written to disk. With a Cipher the content is
+// moved into Sealed and Content stays empty; dates, timestamps and
+// template metadata remain readable.
+type storedDay struct {
+	Scratchpad
+	Sealed string `json:"sealed,omitempty"`
+}
+
+// SetCipher makes future saves seal their content and lets reads open
+// sealed days. nil stores plain text again; sealed days then fail to load
+// with ErrLocked.
+func (m *Manager) SetCipher(c Cipher) {
+	m.cipher = c
+}
+
+// Encrypted reports whether saves seal their content.
+func (m *Manager) Encrypted() bool {
+	return m.cipher != nil
+}
+
+// Cipher returns the cipher set by SetCipher, or nil.
+func (m *Manager) Cipher() Cipher {
+	return m.cipher
+}
+
+// filePerm keeps encrypted stores private to the owner; metadata is still
+// readable in them.
+func (m *Manager) filePerm() fs.FileMode {
+	if m.cipher != nil {
+		return 0o600
+	}
+	return 0o644
+}
+
+// encodeDay marshals scratchpad in format f, sealing its content when the
+// store is encrypted.
+func (m *Manager) encodeDay(scratchpad *Scratchpad, f Format) ([]byte, error) {
+	return encodeWith(scratchpad, f, m.cipher)
+}
+
+func encodeWith(scratchpad *Scratchpad, f Format, c Cipher) ([]byte, error) {
+	if c == nil {
+		return encode(scratchpad, f)
+	}
+	sealed, err := c.Seal([]byte(scratchpad.Content), sealedFor(scratchpad))
+	if err != nil {
+		return nil, fmt.Errorf("seal content: %w", err)
+	}
+	day := storedDay{Scratchpad: *scratchpad, Sealed: base64.StdEncoding.EncodeToString(sealed)}
+	day.Content = ""
+	return encodeStored(&day, f)
+}
+
+// sealedFor is the additional data binding sealed content to its day or
+// page.
+func sealedFor(scratchpad *Scratchpad) []byte {
+	if scratchpad.Name != "" {
+		return []byte("page:" + scratchpad.Name)
+	}
+	return []byte(scratchpad.Date)
+}
+
+// decodeDay parses data, upgrading older schemas, and opens sealed
+// content.
+func (m *Manager) decodeDay(data []byte, f Format) (*Scratchpad, error) {
+	return decodeWith(data, f, m.cipher)
+}
+
+func decodeWith(data []byte, f Format, c Cipher) (*Scratchpad, error) {
+	day, err := decode(data, f)
+	if err != nil {
+		return nil, err
+	}
+	if day.Sealed == "" {
+		return &day.Scratchpad, nil
+	}
+	if c == nil {
+		return nil, ErrLocked
+	}
+	sealed, err := base64.StdEncoding.DecodeString(day.Sealed)
+	if err != nil {
+		return nil, fmt.Errorf("sealed content: %w", err)
+	}
+	content, err := c.Open(sealed, sealedFor(&day.Scratchpad))
+	if err != nil {
+		return nil, fmt.Errorf("open sealed content: %w", err)
+	}
+	day.Content = string(content)
+	return &day.Scratchpad, nil
+}
+
+// Recrypt rewrites every day, page, revision, trashed copy, conflict
+// copy, quarantined file and attachment with its content sealed under to,
+// or in plain text when to is nil, and switches the store to it. Files are opened with the current cipher or
+// with to, so an interrupted run can simply be repeated, and files
+// already in the target state are left alone. Timestamps are unchanged.
+// It returns the number of files rewritten.
+func (m *Manager) Recrypt(to Cipher) (int, error) {
+	files, err := m.scanDayFiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, root := range []string{historyDirName, trashDirName, filepath.Join(trashDirName, resolvedDirName)} {
+		stamped, serr := m.scanStampedFiles(root)
+		if serr != nil {
+			return 0, serr
+		}
+		files = append(files, stamped...)
+	}
+	files = slices.DeleteFunc(files, func(file storedFile) bool { return !isDate(file.date) })
+	copies, err := m.ConflictCopies()
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range copies {
+		files = append(files, storedFile{path: filepath.Join(m.storageDir, c.Path), date: c.Date, at: dayFile{format: c.format}})
+	}
+	pages, err := m.scanPageFiles()
+	if err != nil {
+		return 0, err
+	}
+	files = append(files, pages...)
+
+	perm := fs.FileMode(0o644)
+	if to != nil {
+		perm = 0o600
+	}
+	rewritten := 0
+	for _, file := range files {
+		done, rerr := m.recryptFile(file, to, perm)
+		if rerr != nil {
+			return rewritten, rerr
+		}
+		if done {
+			rewritten++
+		}
+	}
+	n, err := m.recryptAllAttachments(to)
+	rewritten += n
+	if err != nil {
+		return rewritten, err
+	}
+	n, err = m.recryptQuarantine(to)
+	rewritten += n
+	if err != nil {
+		return rewritten, err
+	}
+	m.cipher = to
+	return rewritten, m.dropIndex()
+}
+
+// recryptFile rewrites one file for Recrypt, reporting whether it had to.
+func (m *Manager) recryptFile(file storedFile, to Cipher, perm fs.FileMode) (bool, error) {
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", file.path, err)
+	}
+	stored, err := decode(data, file.at.format)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", file.path, err)
+	}
+	var scratchpad *Scratchpad
+	switch {
+	case stored.Sealed == "" && to == nil:
+		return false, nil
+	case stored.Sealed == "":
+		scratchpad = &stored.Scratchpad
+	default:
+		if to != nil {
+			if _, oerr := decodeWith(data, file.at.format, to); oerr == nil {
+				return false, nil // already sealed under the target key
+			}
+		}
+		if scratchpad, err = decodeWith(data, file.at.format, m.cipher); err != nil {
+			return false, fmt.Errorf("%s: %w", file.path, err)
+		}
+	}
+	if scratchpad.Date == "" && scratchpad.Name == "" && isDate(file.date) {
+		// Bind content to the day it belongs to even when the file does
+		// not name it, as a conflict copy from another tool may not.
+		scratchpad.Date = file.date
+	}
+	encoded, err := encodeWith(scratchpad, file.at.format, to)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", file.path, err)
+	}
+	if err := writeFileAtomic(file.path, encoded, perm); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recryptQuarantine seals or opens the files sp doctor moved to
+// .quarantine. They were quarantined because they may not parse, so each
+// is sealed whole, bound to its path there, into a file with sealedExt
+// appended, like an attachment.
+func (m *Manager) recryptQuarantine(to Cipher) (int, error) {
+	root := filepath.Join(m.storageDir, quarantineDirName)
+	rewritten := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == root {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		plain, sealed := strings.CutSuffix(path, sealedExt)
+		rel, _ := filepath.Rel(root, plain)
+		ad := []byte("quarantine:" + filepath.ToSlash(rel))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if sealed {
+			if to != nil {
+				if _, oerr := to.Open(data, ad); oerr == nil {
+					return nil // already sealed under the target key
+				}
+			}
+			if m.cipher == nil {
+				return fmt.Errorf("%s: %w", path, ErrLocked)
+			}
+			if data, err = m.cipher.Open(data, ad); err != nil {
+				return fmt.Errorf("%s: open sealed content: %w", path, err)
+			}
+		} else if to == nil {
+			return nil
+		}
+		target, perm := plain, fs.FileMode(0o644)
+		if to != nil {
+			if data, err = to.Seal(data, ad); err != nil {
+				return fmt.Errorf("%s: seal content: %w", path, err)
+			}
+			target, perm = plain+sealedExt, 0o600
+		}
+		if err := writeFileAtomic(target, data, perm); err != nil {
+			return err
+		}
+		if target != path {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("remove %s: %w", path, err)
+			}
+		}
+		rewritten++
+		return nil
+	})
+	return rewritten, err
+}
+
+// scanStampedFiles lists the timestamp-named files kept per date under
+// root (.history or .trash).
+func (m *Manager) scanStampedFiles(root string) ([]storedFile, error) {
+	dates, err := os.ReadDir(filepath.Join(m.storageDir, root))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	var files []storedFile
+	for _, date := range dates {
+		if !date.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.storageDir, root, date.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || !isFormatExt(ext) {
+				continue
+			}
+			files = append(files, storedFile{
+				path: filepath.Join(dir, entry.Name()),
+				date: date.Name(),
+				at:   dayFile{format: Format(ext[1:])},
+			})
+		}
+	}
+	return files, nil
+}
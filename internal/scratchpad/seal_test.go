@@ -0,0 +1,169 @@
+package scratchpad
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pders01/sp/internal/vault"
+)
+
+func testCipher(t *testing.T, fill byte) Cipher {
+	t.Helper()
+	c, err := vault.NewCipher(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// storeBytes concatenates every file below the storage directory.
+func storeBytes(t *testing.T, mgr *Manager) []byte {
+	t.Helper()
+	var all []byte
+	err := filepath.WalkDir(mgr.storageDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		all = append(all, data...)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+func TestSealedDayKeepsContentOffDisk(t *testing.T) {
+	for _, f := range formats {
+		t.Run(string(f), func(t *testing.T) {
+			mgr := setupHistoryManager(t, HistoryPolicy{Keep: 5})
+			mgr.SetFormat(f)
+			mgr.SetCipher(testCipher(t, 1))
+			saveContent(t, mgr, "2024-03-01", "first secret")
+			saveContent(t, mgr, "2024-03-01", "second secret")
+
+			if disk := storeBytes(t, mgr); bytes.Contains(disk, []byte("secret")) {
+				t.Errorf("plain text found on disk:\n%s", disk)
+			}
+			sp, err := mgr.GetByDate("2024-03-01")
+			if err != nil || sp.Content != "second secret" {
+				t.Fatalf("GetByDate = %+v, %v", sp, err)
+			}
+			revs, _ := mgr.History("2024-03-01")
+			if len(revs) != 2 {
+				t.Fatalf("revisions = %d", len(revs))
+			}
+			if rev, err := mgr.LoadRevision("2024-03-01", revs[1].ID); err != nil || rev.Content != "first secret" {
+				t.Errorf("LoadRevision = %+v, %v", rev, err)
+			}
+			if dates, _ := mgr.ListDates(); len(dates) != 1 {
+				t.Errorf("dates must stay listable without opening content, got %v", dates)
+			}
+		})
+	}
+}
+
+func TestSealedDayNeedsTheKey(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	saveContent(t, mgr, "2024-03-01", "secret")
+
+	mgr.SetCipher(nil)
+	if _, err := mgr.GetByDate("2024-03-01"); !errors.Is(err, ErrLocked) {
+		t.Errorf("without a cipher: err = %v, want ErrLocked", err)
+	}
+	mgr.SetCipher(testCipher(t, 2))
+	if _, err := mgr.GetByDate("2024-03-01"); err == nil {
+		t.Error("expected error opening with the wrong key")
+	}
+}
+
+func TestSealedContentIsBoundToItsDate(t *testing.T) {
+	mgr := setupTestManager(t)
+	mgr.SetCipher(testCipher(t, 1))
+	saveContent(t, mgr, "2024-03-01", "secret")
+
+	data, _ := os.ReadFile(filepath.Join(mgr.storageDir, "2024-03-01.json"))
+	moved := bytes.Replace(data, []byte("2024-03-01"), []byte("2024-03-02"), 1)
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-02.json"), moved, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.GetByDate("2024-03-02"); err == nil {
+		t.Error("content sealed for another day must not open")
+	}
+}
+
+func TestRecryptRoundTrip(t *testing.T) {
+	mgr := setupHistoryManager(t, HistoryPolicy{Keep: 5})
+	saveContent(t, mgr, "2024-03-01", "old secret")
+	saveContent(t, mgr, "2024-03-01", "new secret")
+	saveContent(t, mgr, "2024-03-02", "trashed secret")
+	if err := mgr.Delete("2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two days (one in the trash) and three revisions.
+	n, err := mgr.Recrypt(testCipher(t, 1))
+	if err != nil || n != 5 {
+		t.Fatalf("Recrypt = %d, %v", n, err)
+	}
+	if disk := storeBytes(t, mgr); bytes.Contains(disk, []byte("secret")) {
+		t.Errorf("plain text left after Recrypt:\n%s", disk)
+	}
+	if n, err := mgr.Recrypt(testCipher(t, 1)); err != nil || n != 0 {
+		t.Errorf("repeated Recrypt = %d, %v; want nothing rewritten", n, err)
+	}
+	if err := mgr.RestoreTrashed("2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+	if sp, err := mgr.GetByDate("2024-03-02"); err != nil || sp.Content != "trashed secret" {
+		t.Errorf("restored day = %+v, %v", sp, err)
+	}
+
+	if n, err := mgr.Recrypt(nil); err != nil || n != 5 {
+		t.Fatalf("Recrypt(nil) = %d, %v", n, err)
+	}
+	if mgr.Encrypted() || !bytes.Contains(storeBytes(t, mgr), []byte("old secret")) {
+		t.Error("store should be plain text again")
+	}
+}
+
+func TestRecryptCoversConflictCopiesAndQuarantine(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "mine")
+	writeCopy(t, mgr, "2024-03-01.sync-conflict-20240301-101112-ABCDEFG.json", Scratchpad{Content: "copied secret"})
+	quarantined := filepath.Join(mgr.Dir(), quarantineDirName, "2024", "03", "02.json")
+	if err := os.MkdirAll(filepath.Dir(quarantined), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(quarantined, []byte(`{"content": "quarantined secret`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := mgr.Recrypt(testCipher(t, 1)); err != nil || n != 3 {
+		t.Fatalf("Recrypt = %d, %v", n, err)
+	}
+	if disk := storeBytes(t, mgr); bytes.Contains(disk, []byte("secret")) {
+		t.Errorf("plain text left after Recrypt:\n%s", disk)
+	}
+	if n, err := mgr.Recrypt(testCipher(t, 1)); err != nil || n != 0 {
+		t.Errorf("repeated Recrypt = %d, %v; want nothing rewritten", n, err)
+	}
+
+	if n, err := mgr.Recrypt(nil); err != nil || n != 3 {
+		t.Fatalf("Recrypt(nil) = %d, %v", n, err)
+	}
+	disk := storeBytes(t, mgr)
+	for _, want := range []string{"copied secret", "quarantined secret"} {
+		if !bytes.Contains(disk, []byte(want)) {
+			t.Errorf("%q not readable after Recrypt(nil)", want)
+		}
+	}
+	if _, err := os.Stat(quarantined); err != nil {
+		t.Errorf("quarantined file not restored: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package scratchpad
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LineMatcher decides which lines of a day Search returns. A
+// *regexp.Regexp is one; so is a *WordQuery.
+type LineMatcher interface {
+	MatchString(line string) bool
+}
+
+// SearchQuery selects the lines Search returns.
+type SearchQuery struct {
+	// Pattern matches lines. A *WordQuery is answered from the full-text
+	// index, so only the days holding it are read; any other pattern is
+	// tried against every day.
+	Pattern LineMatcher
+	// Since and Until bound the days searched, inclusive, as YYYY-MM-DD.
+	// Empty leaves that end open.
+	Since, Until string
+	// Context is the number of lines kept around each match.
+	Context int
+}
+
+// SearchResult holds the matching lines of one day, with their context,
+// in line order.
+type SearchResult struct {
+	Date  string       `json:"date"`
+	Lines []SearchLine `json:"lines"`
+}
+
+// SearchLine is a line of a day's content. Number counts from 1; lines
+// that are only context have Match unset.
+type SearchLine struct {
+	Number int    `json:"line"`
+	Text   string `json:"text"`
+	Match  bool   `json:"match"`
+}
+
+// SkippedDay is a day Search or Reindex could not read. The rest of the
+// store is still searched; sp doctor tells what is wrong with the file.
+type SkippedDay struct {
+	Date string
+	// Path is relative to the storage directory, or empty when the file
+	// could not be found.
+	Path string
+	Err  error
+}
+
+// Search returns, oldest first, every day in the query's range with a
+// line matching its pattern. Days are read through the Manager, so the
+// search sees what the TUI sees whatever the format or layout. Days in
+// the range that cannot be read are skipped and returned.
+func (m *Manager) Search(q SearchQuery) ([]SearchResult, []SkippedDay, error) {
+	inRange := func(date string) bool {
+		return (q.Since == "" || date >= q.Since) && (q.Until == "" || date <= q.Until)
+	}
+	dates, unread, err := m.searchDates(q.Pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	var skipped []SkippedDay
+	for _, s := range unread {
+		if inRange(s.Date) {
+			skipped = append(skipped, s)
+		}
+	}
+	var results []SearchResult
+	for _, date := range dates {
+		if !inRange(date) {
+			continue
+		}
+		scratchpad, err := m.Lookup(date)
+		if err != nil {
+			skipped = append(skipped, m.skippedDay(date, "", err))
+			continue
+		}
+		if lines := searchContent(scratchpad.Content, q.Pattern, q.Context); len(lines) > 0 {
+			results = append(results, SearchResult{Date: date, Lines: lines})
+		}
+	}
+	sortSkipped(skipped)
+	return results, skipped, nil
+}
+
+// searchDates returns, oldest first, the days that may match pattern,
+// and the days the index could not read.
+func (m *Manager) searchDates(pattern LineMatcher) ([]string, []SkippedDay, error) {
+	if words, ok := pattern.(*WordQuery); ok {
+		ix, skipped, err := m.currentSearchIndex()
+		if err != nil {
+			return nil, nil, err
+		}
+		return ix.lookup(words), skipped, nil
+	}
+	dates, err := m.ListDates()
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(dates)
+	return dates, nil, nil
+}
+
+// skippedDay describes date, stored at path or wherever sp reads it from,
+// as skipped because of err.
+func (m *Manager) skippedDay(date, path string, err error) SkippedDay {
+	if path == "" {
+		path, _, _ = m.locate(date)
+	}
+	if path != "" {
+		path, _ = filepath.Rel(m.storageDir, path)
+	}
+	return SkippedDay{Date: date, Path: path, Err: err}
+}
+
+// sortSkipped orders skipped days oldest first.
+func sortSkipped(skipped []SkippedDay) {
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Date < skipped[j].Date })
+}
+
+// searchContent is the Store-independent part of Search: it returns the
+// lines of content matching pattern, each with up to context lines on
+// either side. Overlapping context is shared.
+func searchContent(content string, pattern LineMatcher, context int) []SearchLine {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var out []SearchLine
+	next := 0 // first line not yet in out
+	for i, line := range lines {
+		if !pattern.MatchString(line) {
+			continue
+		}
+		if i < next {
+			// Already added as context after an earlier match.
+			out[len(out)-(next-i)].Match = true
+		} else {
+			for j := max(next, i-context); j < i; j++ {
+				out = append(out, SearchLine{Number: j + 1, Text: lines[j]})
+			}
+			out = append(out, SearchLine{Number: i + 1, Text: line, Match: true})
+			next = i + 1
+		}
+		for ; next < min(len(lines), i+1+context); next++ {
+			out = append(out, SearchLine{Number: next + 1, Text: lines[next]})
+		}
+	}
+	return out
+}
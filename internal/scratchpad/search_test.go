@@ -0,0 +1,100 @@
+package scratchpad
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestSearchContent(t *testing.T) {
+	content := "one\ntwo TODO\nthree\nfour\nfive TODO\nsix TODO\nseven\neight\nnine TODO\n"
+	tests := []struct {
+		name    string
+		context int
+		want    []int // line numbers, negative for context lines
+	}{
+		{name: "no context", context: 0, want: []int{2, 5, 6, 9}},
+		{name: "shared context", context: 1, want: []int{-1, 2, -3, -4, 5, 6, -7, -8, 9}},
+		{name: "clipped at the ends", context: 3, want: []int{-1, 2, -3, -4, 5, 6, -7, -8, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := searchContent(content, regexp.MustCompile("TODO"), tt.context)
+			got := make([]int, len(lines))
+			for i, line := range lines {
+				got[i] = line.Number
+				if !line.Match {
+					got[i] = -line.Number
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("lines = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("lines = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestManagerSearch(t *testing.T) {
+	mgr := setupTestManager(t)
+	for date, content := range map[string]string{
+		"2023-12-31": "Wrote about Postgres vacuum.\n",
+		"2024-01-15": "# Notes\n\npostgres upgrade planned\n",
+		"2024-02-01": "Nothing relevant.\n",
+	} {
+		if err := mgr.Save(&Scratchpad{Date: date, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, _, err := mgr.Search(SearchQuery{Pattern: regexp.MustCompile("(?i)postgres")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Date != "2023-12-31" || results[1].Date != "2024-01-15" ||
+		results[1].Lines[0] != (SearchLine{Number: 3, Text: "postgres upgrade planned", Match: true}) {
+		t.Errorf("results = %+v", results)
+	}
+
+	results, _, err = mgr.Search(SearchQuery{Pattern: regexp.MustCompile("(?i)postgres"), Since: "2024-01-01", Until: "2024-01-31"})
+	if err != nil || len(results) != 1 || results[0].Date != "2024-01-15" {
+		t.Errorf("bounded results = %+v, %v", results, err)
+	}
+}
+
+func TestSearchSkipsUnreadableDays(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-03-01", "deploy went fine")
+	saveContent(t, mgr, "2024-03-03", "deploy rolled back")
+	if err := os.WriteFile(filepath.Join(mgr.storageDir, "2024-03-02.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := ParseWordQuery("deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []LineMatcher{regexp.MustCompile("deploy"), words} {
+		results, skipped, err := mgr.Search(SearchQuery{Pattern: pattern})
+		if err != nil {
+			t.Fatalf("Search(%v) = %v", pattern, err)
+		}
+		if len(results) != 2 || len(skipped) != 1 || skipped[0].Date != "2024-03-02" || skipped[0].Path != "2024-03-02.json" {
+			t.Errorf("Search(%v) = %+v, skipped %+v", pattern, results, skipped)
+		}
+		_, skipped, _ = mgr.Search(SearchQuery{Pattern: pattern, Since: "2024-03-03"})
+		if len(skipped) != 0 {
+			t.Errorf("skipped outside the range: %+v", skipped)
+		}
+	}
+
+	n, skipped, err := mgr.Reindex()
+	if err != nil || n != 2 || len(skipped) != 1 {
+		t.Errorf("Reindex = %d, %+v, %v", n, skipped, err)
+	}
+}
@@ -0,0 +1,81 @@
+package scratchpad
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/templates"
+)
+
+// Store is the persistence contract behind sp's commands and TUI. Manager
+// is the filesystem implementation; MemoryStore keeps days in memory for
+// tests and for embedding sp's store in other tools.
+type Store interface {
+	// GetByDate returns the scratchpad for date, or a blank one when the
+	// day has not been saved yet.
+	GetByDate(date string) (*Scratchpad, error)
+	// Save persists scratchpad and stamps its Modified time.
+	Save(scratchpad *Scratchpad) error
+	// ListDates returns every saved date in no particular order.
+	ListDates() ([]string, error)
+	// Delete removes the scratchpad for date. Manager moves it to the
+	// trash; MemoryStore drops it.
+	Delete(date string) error
+	// ApplyTemplateSections appends unused template sections to date and
+	// records them in its metadata.
+	ApplyTemplateSections(date string, sections []templates.Section) (*Scratchpad, error)
+}
+
+var (
+	_ Store = (*Manager)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// dateLayout is the YYYY-MM-DD form used as a key by every Store.
+const dateLayout = "2006-01-02"
+
+// Today returns the current date in the YYYY-MM-DD form used as a key by
+// every Store. The day starts and is counted as clock.Set says.
+func Today() string {
+	return clock.Today().Format(dateLayout)
+}
+
+// isDate reports whether s is a real calendar date in YYYY-MM-DD form.
+func isDate(s string) bool {
+	_, err := time.Parse(dateLayout, s)
+	return err == nil
+}
+
+// applyTemplateSections is the Store-independent part of
+// ApplyTemplateSections: it appends sections to scratchpad in place and
+// reports whether anything changed.
+func applyTemplateSections(scratchpad *Scratchpad, sections []templates.Section) bool {
+	applied := make(map[string]bool, len(scratchpad.AppliedTemplates))
+	for _, id := range scratchpad.AppliedTemplates {
+		applied[id] = true
+	}
+
+	content := scratchpad.Content
+	changed := false
+	for _, section := range sections {
+		alreadyApplied := applied[section.ID]
+		if section.ID == "" || (alreadyApplied && !section.Force) {
+			continue
+		}
+		if content != "" {
+			trailingNewlines := len(content) - len(strings.TrimRight(content, "\n"))
+			if trailingNewlines < 2 {
+				content += strings.Repeat("\n", 2-trailingNewlines)
+			}
+		}
+		content += "## " + strings.TrimSpace(section.Title) + "\n\n" + strings.TrimSpace(section.Body) + "\n"
+		if !alreadyApplied {
+			scratchpad.AppliedTemplates = append(scratchpad.AppliedTemplates, section.ID)
+			applied[section.ID] = true
+		}
+		changed = true
+	}
+	scratchpad.Content = content
+	return changed
+}
@@ -0,0 +1,91 @@
+package scratchpad
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"testing"
+
+	"github.com/pders01/sp/internal/templates"
+)
+
+// storeImplementations runs the Store contract against every backend.
+var storeImplementations = map[string]func(t *testing.T) Store{
+	"filesystem": func(t *testing.T) Store { return setupTestManager(t) },
+	"memory":     func(*testing.T) Store { return NewMemoryStore() },
+}
+
+func TestStoreContract(t *testing.T) {
+	for name, newStore := range storeImplementations {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			blank, err := store.GetByDate("2024-01-01")
+			if err != nil || blank.Date != "2024-01-01" || blank.Content != "" {
+				t.Fatalf("GetByDate on missing day = %+v, %v", blank, err)
+			}
+
+			blank.Content = "hello"
+			if err := store.Save(blank); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if err := store.Save(&Scratchpad{Date: "2024-01-02", Content: "second"}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			loaded, err := store.GetByDate("2024-01-01")
+			if err != nil || loaded.Content != "hello" {
+				t.Fatalf("GetByDate = %+v, %v", loaded, err)
+			}
+
+			dates, err := store.ListDates()
+			if err != nil {
+				t.Fatalf("ListDates: %v", err)
+			}
+			sort.Strings(dates)
+			if len(dates) != 2 || dates[0] != "2024-01-01" || dates[1] != "2024-01-02" {
+				t.Errorf("ListDates = %v", dates)
+			}
+
+			got, err := store.ApplyTemplateSections("2024-01-01", []templates.Section{{
+				ID: "tasks", Title: "Tasks", Body: "- [ ] one",
+			}})
+			if err != nil {
+				t.Fatalf("ApplyTemplateSections: %v", err)
+			}
+			if want := "hello\n\n## Tasks\n\n- [ ] one\n"; got.Content != want {
+				t.Errorf("content = %q, want %q", got.Content, want)
+			}
+			if loaded, _ = store.GetByDate("2024-01-01"); len(loaded.AppliedTemplates) != 1 {
+				t.Errorf("applied templates not persisted: %+v", loaded)
+			}
+
+			if err := store.Delete("2024-01-02"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := store.Delete("2024-01-02"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("second Delete err = %v, want fs.ErrNotExist", err)
+			}
+			if dates, _ = store.ListDates(); len(dates) != 1 {
+				t.Errorf("ListDates after Delete = %v", dates)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+	store := NewMemoryStore()
+	sp := &Scratchpad{Date: "2024-01-01", Content: "saved", AppliedTemplates: []string{"a"}}
+	if err := store.Save(sp); err != nil {
+		t.Fatal(err)
+	}
+	sp.Content = "mutated after save"
+	sp.AppliedTemplates[0] = "b"
+
+	loaded, err := store.GetByDate("2024-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Content != "saved" || loaded.AppliedTemplates[0] != "a" {
+		t.Errorf("stored day changed through caller's pointer: %+v", loaded)
+	}
+}
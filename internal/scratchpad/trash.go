@@ -0,0 +1,195 @@
+package scratchpad
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashDirName holds deleted days, one sub-directory per date, each file
+// named after the moment it was deleted.
+const trashDirName = ".trash"
+
+// resolvedDirName holds, inside the trash and laid out like it, the
+// conflict copies ResolveConflict merged away. They are not days, so
+// Trash and RestoreTrashed never offer them; EmptyTrash clears them.
+const resolvedDirName = "conflicts"
+
+// TrashedDay is one deleted copy of a day.
+type TrashedDay struct {
+	Date    string
+	ID      string
+	Deleted time.Time
+	path    string
+	format  Format
+}
+
+func (m *Manager) trashDir() string {
+	return filepath.Join(m.storageDir, trashDirName)
+}
+
+// Delete moves date into the trash, attachments included. A day stored
+// more than once, in another layout or format, has every copy trashed;
+// the one sp reads goes last, so it is the one RestoreTrashed brings
+// back. The day's history is kept, so a restored day can still be rolled
+// back. Deleting a day that was never saved reports fs.ErrNotExist.
+func (m *Manager) Delete(date string) error {
+	path, at, err := m.locate(date)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(m.trashDir(), date)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create trash directory: %w", err)
+	}
+	now := time.Now()
+	for _, other := range m.candidates() {
+		copyPath := m.dayPath(date, other)
+		if copyPath == path {
+			continue
+		}
+		if _, err := os.Stat(copyPath); err != nil {
+			continue
+		}
+		if err := os.Rename(copyPath, stampedPath(dir, now, other.format)); err != nil {
+			return fmt.Errorf("move %s to trash: %w", date, err)
+		}
+		m.pruneEmptyParents(copyPath)
+	}
+	trashed := stampedPath(dir, now, at.format)
+	if err := os.Rename(path, trashed); err != nil {
+		return fmt.Errorf("move %s to trash: %w", date, err)
+	}
+	m.pruneEmptyParents(path)
+	if err := m.trashAttachments(date, trashed); err != nil {
+		return err
+	}
+	return m.changed(date, "Delete "+date)
+}
+
+// Trash lists the deleted days, most recently deleted first.
+func (m *Manager) Trash() ([]TrashedDay, error) {
+	trashed, err := trashedIn(m.trashDir())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(trashed, func(i, j int) bool { return trashed[i].ID > trashed[j].ID })
+	return trashed, nil
+}
+
+// trashedIn lists the stamped files in root, one sub-directory per date.
+func trashedIn(root string) ([]TrashedDay, error) {
+	dates, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+	var trashed []TrashedDay
+	for _, date := range dates {
+		if !date.IsDir() || !isDate(date.Name()) {
+			continue
+		}
+		dir := filepath.Join(root, date.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trash: %w", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if entry.IsDir() || !isFormatExt(ext) {
+				continue
+			}
+			id := strings.TrimSuffix(name, ext)
+			deleted, perr := time.Parse(revisionIDLayout, id)
+			if perr != nil {
+				continue
+			}
+			trashed = append(trashed, TrashedDay{
+				Date:    date.Name(),
+				ID:      id,
+				Deleted: deleted.Local(),
+				path:    filepath.Join(dir, name),
+				format:  Format(ext[1:]),
+			})
+		}
+	}
+	return trashed, nil
+}
+
+// RestoreTrashed puts the most recently deleted copy of date back. It
+// refuses when the day exists again, rather than overwriting it.
+func (m *Manager) RestoreTrashed(date string) error {
+	trashed, err := m.Trash()
+	if err != nil {
+		return err
+	}
+	for _, t := range trashed {
+		if t.Date != date {
+			continue
+		}
+		if _, _, lerr := m.locate(date); lerr == nil {
+			return fmt.Errorf("%s exists; delete it before restoring the trashed copy", date)
+		}
+		at := dayFile{layout: m.current().layout, format: t.format}
+		path := m.dayPath(date, at)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("create day directory: %w", err)
+		}
+		if err := os.Rename(t.path, path); err != nil {
+			return fmt.Errorf("restore %s from trash: %w", date, err)
+		}
+		m.pruneEmptyParents(t.path)
+		if err := m.restoreAttachments(date, t.path); err != nil {
+			return err
+		}
+		return m.changed(date, fmt.Sprintf("Restore %s from the trash", date))
+	}
+	return fmt.Errorf("%s is not in the trash: %w", date, fs.ErrNotExist)
+}
+
+// EmptyTrash permanently removes days deleted more than olderThan ago; zero
+// removes everything. It returns the number of days removed; conflict
+// copies resolved as long ago go too, uncounted.
+func (m *Manager) EmptyTrash(olderThan time.Duration) (int, error) {
+	trashed, err := m.Trash()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, t := range trashed {
+		if olderThan > 0 && t.Deleted.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(t.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("remove %s from trash: %w", t.Date, err)
+		}
+		if err := os.RemoveAll(strings.TrimSuffix(t.path, filepath.Ext(t.path))); err != nil {
+			return removed, fmt.Errorf("remove %s attachments from trash: %w", t.Date, err)
+		}
+		m.pruneEmptyParents(t.path)
+		removed++
+	}
+	resolved, err := trashedIn(filepath.Join(m.trashDir(), resolvedDirName))
+	if err != nil {
+		return removed, err
+	}
+	for _, t := range resolved {
+		if olderThan > 0 && t.Deleted.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(t.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("remove %s conflict copy from trash: %w", t.Date, err)
+		}
+		m.pruneEmptyParents(t.path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,134 @@
+package scratchpad
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteMovesDayToTrash(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-04-01", "oops, template by accident")
+
+	if err := mgr.Delete("2024-04-01"); err != nil {
+		t.Fatal(err)
+	}
+	if dates, _ := mgr.ListDates(); len(dates) != 0 {
+		t.Errorf("deleted day still listed: %v", dates)
+	}
+	trashed, err := mgr.Trash()
+	if err != nil || len(trashed) != 1 || trashed[0].Date != "2024-04-01" {
+		t.Fatalf("Trash = %+v, %v", trashed, err)
+	}
+	if err := mgr.Delete("2024-04-01"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("deleting twice = %v, want fs.ErrNotExist", err)
+	}
+
+	if err := mgr.RestoreTrashed("2024-04-01"); err != nil {
+		t.Fatal(err)
+	}
+	sp, err := mgr.GetByDate("2024-04-01")
+	if err != nil || sp.Content != "oops, template by accident" {
+		t.Errorf("restored day = %+v, %v", sp, err)
+	}
+	if trashed, _ := mgr.Trash(); len(trashed) != 0 {
+		t.Errorf("trash after restore = %+v", trashed)
+	}
+	if _, err := os.Stat(mgr.trashDir()); !os.IsNotExist(err) {
+		t.Error("empty trash directory should be pruned")
+	}
+}
+
+func TestDeleteTrashesEveryCopy(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-04-07", "flat copy")
+	stale, err := encode(&Scratchpad{SchemaVersion: SchemaVersion, Date: "2024-04-07", Content: "nested copy"}, FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(mgr.storageDir, "2024", "04", "07.md")
+	if err := os.MkdirAll(filepath.Dir(nested), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nested, stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.Delete("2024-04-07"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Lookup("2024-04-07"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("day still readable after delete: %v", err)
+	}
+	if trashed, _ := mgr.Trash(); len(trashed) != 2 {
+		t.Errorf("Trash = %+v, want both copies", trashed)
+	}
+	if err := mgr.RestoreTrashed("2024-04-07"); err != nil {
+		t.Fatal(err)
+	}
+	if sp, err := mgr.GetByDate("2024-04-07"); err != nil || sp.Content != "flat copy" {
+		t.Errorf("restored = %+v, %v, want the copy sp read", sp, err)
+	}
+}
+
+func TestRestoreTrashedRefusesToOverwrite(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-04-02", "old")
+	if err := mgr.Delete("2024-04-02"); err != nil {
+		t.Fatal(err)
+	}
+	saveContent(t, mgr, "2024-04-02", "new")
+	if err := mgr.RestoreTrashed("2024-04-02"); err == nil {
+		t.Fatal("restore overwrote an existing day")
+	}
+	if err := mgr.RestoreTrashed("2024-04-03"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("restoring an untrashed day = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestRestoreTrashedUsesCurrentLayout(t *testing.T) {
+	mgr := setupTestManager(t)
+	saveContent(t, mgr, "2024-04-04", "flat")
+	if err := mgr.Delete("2024-04-04"); err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetLayout(LayoutNested)
+	if err := mgr.RestoreTrashed("2024-04-04"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.storageDir, "2024", "04", "04.json")); err != nil {
+		t.Errorf("restored day not in nested layout: %v", err)
+	}
+}
+
+func TestEmptyTrashOlderThan(t *testing.T) {
+	mgr := setupTestManager(t)
+	for _, date := range []string{"2024-04-05", "2024-04-06"} {
+		saveContent(t, mgr, date, date)
+		if err := mgr.Delete(date); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Backdate one deletion by renaming it to an older ID.
+	trashed, _ := mgr.Trash()
+	old := trashed[1]
+	backdated := filepath.Join(filepath.Dir(old.path), time.Now().AddDate(0, 0, -40).UTC().Format(revisionIDLayout)+".json")
+	if err := os.Rename(old.path, backdated); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := mgr.EmptyTrash(30 * 24 * time.Hour)
+	if err != nil || n != 1 {
+		t.Fatalf("EmptyTrash(30d) = %d, %v", n, err)
+	}
+	trashed, _ = mgr.Trash()
+	if len(trashed) != 1 || trashed[0].Date == old.Date {
+		t.Errorf("trash after expiring = %+v", trashed)
+	}
+	if n, err := mgr.EmptyTrash(0); err != nil || n != 1 {
+		t.Errorf("EmptyTrash(0) = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,184 @@
+package scratchpad
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettle coalesces the burst of events a single save produces (temp
+// file, rename, history) and sync tools that write several days at once.
+const watchSettle = 150 * time.Millisecond
+
+// Watch reports the dates whose day files are created, changed, renamed
+// or removed, in either layout, until ctx is done. Events are coalesced:
+// each batch lists every date touched since the previous one, sorted. The
+// channel is closed when watching stops.
+func (m *Manager) Watch(ctx context.Context) (<-chan []string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("fsnotify: %w", err)
+	}
+	if err := m.watchTree(watcher); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan []string)
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+		pending := make(map[string]bool)
+		var settle <-chan time.Time
+		var out chan<- []string
+		var batch []string
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&fsnotify.Create != 0 {
+					// New year and month directories of the nested layout.
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() && m.isLayoutDir(ev.Name) {
+						_ = m.watchDir(watcher, ev.Name)
+						for _, date := range m.filesBelow(ev.Name) {
+							pending[date] = true
+						}
+					}
+				}
+				if date, ok := m.dateOfPath(ev.Name); ok && ev.Op != fsnotify.Chmod {
+					pending[date] = true
+				}
+				if len(pending) > 0 {
+					settle = time.After(watchSettle)
+				}
+			case <-settle:
+				settle = nil
+				for date := range pending {
+					batch = append(batch, date)
+				}
+				clear(pending)
+				slices.Sort(batch)
+				batch = slices.Compact(batch)
+				out = changes
+			case out <- batch:
+				out, batch = nil, nil
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// watchTree watches the storage directory and its year and month
+// directories.
+func (m *Manager) watchTree(watcher *fsnotify.Watcher) error {
+	if err := watcher.Add(m.storageDir); err != nil {
+		return fmt.Errorf("watch %s: %w", m.storageDir, err)
+	}
+	years, err := os.ReadDir(m.storageDir)
+	if err != nil {
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+	for _, year := range years {
+		if year.IsDir() && isDigits(year.Name(), 4) {
+			if err := m.watchDir(watcher, filepath.Join(m.storageDir, year.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watchDir watches dir and, for a year directory, its month directories.
+func (m *Manager) watchDir(watcher *fsnotify.Watcher, dir string) error {
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	if filepath.Dir(dir) != m.storageDir {
+		return nil
+	}
+	months, err := os.ReadDir(dir)
+	if err != nil {
+		return nil // removed again already
+	}
+	for _, month := range months {
+		if month.IsDir() && isDigits(month.Name(), 2) {
+			if err := watcher.Add(filepath.Join(dir, month.Name())); err != nil {
+				return fmt.Errorf("watch %s: %w", month.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// isLayoutDir reports whether dir is a YYYY or YYYY/MM directory of the
+// nested layout.
+func (m *Manager) isLayoutDir(dir string) bool {
+	rel, err := filepath.Rel(m.storageDir, dir)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	switch len(parts) {
+	case 1:
+		return isDigits(parts[0], 4)
+	case 2:
+		return isDigits(parts[0], 4) && isDigits(parts[1], 2)
+	}
+	return false
+}
+
+// filesBelow lists the dates of day files already inside a directory that
+// appeared in one piece, e.g. a month synced in from another machine.
+func (m *Manager) filesBelow(dir string) []string {
+	var dates []string
+	_ = filepath.WalkDir(dir, func(path string, _ os.DirEntry, err error) error {
+		if err == nil {
+			if date, ok := m.dateOfPath(path); ok {
+				dates = append(dates, date)
+			}
+		}
+		return nil
+	})
+	return dates
+}
+
+// dateOfPath maps a day file path in either layout to its date. A sync
+// tool's conflict copy maps to the day it is a copy of.
+func (m *Manager) dateOfPath(path string) (string, bool) {
+	rel, err := filepath.Rel(m.storageDir, path)
+	if err != nil {
+		return "", false
+	}
+	ext := filepath.Ext(rel)
+	if !isFormatExt(ext) {
+		return "", false
+	}
+	parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, ext)), "/")
+	if original, _, ok := parseConflictName(parts[len(parts)-1]); ok {
+		parts[len(parts)-1] = original
+	}
+	var date string
+	switch len(parts) {
+	case 1:
+		date = parts[0]
+	case 3:
+		date = parts[0] + "-" + parts[1] + "-" + parts[2]
+	default:
+		return "", false
+	}
+	return date, isDate(date)
+}
@@ -0,0 +1,89 @@
+package scratchpad
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// nextBatch waits for the watcher to report want, tolerating batches that
+// only hold part of it.
+func nextBatch(t *testing.T, changes <-chan []string, want ...string) {
+	t.Helper()
+	seen := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case batch, ok := <-changes:
+			if !ok {
+				t.Fatal("watch channel closed")
+			}
+			for _, date := range batch {
+				seen[date] = true
+			}
+			if !slices.ContainsFunc(want, func(date string) bool { return !seen[date] }) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v, saw %v", want, seen)
+		}
+	}
+}
+
+func TestWatchReportsChangedDays(t *testing.T) {
+	mgr := setupTestManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := mgr.Watch(ctx)
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+
+	saveContent(t, mgr, "2024-03-01", "flat")
+	nextBatch(t, changes, "2024-03-01")
+
+	// A nested month synced in from elsewhere, directories and all.
+	month := filepath.Join(mgr.storageDir, "2025", "01")
+	if err := os.MkdirAll(month, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(month, "02.json"), []byte(`{"date":"2025-01-02"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nextBatch(t, changes, "2025-01-02")
+
+	if err := os.Remove(filepath.Join(month, "02.json")); err != nil {
+		t.Fatal(err)
+	}
+	nextBatch(t, changes, "2025-01-02")
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestDateOfPath(t *testing.T) {
+	mgr := setupTestManager(t)
+	for path, want := range map[string]string{
+		"2024-03-01.json":    "2024-03-01",
+		"2024/03/01.md":      "2024-03-01",
+		"2024-03-01.txt":     "",
+		".index":             "",
+		".history/x.json":    "",
+		"2024/03/notes.json": "",
+
+		"2024-03-01.sync-conflict-20240301-101112-ABCDEFG.json": "2024-03-01",
+		"2024/03/01 (Laptop's conflicted copy 2024-03-01).md":   "2024-03-01",
+	} {
+		got, ok := mgr.dateOfPath(filepath.Join(mgr.storageDir, filepath.FromSlash(path)))
+		if !ok {
+			got = ""
+		}
+		if got != want {
+			t.Errorf("dateOfPath(%s) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+}
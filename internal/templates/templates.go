@@ -9,10 +9,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/pders01/sp/internal/clock"
+	"github.com/pders01/sp/internal/paths"
 )
 
 // Definition describes one chooser entry. Exactly one of Body, File, or
@@ -45,9 +47,13 @@ func Builtins() []Definition {
 	}}
 }
 
+// Limits on command templates, shared with sp log, which runs commands
+// for the day too.
 const (
-	commandTimeout   = 10 * time.Second
-	maxCommandOutput = 1 << 20 // 1 MiB
+	// CommandTimeout is how long a command may run before it is killed.
+	CommandTimeout = 10 * time.Second
+	// MaxCommandOutput is how much output sp keeps from a command.
+	MaxCommandOutput = 1 << 20 // 1 MiB
 )
 
 var (
@@ -107,7 +113,7 @@ func RenderContext(ctx context.Context, def Definition, date string) (Section, e
 	body := def.Body
 	switch {
 	case def.File != "":
-		path, err := expandHome(def.File)
+		path, err := paths.Expand(def.File)
 		if err != nil {
 			return Section{}, err
 		}
@@ -117,7 +123,7 @@ func RenderContext(ctx context.Context, def Definition, date string) (Section, e
 		}
 		body = string(data)
 	case len(def.Command) > 0:
-		output, err := runCommand(ctx, def, date, commandTimeout, maxCommandOutput)
+		output, err := runCommand(ctx, def, date, CommandTimeout, MaxCommandOutput)
 		if err != nil {
 			return Section{}, err
 		}
@@ -130,13 +136,20 @@ func RenderContext(ctx context.Context, def Definition, date string) (Section, e
 	return Section{ID: def.ID, Title: def.Name, Body: body}, nil
 }
 
-type limitedBuffer struct {
+// LimitedBuffer keeps the first bytes written to it, up to a limit, and
+// discards the rest without failing the writer.
+type LimitedBuffer struct {
 	buffer   bytes.Buffer
 	limit    int
 	overflow bool
 }
 
-func (b *limitedBuffer) Write(p []byte) (int, error) {
+// NewLimitedBuffer returns a buffer keeping at most limit bytes.
+func NewLimitedBuffer(limit int) *LimitedBuffer {
+	return &LimitedBuffer{limit: limit}
+}
+
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
 	remaining := b.limit - b.buffer.Len()
 	if remaining < len(p) {
 		b.overflow = true
@@ -150,11 +163,14 @@ func (b *limitedBuffer) Write(p []byte) (int, error) {
 	return b.buffer.Write(p)
 }
 
-func (b *limitedBuffer) String() string { return b.buffer.String() }
+func (b *LimitedBuffer) String() string { return b.buffer.String() }
+
+// Overflowed reports whether output beyond the limit was discarded.
+func (b *LimitedBuffer) Overflowed() bool { return b.overflow }
 
 func runCommand(parent context.Context, def Definition, date string, timeout time.Duration, maxOutput int) (string, error) {
 	args := append([]string(nil), def.Command...)
-	path, err := expandHome(args[0])
+	path, err := paths.Expand(args[0])
 	if err != nil {
 		return "", err
 	}
@@ -164,13 +180,13 @@ func runCommand(parent context.Context, def Definition, date string, timeout tim
 	defer cancel()
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...) // #nosec G204 -- explicit, opt-in user configuration.
 	cmd.Env = commandEnvironment(date)
-	stdout := &limitedBuffer{limit: maxOutput}
-	stderr := &limitedBuffer{limit: maxOutput}
+	stdout := NewLimitedBuffer(maxOutput)
+	stderr := NewLimitedBuffer(maxOutput)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 	runErr := cmd.Run()
 	switch {
-	case stdout.overflow || stderr.overflow:
+	case stdout.Overflowed() || stderr.Overflowed():
 		return "", fmt.Errorf("run template %q: %w", def.Name, errCommandOutputTooBig)
 	case errors.Is(ctx.Err(), context.DeadlineExceeded):
 		return "", fmt.Errorf("run template %q: timed out after %s", def.Name, timeout)
@@ -186,8 +202,16 @@ func runCommand(parent context.Context, def Definition, date string, timeout tim
 	return stdout.String(), nil
 }
 
+// commandEnvironment is all a command template sees of sp's environment.
+// TZ is the configured [day] timezone when there is one, so the command
+// tells the time the way SP_DATE was chosen.
 func commandEnvironment(date string) []string {
 	env := []string{"SP_DATE=" + date}
+	if zone := clock.Current().Zone(); zone != "" {
+		env = append(env, "TZ="+zone)
+	} else if value, ok := os.LookupEnv("TZ"); ok {
+		env = append(env, "TZ="+value)
+	}
 	for _, name := range []string{
 		"PATH", "LANG", "LC_ALL", "LC_CTYPE", "TMPDIR", "TEMP", "TMP",
 		"SYSTEMROOT", "WINDIR",
@@ -198,17 +222,3 @@ func commandEnvironment(date string) []string {
 	}
 	return env
 }
-
-func expandHome(path string) (string, error) {
-	if path != "~" && !strings.HasPrefix(path, "~/") {
-		return path, nil
-	}
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("expand %q: %w", path, err)
-	}
-	if path == "~" {
-		return home, nil
-	}
-	return filepath.Join(home, strings.TrimPrefix(path, "~/")), nil
-}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/pders01/sp/internal/clock"
 )
 
 func TestNormalizeFillsID(t *testing.T) {
@@ -89,6 +91,38 @@ func TestCommandGetsMinimalEnvironment(t *testing.T) {
 	}
 }
 
+func TestCommandGetsConfiguredTimezone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell fixture is Unix-only")
+	}
+	t.Setenv("TZ", "UTC")
+	run := func() string {
+		t.Helper()
+		section, err := Render(Definition{
+			ID:      "tz",
+			Name:    "Timezone",
+			Command: []string{"sh", "-c", `printf 'TZ=%s' "${TZ-unset}"`},
+		}, "2026-07-19")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return section.Body
+	}
+	if got := run(); got != "TZ=UTC" {
+		t.Errorf("without a configured zone = %q, want the inherited TZ", got)
+	}
+
+	defer clock.Set(clock.Current())
+	boundary, err := clock.Parse("", "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(boundary)
+	if got := run(); got != "TZ=Asia/Tokyo" {
+		t.Errorf("with a configured zone = %q", got)
+	}
+}
+
 func TestCommandTimeout(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("shell fixture is Unix-only")
@@ -32,6 +32,18 @@ type App struct {
 	appliedTemplates map[string]map[string]bool
 	applyTemplates   TemplateApplier
 	templateChooser  *templateChooser
+	deleteDay        Deleter
+	confirmDelete    string
+	dayChanges       <-chan []string
+	reloadDay        DayReloader
+	workspaces       []string
+	workspace        string
+	switchWorkspace  WorkspaceSwitcher
+	workspaceChooser *workspaceChooser
+	pageChooser      *pageChooser
+	// pageFromCalendar sends the notebook back to the calendar when the
+	// page opened from there is closed.
+	pageFromCalendar bool
 }
 
 // NewApp builds the router around an already-configured calendar and
@@ -46,11 +58,12 @@ func NewApp(cal *Calendar, nb *Notebook, mode AppMode) *App {
 	}
 }
 
-// Init kicks off both sub-views' watchers up front. They only emit
-// events when something signals them, so running both is cheap and
-// avoids a re-init delay when popping back to the calendar.
+// Init kicks off both sub-views' watchers up front, plus live reload
+// when wired. They only emit events when something signals them, so
+// running both is cheap and avoids a re-init delay when popping back to
+// the calendar.
 func (a *App) Init() tea.Cmd {
-	return tea.Batch(a.cal.Init(), a.nb.Init())
+	return tea.Batch(a.cal.Init(), a.nb.Init(), a.waitForDayChanges())
 }
 
 // Close releases resources held by the sub-views. Safe to call after
@@ -69,6 +82,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a.finishTemplateApply(applied)
 	}
+	if changed, ok := msg.(daysChangedMsg); ok {
+		if changed.source != nil && changed.source != a.dayChanges {
+			return a, nil
+		}
+		return a, a.reloadDays(changed)
+	}
 	if a.templateChooser != nil {
 		if ws, ok := msg.(tea.WindowSizeMsg); ok {
 			a.cal.Update(ws)
@@ -77,8 +96,20 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a.updateTemplateChooser(msg)
 	}
-	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "a" && a.startTemplateChooser() {
-		return a, nil
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case a.confirmDelete != "":
+			return a.updateDeleteConfirm(key)
+		case a.workspaceChooser != nil:
+			return a.updateWorkspaceChooser(key)
+		case a.pageChooser != nil:
+			return a.updatePageChooser(key)
+		case key.String() == "a" && a.startTemplateChooser(),
+			key.String() == "d" && a.startDeleteConfirm(),
+			key.String() == "w" && a.startWorkspaceChooser(),
+			key.String() == "p" && a.startPageChooser():
+			return a, nil
+		}
 	}
 
 	if a.mode == ModeNotebook || a.mode == ModeCalendar {
@@ -134,6 +165,10 @@ func (a *App) updateNotebook(msg tea.Msg) (tea.Model, tea.Cmd) {
 	_, cmd := a.nb.Update(msg)
 
 	switch {
+	case a.pageFromCalendar && a.nb.page == nil:
+		a.pageFromCalendar = false
+		a.mode = ModeCalendar
+		return a, cmd
 	case a.nb.IsPopping():
 		// Esc / Backspace: pop to calendar when one is behind us, else
 		// quit. Sync any inline-edit changes into the calendar's data
@@ -144,6 +179,12 @@ func (a *App) updateNotebook(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if content != "" {
 			a.cal.MarkDate(date, content)
 		}
+		// Conflict copies merged in the notebook are gone; unflag them.
+		for copied := range a.cal.syncCopies {
+			if !a.nb.syncCopies[copied] {
+				a.cal.MarkSyncCopy(copied, false)
+			}
+		}
 		if date != "" {
 			a.cal.SetCursor(date)
 		}
@@ -170,6 +211,15 @@ func (a *App) View() string {
 	if a.templateChooser != nil {
 		return a.renderTemplateChooser()
 	}
+	if a.confirmDelete != "" {
+		return a.renderDeleteConfirm()
+	}
+	if a.workspaceChooser != nil {
+		return a.renderWorkspaceChooser()
+	}
+	if a.pageChooser != nil {
+		return a.renderPageChooser()
+	}
 	switch a.mode {
 	case ModeNotebook:
 		return a.nb.View()
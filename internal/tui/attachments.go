@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Attachment is a file kept with a day, listed beside the page.
+type Attachment struct {
+	Name string
+	Size int64
+}
+
+// AttachmentLoader lists the files attached to a day.
+type AttachmentLoader func(date string) ([]Attachment, error)
+
+// attachmentPanelWidth is the width of the attachments panel, border
+// included. Narrower terminals leave the page the whole width.
+const attachmentPanelWidth = 30
+
+// SetAttachments lists each page's attachments in a panel beside it,
+// loaded the first time the page is shown.
+func (n *Notebook) SetAttachments(load AttachmentLoader) {
+	n.loadAttachments = load
+	n.attachments = make(map[string][]Attachment)
+	n.updateViewportContent()
+}
+
+// attachmentsOf returns date's attachments, loading and caching them on
+// first use. A day that fails to load shows none.
+func (n *Notebook) attachmentsOf(date string) []Attachment {
+	if n.loadAttachments == nil {
+		return nil
+	}
+	if attachments, ok := n.attachments[date]; ok {
+		return attachments
+	}
+	attachments, err := n.loadAttachments(date)
+	if err != nil {
+		return nil
+	}
+	n.attachments[date] = attachments
+	return attachments
+}
+
+// panelWidth is the width taken by the attachments panel on the current
+// page, 0 when it is hidden.
+func (n *Notebook) panelWidth() int {
+	if len(n.pages) == 0 || n.revisions != nil || n.merging != nil || n.page != nil || n.width < 2*attachmentPanelWidth {
+		return 0
+	}
+	if len(n.attachmentsOf(n.pages[n.current])) == 0 {
+		return 0
+	}
+	return attachmentPanelWidth
+}
+
+func (n *Notebook) renderAttachments() string {
+	palette := n.theme.Palette()
+	inner := attachmentPanelWidth - 3 // border and padding
+	lines := []string{palette.Header.Render("Attachments"), ""}
+	for _, a := range n.attachmentsOf(n.pages[n.current]) {
+		size := formatSize(a.Size)
+		name := truncate(a.Name, inner-len(size)-1)
+		gap := strings.Repeat(" ", max(inner-len([]rune(name))-len(size), 1))
+		lines = append(lines, lipgloss.NewStyle().Foreground(palette.Text).Render(name)+gap+palette.MutedText.Render(size))
+	}
+	return lipgloss.NewStyle().
+		Width(attachmentPanelWidth-1).
+		Height(n.viewport.Height).
+		PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(palette.Muted).
+		Render(strings.Join(lines, "\n"))
+}
+
+// formatSize renders a byte count the way ls -h does.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes)/unit, "KMGTPE"
+	i := 0
+	for value >= unit && i < len(suffix)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %cB", value, suffix[i])
+}
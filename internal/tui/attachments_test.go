@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNotebookListsAttachmentsBesidePage(t *testing.T) {
+	nb := NewNotebook([]string{"2024-01-15", "2024-01-16"})
+	defer nb.Close()
+	nb.SetContents(map[string]string{"2024-01-15": "plain day", "2024-01-16": "# With files"})
+	loads := 0
+	nb.SetAttachments(func(date string) ([]Attachment, error) {
+		loads++
+		if date == "2024-01-16" {
+			return []Attachment{{Name: "screenshot.png", Size: 2048}, {Name: "build.log", Size: 12}}, nil
+		}
+		return nil, nil
+	})
+	nb.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	view := nb.View()
+	if !strings.Contains(view, "Attachments") || !strings.Contains(view, "screenshot.png") || !strings.Contains(view, "2.0 KB") {
+		t.Errorf("panel missing:\n%s", view)
+	}
+	if nb.viewport.Width != 100-attachmentPanelWidth {
+		t.Errorf("page width = %d, want it narrowed by the panel", nb.viewport.Width)
+	}
+	before := loads
+	nb.View()
+	if loads != before {
+		t.Error("attachments should be cached between renders")
+	}
+
+	pressRune(nb, 'l')
+	if strings.Contains(nb.View(), "Attachments") || nb.viewport.Width != 100 {
+		t.Error("a day without attachments should get the whole width")
+	}
+
+	pressRune(nb, 'h')
+	nb.Update(tea.WindowSizeMsg{Width: 50, Height: 30})
+	if strings.Contains(nb.View(), "Attachments") {
+		t.Error("a narrow terminal should hide the panel")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KB", 5 << 20: "5.0 MB"} {
+		if got := formatSize(bytes); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pders01/sp/internal/clock"
 	"github.com/pders01/sp/internal/editor"
+	"github.com/pders01/sp/internal/scratchpad"
 )
 
 // CalendarView selects which granularity the calendar renders.
@@ -33,6 +35,8 @@ type Calendar struct {
 	hasData            map[string]bool
 	previews           map[string]string
 	contents           map[string]string
+	conflicts          map[string]bool
+	syncCopies         map[string]bool
 	cursor             time.Time
 	today              time.Time
 	view               CalendarView
@@ -46,6 +50,11 @@ type Calendar struct {
 	save               Saver
 	loader             func(date string) (string, error)
 	templatesAvailable bool
+	deleteAvailable    bool
+	// workspace names the workspace shown, "" for the top-level store.
+	workspace           string
+	workspacesAvailable bool
+	pagesAvailable      bool
 }
 
 // NewCalendar creates a calendar seeded with the given dates as "has data".
@@ -54,19 +63,20 @@ func NewCalendar(dates []string) *Calendar {
 	for _, d := range dates {
 		hasData[d] = true
 	}
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today := clock.Today()
 	return &Calendar{
-		icons:    DefaultIconSet(),
-		hasData:  hasData,
-		previews: make(map[string]string),
-		contents: make(map[string]string),
-		cursor:   today,
-		today:    today,
-		view:     ViewMonth,
-		width:    80,
-		height:   24,
-		theme:    newThemeWatcher(ThemePrefAuto),
+		icons:      DefaultIconSet(),
+		hasData:    hasData,
+		previews:   make(map[string]string),
+		contents:   make(map[string]string),
+		conflicts:  make(map[string]bool),
+		syncCopies: make(map[string]bool),
+		cursor:     today,
+		today:      today,
+		view:       ViewMonth,
+		width:      80,
+		height:     24,
+		theme:      newThemeWatcher(ThemePrefAuto),
 	}
 }
 
@@ -80,7 +90,8 @@ func (c *Calendar) SetThemePref(pref string) {
 // used by the e shortcut. With all three present, e suspends the TUI
 // via tea.ExecProcess, persists changes, and resumes the calendar.
 // With any unset, e falls back to quit-with-direct-edit so the caller
-// can run the editor itself.
+// can run the editor itself. load also fetches the documents of days
+// known only from SetPreviews.
 func (c *Calendar) SetEditor(ed *editor.Editor, save Saver, load func(date string) (string, error)) {
 	c.editor = ed
 	c.save = save
@@ -94,6 +105,7 @@ func (c *Calendar) SetContents(contents map[string]string) {
 	c.previews = make(map[string]string, len(contents))
 	for date, body := range contents {
 		c.contents[date] = body
+		c.conflicts[date] = scratchpad.HasConflict(body)
 		preview := extractPreview(body)
 		if preview != "" {
 			c.previews[date] = preview
@@ -102,6 +114,52 @@ func (c *Calendar) SetContents(contents map[string]string) {
 	}
 }
 
+// SetPreviews marks dates as having data and sets their cell annotations
+// without their documents, which are loaded on demand when the cursor
+// lands on a day. This keeps startup independent of the number of days.
+func (c *Calendar) SetPreviews(previews map[string]string) {
+	c.previews = make(map[string]string, len(previews))
+	for date, preview := range previews {
+		c.hasData[date] = true
+		if preview != "" {
+			c.previews[date] = preview
+		}
+	}
+}
+
+// SetConflicts flags days that hold both sides of a sync conflict, known
+// from the store's summaries before their documents are loaded.
+func (c *Calendar) SetConflicts(dates []string) {
+	for _, date := range dates {
+		c.conflicts[date] = true
+	}
+}
+
+// MarkSyncCopy records whether a file-sync tool left a conflict copy of
+// date. Unlike a conflict inside the day, it outlives edits to the day
+// until the copy is merged.
+func (c *Calendar) MarkSyncCopy(date string, present bool) {
+	if present {
+		c.syncCopies[date] = true
+	} else {
+		delete(c.syncCopies, date)
+	}
+}
+
+// content returns the document for date, loading and caching it when only
+// its preview is known.
+func (c *Calendar) content(date string) string {
+	if body, ok := c.contents[date]; ok || !c.hasData[date] || c.loader == nil {
+		return body
+	}
+	body, err := c.loader(date)
+	if err != nil {
+		return fmt.Sprintf("Error loading: %v", err)
+	}
+	c.contents[date] = body
+	return body
+}
+
 // extractPreview returns the first non-empty line of body, with leading
 // markdown heading markers stripped.
 func extractPreview(body string) string {
@@ -169,6 +227,13 @@ func (c *Calendar) finishEdit(msg editDoneMsg) (tea.Model, tea.Cmd) {
 		c.theme.SetStatus(fmt.Sprintf("read: %v", rerr), 2*time.Second)
 		return c, c.theme.expireStatusCmd(2 * time.Second)
 	}
+	stored, conflict := editConflict(c.loader, msg)
+	if conflict && newContent == msg.base {
+		// Untouched in the editor: keep what arrived on disk instead of
+		// writing the stale copy back over it.
+		c.MarkDate(msg.date, stored)
+		return c, nil
+	}
 	if c.save != nil {
 		if serr := c.save(msg.date, newContent); serr != nil {
 			c.theme.SetStatus(fmt.Sprintf("save: %v", serr), 2*time.Second)
@@ -177,6 +242,7 @@ func (c *Calendar) finishEdit(msg editDoneMsg) (tea.Model, tea.Cmd) {
 	}
 	// Reflect the new entry in the calendar's data so cells repaint.
 	delete(c.previews, msg.date)
+	c.conflicts[msg.date] = scratchpad.HasConflict(newContent)
 	if newContent != "" {
 		c.hasData[msg.date] = true
 		c.contents[msg.date] = newContent
@@ -186,6 +252,10 @@ func (c *Calendar) finishEdit(msg editDoneMsg) (tea.Model, tea.Cmd) {
 	} else {
 		delete(c.contents, msg.date)
 	}
+	if conflict {
+		c.theme.SetStatus(msgEditConflict(msg.date), conflictStatusTTL)
+		return c, c.theme.expireStatusCmd(conflictStatusTTL)
+	}
 	c.theme.SetStatus("Saved", 1500*time.Millisecond)
 	return c, c.theme.expireStatusCmd(1500 * time.Millisecond)
 }
@@ -309,6 +379,9 @@ func (c *Calendar) View() string {
 			{keys: "enter", label: "open", visible: true},
 			{keys: "e", label: "edit", visible: true},
 			{keys: "a", label: "templates", visible: c.templatesAvailable},
+			{keys: "d", label: "delete", visible: c.deleteAvailable},
+			{keys: "p", label: "pages", visible: c.pagesAvailable},
+			{keys: "w", label: "workspace", visible: c.workspacesAvailable},
 			{keys: "y", label: "year view", visible: true},
 			{keys: "t", label: "today", visible: true},
 			{keys: "Ctrl+t", label: "theme", visible: true},
@@ -316,6 +389,9 @@ func (c *Calendar) View() string {
 		})
 	}
 
+	if c.workspace != "" {
+		headerText += " · " + c.workspace
+	}
 	header := c.theme.Palette().Header.Render(headerText)
 	if status := c.theme.StatusText(); status != "" {
 		header = lipgloss.JoinHorizontal(
@@ -348,6 +424,12 @@ func (c *Calendar) View() string {
 func (c *Calendar) focusLine() string {
 	date := c.cursor.Format("2006-01-02")
 	weekday := c.cursor.Format("Mon")
+	if c.syncCopies[date] {
+		return fmt.Sprintf("Focus: %s (%s) — sync conflict copy: open the day and press m to merge", date, weekday)
+	}
+	if c.conflicts[date] {
+		return fmt.Sprintf("Focus: %s (%s) — sync conflict: edit the day to keep one version", date, weekday)
+	}
 	if preview, ok := c.previews[date]; ok {
 		return fmt.Sprintf("Focus: %s (%s) — %s", date, weekday, preview)
 	}
@@ -392,7 +474,7 @@ func (c *Calendar) renderDocumentPreview(width, height int) string {
 	title := c.theme.Palette().MutedText.Bold(true).Render(
 		fmt.Sprintf("%s · %s", date, c.cursor.Format("Monday")),
 	)
-	content := c.contents[date]
+	content := c.content(date)
 	if strings.TrimSpace(content) == "" {
 		content = c.theme.Palette().MutedText.Render("No entry for this day.")
 	} else {
@@ -508,6 +590,9 @@ func (c *Calendar) renderDayCell(day time.Time, month time.Month, w, h int) stri
 }
 
 func (c *Calendar) cellAnnotation(day time.Time, dateStr string, w int) string {
+	if c.conflicts[dateStr] || c.syncCopies[dateStr] {
+		return c.theme.Palette().ErrorMessage.Render(truncate("! conflict", w))
+	}
 	if preview := c.previews[dateStr]; preview != "" {
 		return c.theme.Palette().MutedText.Render(truncate(preview, w))
 	}
@@ -682,12 +767,21 @@ func (c *Calendar) SetCursor(date string) {
 func (c *Calendar) MarkDate(date, content string) {
 	c.hasData[date] = true
 	c.contents[date] = content
+	c.conflicts[date] = scratchpad.HasConflict(content)
 	delete(c.previews, date)
 	if preview := extractPreview(content); preview != "" {
 		c.previews[date] = preview
 	}
 }
 
+// UnmarkDate forgets a deleted day so its cell and preview go blank.
+func (c *Calendar) UnmarkDate(date string) {
+	delete(c.hasData, date)
+	delete(c.contents, date)
+	delete(c.previews, date)
+	delete(c.conflicts, date)
+}
+
 // startEdit suspends the TUI to run the editor on the picked day. Returns
 // nil when no editor is wired or preparation fails (caller falls back to
 // quit-with-direct-edit so the orchestrator can run the editor).
@@ -706,7 +800,7 @@ func (c *Calendar) startEdit(date string) tea.Cmd {
 		return c.theme.expireStatusCmd(2 * time.Second)
 	}
 	return tea.ExecProcess(cmd, func(execErr error) tea.Msg {
-		return editDoneMsg{date: date, path: path, cleanup: cleanup, err: execErr}
+		return editDoneMsg{date: date, base: content, path: path, cleanup: cleanup, err: execErr}
 	})
 }
 
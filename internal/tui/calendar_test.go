@@ -484,3 +484,45 @@ func TestCalendarYearViewRenders(t *testing.T) {
 		t.Errorf("expected month tile labels, got: %q", out)
 	}
 }
+
+func TestCalendarLoadsContentOnDemand(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	cal := NewCalendar(nil)
+	cal.SetPreviews(map[string]string{today: "Standup", "2020-01-01": ""})
+	var loaded []string
+	cal.SetEditor(nil, nil, func(date string) (string, error) {
+		loaded = append(loaded, date)
+		return "# Standup\n\nship the index", nil
+	})
+
+	if !cal.HasData("2020-01-01") || cal.previews[today] != "Standup" {
+		t.Fatalf("previews not applied: hasData=%v previews=%v", cal.hasData, cal.previews)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("content loaded before it was shown: %v", loaded)
+	}
+	cal.Update(tea.WindowSizeMsg{Width: 120, Height: 60})
+	if view := cal.View(); !strings.Contains(view, "ship the index") {
+		t.Errorf("document preview missing loaded content:\n%s", view)
+	}
+	cal.View()
+	if len(loaded) != 1 || loaded[0] != today {
+		t.Errorf("loaded = %v, want only %s once", loaded, today)
+	}
+}
+
+func TestCalendarFlagsSyncConflicts(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	cal := NewCalendar(nil)
+	cal.SetPreviews(map[string]string{today: "this machine"})
+	cal.SetConflicts([]string{today})
+	cal.Update(tea.WindowSizeMsg{Width: 120, Height: 60})
+	if view := cal.View(); !strings.Contains(view, "! conflict") || !strings.Contains(view, "sync conflict") {
+		t.Errorf("conflict not flagged:\n%s", view)
+	}
+
+	cal.MarkDate(today, "# Resolved")
+	if view := cal.View(); strings.Contains(view, "conflict") {
+		t.Errorf("resolved day still flagged:\n%s", view)
+	}
+}
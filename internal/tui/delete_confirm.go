@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Deleter removes a day from the store. The filesystem store moves it to
+// the trash, so the confirmation prompt promises it can be restored.
+type Deleter func(date string) error
+
+// SetDeleter wires the d key in both views. Without it the key does
+// nothing.
+func (a *App) SetDeleter(del Deleter) {
+	a.deleteDay = del
+	a.cal.deleteAvailable = del != nil
+	a.nb.deleteAvailable = del != nil
+}
+
+// startDeleteConfirm opens the prompt for the focused day. Days without
+// content in the calendar, year view and the revision browser have
+// nothing to delete.
+func (a *App) startDeleteConfirm() bool {
+	if a.deleteDay == nil {
+		return false
+	}
+	var date string
+	switch a.mode {
+	case ModeCalendar:
+		date = a.cal.CursorDate()
+		if !a.cal.HasData(date) {
+			return false
+		}
+	case ModeNotebook:
+		if a.nb.revisions != nil || a.nb.merging != nil || a.nb.page != nil {
+			return false
+		}
+		date, _ = a.nb.CurrentContent()
+	}
+	if date == "" {
+		return false
+	}
+	a.confirmDelete = date
+	return true
+}
+
+// updateDeleteConfirm resolves the prompt: y deletes, anything else
+// cancels.
+func (a *App) updateDeleteConfirm(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	date := a.confirmDelete
+	a.confirmDelete = ""
+	switch key.String() {
+	case "ctrl+c":
+		a.quitting = true
+		return a, tea.Quit
+	case "y", "Y":
+	default:
+		return a, nil
+	}
+
+	// A day drilled into from the calendar but never saved has no file;
+	// removing it from the views is all there is to do.
+	if err := a.deleteDay(date); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return a, a.templateStatus(fmt.Sprintf("delete: %v", err), true)
+	}
+	a.cal.UnmarkDate(date)
+	a.nb.RemovePage(date)
+	delete(a.appliedTemplates, date)
+	return a, a.templateStatus("Moved "+date+" to trash", false)
+}
+
+func (a *App) renderDeleteConfirm() string {
+	palette := a.cal.theme.Palette()
+	width, height := a.cal.width, a.cal.height
+	content := a.cal.content(a.confirmDelete)
+	if a.mode == ModeNotebook {
+		palette = a.nb.theme.Palette()
+		width, height = a.nb.width, a.nb.height
+		content = a.nb.content(a.confirmDelete)
+	}
+
+	lines := []string{
+		palette.Header.Render("Delete " + a.confirmDelete + "?"),
+		palette.MutedText.Render("The page moves to the trash; `sp trash restore " + a.confirmDelete + "` brings it back."),
+		"",
+	}
+	preview := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if limit := max(height-8, 1); len(preview) > limit {
+		preview = append(preview[:limit], "…")
+	}
+	for _, line := range preview {
+		lines = append(lines, palette.MutedText.Render(truncate(line, max(width-4, 1))))
+	}
+	lines = append(lines, "", palette.Help.Render(renderHelp([]helpEntry{
+		{keys: "y", label: "delete", visible: true},
+		{keys: "n/esc", label: "cancel", visible: true},
+	})))
+	return lipgloss.NewStyle().Width(width).Height(height).Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
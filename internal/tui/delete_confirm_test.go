@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newDeleteTestApp(mode AppMode) (*App, *[]string) {
+	dates := []string{"2024-01-14", "2024-01-15"}
+	cal := NewCalendar(dates)
+	cal.SetContents(map[string]string{"2024-01-14": "older", "2024-01-15": "# Accidental template"})
+	cal.SetCursor("2024-01-15")
+	nb := NewNotebook(dates)
+	nb.SetContents(map[string]string{"2024-01-14": "older", "2024-01-15": "# Accidental template"})
+	app := NewApp(cal, nb, mode)
+	var deleted []string
+	app.SetDeleter(func(date string) error {
+		deleted = append(deleted, date)
+		return nil
+	})
+	app.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	return app, &deleted
+}
+
+func TestAppDeleteRequiresConfirmation(t *testing.T) {
+	app, deleted := newDeleteTestApp(ModeCalendar)
+	defer app.Close()
+
+	pressRune(app, 'd')
+	if !strings.Contains(app.View(), "Delete 2024-01-15?") {
+		t.Fatalf("confirmation prompt not shown:\n%s", app.View())
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(*deleted) != 0 || app.IsQuitting() {
+		t.Fatalf("esc should cancel without deleting or quitting: %v", *deleted)
+	}
+
+	pressRune(app, 'd')
+	pressRune(app, 'y')
+	if len(*deleted) != 1 || (*deleted)[0] != "2024-01-15" {
+		t.Fatalf("deleted = %v", *deleted)
+	}
+	if app.cal.HasData("2024-01-15") {
+		t.Error("calendar still marks the deleted day")
+	}
+	if app.nb.GetCurrentPage() != "2024-01-14" {
+		t.Errorf("notebook page = %q, want the remaining day", app.nb.GetCurrentPage())
+	}
+}
+
+func TestAppDeleteFromNotebook(t *testing.T) {
+	app, deleted := newDeleteTestApp(ModeNotebook)
+	defer app.Close()
+
+	pressRune(app, 'd')
+	pressRune(app, 'Y')
+	if len(*deleted) != 1 || (*deleted)[0] != "2024-01-15" {
+		t.Fatalf("deleted = %v", *deleted)
+	}
+	if len(app.nb.pages) != 1 {
+		t.Errorf("pages = %v", app.nb.pages)
+	}
+}
+
+func TestAppDeleteSkipsEmptyCalendarDays(t *testing.T) {
+	app, deleted := newDeleteTestApp(ModeCalendar)
+	defer app.Close()
+	app.cal.SetCursor("2024-01-20")
+
+	pressRune(app, 'd')
+	if app.confirmDelete != "" {
+		t.Error("prompt opened for a day without content")
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("deleted = %v", *deleted)
+	}
+}
+
+func TestAppDeleteErrorKeepsDay(t *testing.T) {
+	app, _ := newDeleteTestApp(ModeCalendar)
+	defer app.Close()
+	app.SetDeleter(func(string) error { return errors.New("disk on fire") })
+
+	pressRune(app, 'd')
+	pressRune(app, 'y')
+	if !app.cal.HasData("2024-01-15") {
+		t.Error("day removed from view although delete failed")
+	}
+	if !strings.Contains(app.cal.theme.StatusText(), "disk on fire") {
+		t.Errorf("status = %q", app.cal.theme.StatusText())
+	}
+
+	// A page that was never saved has nothing on disk to delete.
+	app.SetDeleter(func(date string) error { return fmt.Errorf("%s: %w", date, fs.ErrNotExist) })
+	pressRune(app, 'd')
+	pressRune(app, 'y')
+	if app.cal.HasData("2024-01-15") {
+		t.Error("unsaved day should still leave the views")
+	}
+}
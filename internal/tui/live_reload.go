@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// conflictStatusTTL keeps the edit-conflict warning up long enough to
+// read; it is the one status that reports something the user may need to
+// act on.
+const conflictStatusTTL = 5 * time.Second
+
+// StoredDay is what live reload needs of a day as it is now on disk.
+type StoredDay struct {
+	Content          string
+	AppliedTemplates []string
+	// SyncConflict reports a conflict copy of the day left by a file-sync
+	// tool.
+	SyncConflict bool
+}
+
+// DayReloader reads a day again after it changed on disk. It returns nil
+// and no error when the day no longer exists.
+type DayReloader func(date string) (*StoredDay, error)
+
+// daysChangedMsg carries one batch of dates whose files changed on disk.
+type daysChangedMsg struct {
+	dates []string
+	// source is the channel the batch came from. A batch from a
+	// workspace switched away from is dropped.
+	source <-chan []string
+}
+
+// SetLiveReload keeps both views in step with the store while the TUI
+// runs: every batch of dates received on changes is read again with
+// reload. Without it the views show the store as it was at startup.
+func (a *App) SetLiveReload(changes <-chan []string, reload DayReloader) {
+	a.dayChanges = changes
+	a.reloadDay = reload
+}
+
+// waitForDayChanges blocks on the next batch of changed dates. It is
+// re-armed after each batch, like the theme watcher.
+func (a *App) waitForDayChanges() tea.Cmd {
+	if a.dayChanges == nil || a.reloadDay == nil {
+		return nil
+	}
+	changes := a.dayChanges
+	return func() tea.Msg {
+		dates, ok := <-changes
+		if !ok {
+			return nil
+		}
+		return daysChangedMsg{dates: dates, source: changes}
+	}
+}
+
+// reloadDays refreshes the changed days in both views. A day every view
+// already holds as stored, such as the echo of our own save, is left
+// alone; the status line only mentions days that changed under the
+// focused view.
+func (a *App) reloadDays(msg daysChangedMsg) tea.Cmd {
+	focused := a.cal.CursorDate()
+	if a.mode == ModeNotebook {
+		focused = a.nb.GetCurrentPage()
+	}
+	focusChanged := false
+	for _, date := range msg.dates {
+		day, err := a.reloadDay(date)
+		if err != nil {
+			// Most likely caught mid-write; the write's own events bring
+			// another batch.
+			continue
+		}
+		nbContent, nbOK := a.nb.contents[date]
+		calContent, calOK := a.cal.contents[date]
+		calOK = calOK && a.cal.HasData(date)
+		if day != nil && a.nb.loadConflict != nil && a.nb.syncCopies[date] != day.SyncConflict {
+			a.nb.MarkSyncConflict(date, day.SyncConflict)
+			a.cal.MarkSyncCopy(date, day.SyncConflict)
+			focusChanged = focusChanged || date == focused
+		}
+		if day == nil {
+			if !a.cal.HasData(date) && !nbOK {
+				continue
+			}
+			a.cal.UnmarkDate(date)
+			a.nb.RemovePage(date)
+			delete(a.appliedTemplates, date)
+			focusChanged = focusChanged || date == focused
+			continue
+		}
+
+		nbStale := nbOK && nbContent != day.Content
+		calStale := calOK && calContent != day.Content
+		if (nbOK || calOK) && !nbStale && !calStale {
+			continue
+		}
+		a.cal.MarkDate(date, day.Content)
+		a.nb.AddPage(date)
+		a.nb.SetPageContent(date, day.Content)
+		if a.appliedTemplates != nil {
+			applied := make(map[string]bool, len(day.AppliedTemplates))
+			for _, id := range day.AppliedTemplates {
+				applied[id] = true
+			}
+			a.appliedTemplates[date] = applied
+		}
+		seen := calOK && !calStale
+		if a.mode == ModeNotebook {
+			seen = nbOK && !nbStale
+		}
+		focusChanged = focusChanged || date == focused && !seen
+	}
+	cmd := a.waitForDayChanges()
+	if focusChanged {
+		cmd = tea.Batch(cmd, a.templateStatus(fmt.Sprintf("%s changed on disk", focused), false))
+	}
+	return cmd
+}
+
+// editConflict reports whether the stored day changed while the editor
+// had it open, returning the stored content.
+func editConflict(load func(date string) (string, error), msg editDoneMsg) (string, bool) {
+	if load == nil {
+		return "", false
+	}
+	stored, err := load(msg.date)
+	if err != nil || stored == msg.base {
+		return "", false
+	}
+	return stored, true
+}
+
+func msgEditConflict(date string) string {
+	return fmt.Sprintf("%s changed on disk while you were editing; saved your version, the other is in its history", date)
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newLiveReloadTestApp(mode AppMode, disk map[string]*StoredDay) *App {
+	app, _ := newDeleteTestApp(mode)
+	app.SetTemplates(nil, nil, nil)
+	app.SetLiveReload(make(chan []string), func(date string) (*StoredDay, error) {
+		return disk[date], nil
+	})
+	return app
+}
+
+func TestAppLiveReloadRefreshesBothViews(t *testing.T) {
+	disk := map[string]*StoredDay{
+		"2024-01-15": {Content: "# Synced from laptop", AppliedTemplates: []string{"standup"}},
+		"2024-01-16": {Content: "new day"},
+	}
+	app := newLiveReloadTestApp(ModeCalendar, disk)
+	defer app.Close()
+
+	app.Update(daysChangedMsg{dates: []string{"2024-01-14", "2024-01-15", "2024-01-16"}})
+
+	if app.cal.HasData("2024-01-14") || slices.Contains(app.nb.pages, "2024-01-14") {
+		t.Error("a day removed on disk should leave both views")
+	}
+	if got := app.cal.previews["2024-01-15"]; got != "Synced from laptop" {
+		t.Errorf("calendar preview = %q", got)
+	}
+	if got := app.nb.contents["2024-01-15"]; got != "# Synced from laptop" {
+		t.Errorf("notebook content = %q", got)
+	}
+	if !app.cal.HasData("2024-01-16") || !slices.Contains(app.nb.pages, "2024-01-16") {
+		t.Errorf("a new day should show up in both views: pages %v", app.nb.pages)
+	}
+	if !app.templateApplied("2024-01-15", "standup") {
+		t.Error("applied templates should follow the file")
+	}
+	if got := app.cal.theme.StatusText(); !strings.Contains(got, "2024-01-15 changed on disk") {
+		t.Errorf("status = %q, want a note about the focused day", got)
+	}
+}
+
+func TestAppLiveReloadIgnoresOwnSaves(t *testing.T) {
+	disk := map[string]*StoredDay{"2024-01-15": {Content: "# Accidental template"}}
+	app := newLiveReloadTestApp(ModeNotebook, disk)
+	defer app.Close()
+	app.nb.SetCurrentDate("2024-01-15")
+
+	app.Update(daysChangedMsg{dates: []string{"2024-01-15"}})
+	if got := app.nb.theme.StatusText(); got != "" {
+		t.Errorf("status = %q, an unchanged day is not news", got)
+	}
+}
+
+func TestAppWaitsForDayChanges(t *testing.T) {
+	changes := make(chan []string, 1)
+	app, _ := newDeleteTestApp(ModeCalendar)
+	defer app.Close()
+	app.SetLiveReload(changes, func(string) (*StoredDay, error) { return nil, nil })
+
+	changes <- []string{"2024-01-14"}
+	msg := app.waitForDayChanges()()
+	if got, ok := msg.(daysChangedMsg); !ok || len(got.dates) != 1 {
+		t.Fatalf("msg = %#v", msg)
+	}
+	close(changes)
+	if msg := app.waitForDayChanges()(); msg != nil {
+		t.Errorf("closed channel should end live reload, got %#v", msg)
+	}
+}
+
+func editedFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "edit.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNotebookFinishEditWarnsWhenDayChangedMeanwhile(t *testing.T) {
+	nb := NewNotebook([]string{"2024-01-01"})
+	defer nb.Close()
+	nb.SetContents(map[string]string{"2024-01-01": "# Old\n"})
+	nb.SetLoader(func(string) (string, error) { return "# Theirs\n", nil })
+	saved := map[string]string{}
+	nb.save = func(date, content string) error {
+		saved[date] = content
+		return nil
+	}
+
+	nb.Update(editDoneMsg{date: "2024-01-01", base: "# Old\n", path: editedFile(t, "# Mine\n")})
+	if saved["2024-01-01"] != "# Mine\n" {
+		t.Errorf("saved = %q, want the edited version", saved["2024-01-01"])
+	}
+	if got := nb.theme.StatusText(); !strings.Contains(got, "changed on disk while you were editing") {
+		t.Errorf("status = %q, want a conflict warning", got)
+	}
+
+	// Closing the editor without changes keeps what arrived on disk.
+	delete(saved, "2024-01-01")
+	nb.Update(editDoneMsg{date: "2024-01-01", base: "# Old\n", path: editedFile(t, "# Old\n")})
+	if _, ok := saved["2024-01-01"]; ok {
+		t.Error("an unchanged buffer must not overwrite the newer day")
+	}
+	if got := nb.contents["2024-01-01"]; got != "# Theirs\n" {
+		t.Errorf("contents = %q, want the version on disk", got)
+	}
+}
+
+func TestCalendarFinishEditKeepsNewerDayWhenUnchanged(t *testing.T) {
+	cal := NewCalendar(nil)
+	defer cal.Close()
+	cal.loader = func(string) (string, error) { return "# Theirs\n", nil }
+	cal.save = func(_, _ string) error {
+		t.Error("an unchanged buffer must not be saved over the newer day")
+		return nil
+	}
+
+	cal.Update(editDoneMsg{date: "2024-05-04", base: "# Old\n", path: editedFile(t, "# Old\n")})
+	if got := cal.contents["2024-05-04"]; got != "# Theirs\n" {
+		t.Errorf("content = %q, want the version on disk", got)
+	}
+}
@@ -0,0 +1,232 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pders01/sp/internal/diff"
+)
+
+// localLabel names this machine's side of a merge, matching the conflict
+// markers sp writes when git sync merges a day.
+const localLabel = "this machine"
+
+// SyncConflict is a day a file-sync tool stored twice: the day as sp
+// reads it and the tool's conflict copy.
+type SyncConflict struct {
+	Date string
+	// Source names the copy, e.g. "Syncthing copy".
+	Source string
+	Local  string
+	Remote string
+	// Base is the newest revision older than both versions. Without one
+	// the merge view shows the versions side by side.
+	Base    string
+	HasBase bool
+}
+
+// ConflictLoader returns the next conflict copy of date, or nil and no
+// error when none is left.
+type ConflictLoader func(date string) (*SyncConflict, error)
+
+// ConflictResolver saves content as date, taking the templates applied on
+// both sides, and clears away the copy ConflictLoader returned.
+type ConflictResolver func(date, content string) error
+
+// mergeView is the notebook's view of one conflict copy being merged.
+type mergeView struct {
+	conflict *SyncConflict
+	chunks   []diff.Chunk
+}
+
+func (m *mergeView) merged() string {
+	return diff.Text(m.chunks, localLabel, m.conflict.Source)
+}
+
+// SetSyncConflicts flags the dates that have conflict copies in both views
+// and wires the notebook's m key that merges them. Without it the key does
+// nothing.
+func (a *App) SetSyncConflicts(dates []string, load ConflictLoader, resolve ConflictResolver) {
+	for _, date := range dates {
+		a.cal.MarkSyncCopy(date, true)
+	}
+	a.nb.SetSyncConflicts(dates, load, resolve)
+}
+
+// SetSyncConflicts flags the dates that have conflict copies and wires the
+// m key that merges them. Without it the key does nothing.
+func (n *Notebook) SetSyncConflicts(dates []string, load ConflictLoader, resolve ConflictResolver) {
+	n.syncCopies = make(map[string]bool, len(dates))
+	for _, date := range dates {
+		n.syncCopies[date] = true
+	}
+	n.loadConflict = load
+	n.resolveConflict = resolve
+}
+
+// MarkSyncConflict records whether date has a conflict copy, e.g. after
+// one appeared on disk.
+func (n *Notebook) MarkSyncConflict(date string, present bool) {
+	if n.syncCopies == nil {
+		n.syncCopies = make(map[string]bool)
+	}
+	if present {
+		n.syncCopies[date] = true
+	} else {
+		delete(n.syncCopies, date)
+	}
+}
+
+// startMerge loads the current page's conflict copy and switches the
+// viewport to the merge.
+func (n *Notebook) startMerge() tea.Cmd {
+	if n.loadConflict == nil || n.resolveConflict == nil || len(n.pages) == 0 {
+		return nil
+	}
+	date := n.pages[n.current]
+	if !n.syncCopies[date] {
+		n.theme.SetStatus("No conflict copy of "+date, 1500*time.Millisecond)
+		return n.theme.expireStatusCmd(1500 * time.Millisecond)
+	}
+	conflict, err := n.loadConflict(date)
+	if err != nil {
+		n.flashError(fmt.Sprintf("merge: %v", err))
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	if conflict == nil {
+		n.MarkSyncConflict(date, false)
+		n.theme.SetStatus("No conflict copy of "+date, 1500*time.Millisecond)
+		return n.theme.expireStatusCmd(1500 * time.Millisecond)
+	}
+	base := ""
+	if conflict.HasBase {
+		base = conflict.Base
+	}
+	n.merging = &mergeView{
+		conflict: conflict,
+		chunks:   diff.Merge3(base, conflict.Local, conflict.Remote),
+	}
+	n.updateViewportContent()
+	n.viewport.GotoTop()
+	return nil
+}
+
+func (n *Notebook) stopMerge() {
+	n.merging = nil
+	n.updateViewportContent()
+	n.viewport.GotoTop()
+}
+
+// handleMergeKey drives the merge view: Enter saves the merge, 1 and 2
+// keep one side, e saves the merge and opens it in the editor, Esc/m
+// return to the page.
+func (n *Notebook) handleMergeKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m := n.merging
+	switch msg.String() {
+	case "ctrl+c", "q":
+		n.quitting = true
+	case "esc", "backspace", "m":
+		n.stopMerge()
+	case "enter":
+		return n, n.resolveMerge(m.merged(), "Merged "+m.conflict.Source)
+	case "1":
+		return n, n.resolveMerge(m.conflict.Local, "Kept "+localLabel)
+	case "2":
+		return n, n.resolveMerge(m.conflict.Remote, "Kept "+m.conflict.Source)
+	case "e":
+		date := m.conflict.Date
+		if cmd := n.resolveMerge(m.merged(), ""); n.merging != nil {
+			return n, cmd
+		}
+		return n, n.startEdit(date)
+	case "up", "k":
+		n.viewport.LineUp(1)
+	case "down", "j":
+		n.viewport.LineDown(1)
+	case "ctrl+u":
+		n.viewport.SetYOffset(n.viewport.YOffset - n.viewport.Height/2)
+	case "ctrl+d":
+		n.viewport.SetYOffset(n.viewport.YOffset + n.viewport.Height/2)
+	}
+	return n, nil
+}
+
+// resolveMerge saves content as the merged day and closes the view. It
+// stays open when saving fails. An empty status skips the confirmation.
+func (n *Notebook) resolveMerge(content, status string) tea.Cmd {
+	date := n.merging.conflict.Date
+	if err := n.resolveConflict(date, content); err != nil {
+		n.flashError(fmt.Sprintf("merge: %v", err))
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	n.contents[date] = content
+	if next, err := n.loadConflict(date); err == nil && next == nil {
+		n.MarkSyncConflict(date, false)
+	}
+	n.stopMerge()
+	if status == "" {
+		return nil
+	}
+	if n.syncCopies[date] {
+		status += "; another conflict copy is left"
+	}
+	n.theme.SetStatus(status, 1500*time.Millisecond)
+	return n.theme.expireStatusCmd(1500 * time.Millisecond)
+}
+
+// renderMerge draws the merge as plain text: the merged day with both
+// sides of each conflicting region marked, or the two versions side by
+// side when there is no base to merge against.
+func (n *Notebook) renderMerge() string {
+	m := n.merging
+	if !m.conflict.HasBase {
+		return n.renderSideBySide()
+	}
+	palette := n.theme.Palette()
+	var lines []string
+	for _, chunk := range m.chunks {
+		if !chunk.Conflict {
+			lines = append(lines, chunk.Lines...)
+			continue
+		}
+		lines = append(lines, palette.ErrorMessage.Render("<<<<<<< "+localLabel))
+		for _, line := range chunk.Ours {
+			lines = append(lines, palette.Header.Render(line))
+		}
+		lines = append(lines, palette.ErrorMessage.Render("======="))
+		for _, line := range chunk.Theirs {
+			lines = append(lines, palette.SelectedDate.Render(line))
+		}
+		lines = append(lines, palette.ErrorMessage.Render(">>>>>>> "+m.conflict.Source))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSideBySide lines up both versions, this machine's on the left.
+// A line only one side has is highlighted and leaves a gap on the other.
+func (n *Notebook) renderSideBySide() string {
+	m := n.merging
+	palette := n.theme.Palette()
+	col := max((n.width-3)/2, 10)
+	cell := func(text string, style lipgloss.Style) string {
+		text = truncate(text, col)
+		return style.Render(text) + strings.Repeat(" ", max(col-len([]rune(text)), 0))
+	}
+	plain := lipgloss.NewStyle()
+	sep := palette.Separator.Render(" │ ")
+	lines := []string{cell(localLabel, palette.MutedText) + sep + cell(m.conflict.Source, palette.MutedText)}
+	for _, l := range diff.Lines(m.conflict.Local, m.conflict.Remote) {
+		switch l.Op {
+		case diff.Equal:
+			lines = append(lines, cell(l.Text, plain)+sep+cell(l.Text, plain))
+		case diff.Delete:
+			lines = append(lines, cell(l.Text, palette.Header)+sep+cell("", plain))
+		case diff.Insert:
+			lines = append(lines, cell("", plain)+sep+cell(l.Text, palette.SelectedDate))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newMergeNotebook opens a notebook on a day with one conflict copy and
+// records what the resolver saves.
+func newMergeNotebook(t *testing.T, conflict SyncConflict, saved map[string]string) *Notebook {
+	t.Helper()
+	nb := NewNotebook([]string{conflict.Date})
+	t.Cleanup(nb.Close)
+	nb.SetContents(map[string]string{conflict.Date: conflict.Local})
+	nb.SetSyncConflicts([]string{conflict.Date},
+		func(date string) (*SyncConflict, error) {
+			if _, done := saved[date]; done {
+				return nil, nil
+			}
+			c := conflict
+			return &c, nil
+		},
+		func(date, content string) error {
+			saved[date] = content
+			return nil
+		})
+	nb.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	return nb
+}
+
+func TestNotebookMergeSavesThreeWayMerge(t *testing.T) {
+	saved := map[string]string{}
+	nb := newMergeNotebook(t, SyncConflict{
+		Date:    "2025-03-04",
+		Source:  "Syncthing copy",
+		Local:   "a\nB\nc\nd\n",
+		Remote:  "a\nb\nc\nD\n",
+		Base:    "a\nb\nc\nd\n",
+		HasBase: true,
+	}, saved)
+
+	if !strings.Contains(nb.View(), "press m to merge") {
+		t.Error("header should point at the merge key")
+	}
+	pressRune(nb, 'm')
+	if nb.merging == nil {
+		t.Fatal("m should open the merge view")
+	}
+	if view := nb.View(); !strings.Contains(view, "merging Syncthing copy") {
+		t.Errorf("merge header missing:\n%s", view)
+	}
+	nb.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if saved["2025-03-04"] != "a\nB\nc\nD\n" {
+		t.Errorf("saved = %q, want both edits", saved["2025-03-04"])
+	}
+	if nb.merging != nil {
+		t.Error("saving should close the merge view")
+	}
+	if _, content := nb.CurrentContent(); content != "a\nB\nc\nD\n" {
+		t.Errorf("page content = %q", content)
+	}
+	if strings.Contains(nb.View(), "press m to merge") {
+		t.Error("merged day still flagged")
+	}
+}
+
+func TestNotebookMergeWithoutBaseShowsSideBySide(t *testing.T) {
+	saved := map[string]string{}
+	nb := newMergeNotebook(t, SyncConflict{
+		Date:   "2025-03-04",
+		Source: "Dropbox copy",
+		Local:  "shared\nmine\n",
+		Remote: "shared\ntheirs\n",
+	}, saved)
+
+	pressRune(nb, 'm')
+	rendered := nb.renderMerge()
+	lines := strings.Split(rendered, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("side-by-side lines = %d, want header + 3:\n%s", len(lines), rendered)
+	}
+	if !strings.Contains(lines[0], "this machine") || !strings.Contains(lines[0], "Dropbox copy") {
+		t.Errorf("column headers = %q", lines[0])
+	}
+	if strings.Count(lines[1], "shared") != 2 {
+		t.Errorf("unchanged line should appear on both sides: %q", lines[1])
+	}
+
+	pressRune(nb, '2')
+	if saved["2025-03-04"] != "shared\ntheirs\n" {
+		t.Errorf("saved = %q, want the copy's version", saved["2025-03-04"])
+	}
+}
+
+func TestNotebookMergeEscKeepsCopy(t *testing.T) {
+	saved := map[string]string{}
+	nb := newMergeNotebook(t, SyncConflict{
+		Date: "2025-03-04", Source: "Syncthing copy", Local: "x\n", Remote: "y\n",
+	}, saved)
+
+	pressRune(nb, 'm')
+	nb.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if nb.merging != nil || nb.IsPopping() {
+		t.Error("esc should only close the merge view")
+	}
+	if len(saved) != 0 {
+		t.Errorf("cancelled merge saved %v", saved)
+	}
+	if !nb.syncCopies["2025-03-04"] {
+		t.Error("copy should still be flagged")
+	}
+}
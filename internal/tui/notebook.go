@@ -10,7 +10,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pders01/sp/internal/diff"
 	"github.com/pders01/sp/internal/editor"
+	"github.com/pders01/sp/internal/scratchpad"
 )
 
 // Saver persists an edited scratchpad. Returning a non-nil error
@@ -20,7 +22,10 @@ type Saver func(date string, content string) error
 // editDoneMsg is dispatched after tea.ExecProcess has handed control
 // back from the external editor.
 type editDoneMsg struct {
-	date    string
+	date string
+	// base is the content the editor started from, to tell whether the
+	// stored copy changed while the TUI was suspended.
+	base    string
 	path    string
 	cleanup func()
 	err     error
@@ -30,6 +35,7 @@ type editDoneMsg struct {
 type Notebook struct {
 	pages              []string
 	contents           map[string]string
+	loader             func(date string) (string, error)
 	current            int
 	viewport           viewport.Model
 	width              int
@@ -41,7 +47,24 @@ type Notebook struct {
 	theme              *themeWatcher
 	editor             *editor.Editor
 	save               Saver
+	history            HistoryLoader
+	revisions          *revisionBrowser
+	syncCopies         map[string]bool
+	loadConflict       ConflictLoader
+	resolveConflict    ConflictResolver
+	merging            *mergeView
+	attachments        map[string][]Attachment
+	loadAttachments    AttachmentLoader
 	templatesAvailable bool
+	deleteAvailable    bool
+	// workspace names the workspace shown, "" for the top-level store.
+	workspace           string
+	workspacesAvailable bool
+	// page is the named page shown in place of the day, if any.
+	page      *openPage
+	pageNames []string
+	loadPage  PageLoader
+	savePage  PageSaver
 }
 
 // NewNotebook creates a new notebook instance. Pages are copied and
@@ -113,6 +136,8 @@ func (n *Notebook) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return n, nil
 	case editDoneMsg:
 		return n.finishEdit(msg)
+	case pageEditDoneMsg:
+		return n.finishPageEdit(msg)
 	case tea.KeyMsg:
 		return n.handleKey(msg)
 	}
@@ -127,13 +152,14 @@ func (n *Notebook) startEdit(date string) tea.Cmd {
 	if n.editor == nil || n.save == nil {
 		return nil
 	}
-	cmd, path, cleanup, err := n.editor.Prepare(n.contents[date])
+	base := n.content(date)
+	cmd, path, cleanup, err := n.editor.Prepare(base)
 	if err != nil {
 		n.flashError(fmt.Sprintf("editor prepare: %v", err))
 		return n.theme.expireStatusCmd(2 * time.Second)
 	}
 	return tea.ExecProcess(cmd, func(execErr error) tea.Msg {
-		return editDoneMsg{date: date, path: path, cleanup: cleanup, err: execErr}
+		return editDoneMsg{date: date, base: base, path: path, cleanup: cleanup, err: execErr}
 	})
 }
 
@@ -152,7 +178,11 @@ func (n *Notebook) finishEdit(msg editDoneMsg) (tea.Model, tea.Cmd) {
 		n.flashError(fmt.Sprintf("read: %v", rerr))
 		return n, n.theme.expireStatusCmd(2 * time.Second)
 	}
-	if newContent == n.contents[msg.date] {
+	stored, conflict := editConflict(n.loader, msg)
+	if newContent == msg.base {
+		if conflict {
+			n.SetPageContent(msg.date, stored)
+		}
 		return n, nil
 	}
 	n.contents[msg.date] = newContent
@@ -160,8 +190,12 @@ func (n *Notebook) finishEdit(msg editDoneMsg) (tea.Model, tea.Cmd) {
 		n.flashError(fmt.Sprintf("save: %v", serr))
 		return n, n.theme.expireStatusCmd(2 * time.Second)
 	}
-	n.theme.SetStatus("Saved", 1500*time.Millisecond)
 	n.updateViewportContent()
+	if conflict {
+		n.theme.SetStatus(msgEditConflict(msg.date), conflictStatusTTL)
+		return n, n.theme.expireStatusCmd(conflictStatusTTL)
+	}
+	n.theme.SetStatus("Saved", 1500*time.Millisecond)
 	return n, n.theme.expireStatusCmd(1500 * time.Millisecond)
 }
 
@@ -170,6 +204,17 @@ func (n *Notebook) flashError(text string) {
 }
 
 func (n *Notebook) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if n.revisions != nil {
+		return n.handleRevisionKey(msg)
+	}
+	if n.merging != nil {
+		return n.handleMergeKey(msg)
+	}
+	if n.page != nil {
+		if cmd, ok := n.handlePageKey(msg); ok {
+			return n, cmd
+		}
+	}
 	switch msg.String() {
 	case "ctrl+c", "q":
 		n.quitting = true
@@ -192,6 +237,10 @@ func (n *Notebook) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+t":
 		n.theme.Cycle()
 		return n, nil
+	case "r":
+		return n, n.startRevisions()
+	case "m":
+		return n, n.startMerge()
 	case "left", "h":
 		if n.current > 0 {
 			n.current--
@@ -229,13 +278,49 @@ func (n *Notebook) View() string {
 	if n.quitting {
 		return ""
 	}
-	if len(n.pages) == 0 {
+	if len(n.pages) == 0 && n.page == nil {
 		return n.theme.Palette().MutedText.Render("No scratchpad pages found.")
 	}
 
-	header := n.theme.Palette().Header.Render(
-		withIcon(n.icons.Notebook, fmt.Sprintf("Notebook · %s", n.pages[n.current])),
-	)
+	shown := n.GetCurrentPage()
+	if n.page != nil {
+		shown = "page " + n.page.name
+	}
+	title := fmt.Sprintf("Notebook · %s", shown)
+	if n.workspace != "" {
+		title = fmt.Sprintf("Notebook · %s · %s", n.workspace, shown)
+	}
+	if r := n.revisions; r != nil {
+		title += fmt.Sprintf(" · revision %d/%d · %s",
+			len(r.revisions)-r.index, len(r.revisions), r.current().Saved.Format("2006-01-02 15:04"))
+	}
+	if m := n.merging; m != nil {
+		title += " · merging " + m.conflict.Source
+		if conflicts := diff.Conflicts(m.chunks); m.conflict.HasBase && conflicts > 0 {
+			title += fmt.Sprintf(" · %d conflicting", conflicts)
+		}
+	}
+	header := n.theme.Palette().Header.Render(withIcon(n.icons.Notebook, title))
+	body := n.viewport.View()
+	if n.panelWidth() > 0 {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, n.renderAttachments())
+	}
+	var warning string
+	switch date := n.GetCurrentPage(); {
+	case n.revisions != nil || n.merging != nil || n.page != nil:
+	case n.syncCopies[date] && n.loadConflict != nil:
+		warning = "sync conflict copy: press m to merge"
+	case scratchpad.HasConflict(n.content(date)):
+		warning = "sync conflict: edit the day to keep one version"
+	}
+	if warning != "" {
+		header = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			header,
+			"   ",
+			n.theme.Palette().ErrorMessage.Render(warning),
+		)
+	}
 	if status := n.theme.StatusText(); status != "" {
 		header = lipgloss.JoinHorizontal(
 			lipgloss.Top,
@@ -246,11 +331,14 @@ func (n *Notebook) View() string {
 	}
 
 	footer := n.renderFooter()
+	if n.page != nil {
+		footer = n.renderPageFooter()
+	}
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		header,
-		n.viewport.View(),
+		body,
 		footer,
 	)
 }
@@ -316,7 +404,7 @@ func (n *Notebook) renderFooter() string {
 	rule := n.theme.Palette().Separator.Render(strings.Repeat("─", max(n.width, 0)))
 
 	// Controls on separate line
-	help := n.theme.Palette().Help.Render(renderHelp([]helpEntry{
+	entries := []helpEntry{
 		{keys: "←/h", label: "prev", visible: true},
 		{keys: "→/l", label: "next", visible: true},
 		{keys: "↑/k", label: "up", visible: true},
@@ -324,10 +412,35 @@ func (n *Notebook) renderFooter() string {
 		{keys: "Ctrl+u/d", label: "page up/down", visible: true},
 		{keys: "enter/e", label: "edit", visible: true},
 		{keys: "a", label: "templates", visible: n.templatesAvailable},
+		{keys: "r", label: "history", visible: n.history != nil},
+		{keys: "m", label: "merge copy", visible: n.syncCopies[n.pages[n.current]] && n.loadConflict != nil},
+		{keys: "d", label: "delete", visible: n.deleteAvailable},
+		{keys: "p", label: "pages", visible: n.loadPage != nil},
+		{keys: "w", label: "workspace", visible: n.workspacesAvailable},
 		{keys: "esc", label: "back", visible: true},
 		{keys: "Ctrl+t", label: "theme", visible: true},
 		{keys: "q", label: "quit", visible: true},
-	}))
+	}
+	if n.revisions != nil {
+		entries = []helpEntry{
+			{keys: "←/h", label: "older", visible: true},
+			{keys: "→/l", label: "newer", visible: true},
+			{keys: "↑/k ↓/j", label: "scroll", visible: true},
+			{keys: "enter", label: "restore", visible: true},
+			{keys: "esc/r", label: "back to page", visible: true},
+		}
+	}
+	if n.merging != nil {
+		entries = []helpEntry{
+			{keys: "↑/k ↓/j", label: "scroll", visible: true},
+			{keys: "enter", label: "save merge", visible: true},
+			{keys: "1", label: "keep " + localLabel, visible: true},
+			{keys: "2", label: "keep " + n.merging.conflict.Source, visible: true},
+			{keys: "e", label: "save and edit", visible: n.editor != nil},
+			{keys: "esc/m", label: "back to page", visible: true},
+		}
+	}
+	help := n.theme.Palette().Help.Render(renderHelp(entries))
 
 	// Center the navigation line
 	navStyle := lipgloss.NewStyle().Width(n.width).Align(lipgloss.Center)
@@ -344,14 +457,29 @@ func (n *Notebook) renderFooter() string {
 
 // updateViewportContent renders the current page's markdown content into the viewport
 func (n *Notebook) updateViewportContent() {
-	if len(n.pages) == 0 {
+	if len(n.pages) == 0 && n.page == nil {
 		n.viewport.SetContent("")
 		return
 	}
-	content := n.contents[n.pages[n.current]]
+	// The attachments panel, when the page has one, takes its width
+	// from the page.
+	n.viewport.Width = n.width - n.panelWidth()
+	if n.merging != nil {
+		n.viewport.SetContent(n.renderMerge())
+		return
+	}
+	var content string
+	switch {
+	case n.page != nil:
+		content = n.page.content
+	case n.revisions != nil:
+		content = n.revisions.current().Content
+	default:
+		content = n.content(n.pages[n.current])
+	}
 	renderer, err := glamour.NewTermRenderer(
 		glamour.WithStandardStyle(n.theme.Style()),
-		glamour.WithWordWrap(n.width-4),
+		glamour.WithWordWrap(n.viewport.Width-4),
 	)
 	if err != nil {
 		n.viewport.SetContent(content)
@@ -371,9 +499,32 @@ func (n *Notebook) SetContents(contents map[string]string) {
 	n.updateViewportContent()
 }
 
-// SetPageContent refreshes one page after templates are applied.
+// SetLoader fetches page contents on demand instead of up front: a page
+// missing from SetContents is loaded the first time it is shown.
+func (n *Notebook) SetLoader(load func(date string) (string, error)) {
+	n.loader = load
+	n.updateViewportContent()
+}
+
+// content returns the document for date, loading and caching it on first
+// use.
+func (n *Notebook) content(date string) string {
+	if body, ok := n.contents[date]; ok || n.loader == nil {
+		return body
+	}
+	body, err := n.loader(date)
+	if err != nil {
+		return fmt.Sprintf("Error loading: %v", err)
+	}
+	n.contents[date] = body
+	return body
+}
+
+// SetPageContent refreshes one page after templates are applied. Its
+// attachments are listed afresh too.
 func (n *Notebook) SetPageContent(date, content string) {
 	n.contents[date] = content
+	delete(n.attachments, date)
 	if len(n.pages) > 0 && n.pages[n.current] == date {
 		n.updateViewportContent()
 		n.viewport.GotoTop()
@@ -439,12 +590,12 @@ func (n *Notebook) CurrentContent() (date, content string) {
 		return "", ""
 	}
 	d := n.pages[n.current]
-	return d, n.contents[d]
+	return d, n.content(d)
 }
 
 // AddPage inserts the given date into the page list (sorted descending)
-// when not already present and seeds an empty content entry. No-op when
-// the date is already known.
+// when not already present and, without a loader, seeds an empty content
+// entry. No-op when the date is already known.
 func (n *Notebook) AddPage(date string) {
 	for _, p := range n.pages {
 		if p == date {
@@ -453,7 +604,26 @@ func (n *Notebook) AddPage(date string) {
 	}
 	n.pages = append(n.pages, date)
 	sort.Sort(sort.Reverse(sort.StringSlice(n.pages)))
-	if _, ok := n.contents[date]; !ok {
+	if _, ok := n.contents[date]; !ok && n.loader == nil {
 		n.contents[date] = ""
 	}
 }
+
+// RemovePage drops a deleted date from the page list, keeping the cursor
+// on the neighbouring page.
+func (n *Notebook) RemovePage(date string) {
+	for i, p := range n.pages {
+		if p != date {
+			continue
+		}
+		n.pages = append(n.pages[:i], n.pages[i+1:]...)
+		delete(n.contents, date)
+		delete(n.attachments, date)
+		if n.current >= len(n.pages) && n.current > 0 {
+			n.current--
+		}
+		n.updateViewportContent()
+		n.viewport.GotoTop()
+		return
+	}
+}
@@ -430,3 +430,34 @@ func TestNotebook_FooterScrolling(t *testing.T) {
 		assert.Contains(t, view, "←/h: prev • →/l: next • ↑/k: up • ↓/j: down • Ctrl+u/d: page up/down • enter/e: edit • esc: back • Ctrl+t: theme • q: quit")
 	}
 }
+
+func TestNotebook_LoadsPagesOnDemand(t *testing.T) {
+	notebook := NewNotebook([]string{"2024-01-15", "2024-01-16", "2024-01-17"})
+	var loaded []string
+	notebook.SetLoader(func(date string) (string, error) {
+		loaded = append(loaded, date)
+		return "page " + date, nil
+	})
+	assert.Equal(t, []string{"2024-01-17"}, loaded, "only the current page is loaded")
+
+	notebook.Update(tea.KeyMsg{Type: tea.KeyRight})
+	date, content := notebook.CurrentContent()
+	assert.Equal(t, "page "+date, content)
+	assert.Len(t, loaded, 2)
+
+	notebook.AddPage("2024-01-18")
+	notebook.SetCurrentDate("2024-01-18")
+	_, content = notebook.CurrentContent()
+	assert.Equal(t, "page 2024-01-18", content, "added pages load their saved content")
+}
+
+func TestNotebook_FlagsSyncConflicts(t *testing.T) {
+	notebook := NewNotebook([]string{"2024-01-15"})
+	notebook.SetContents(map[string]string{
+		"2024-01-15": "<<<<<<< this machine\nmine\n=======\ntheirs\n>>>>>>> remote\n",
+	})
+	assert.Contains(t, notebook.View(), "sync conflict")
+
+	notebook.SetPageContent("2024-01-15", "mine")
+	assert.NotContains(t, notebook.View(), "sync conflict")
+}
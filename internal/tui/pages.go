@@ -0,0 +1,256 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pders01/sp/internal/editor"
+	"github.com/pders01/sp/internal/scratchpad"
+)
+
+// PageLoader reads a named page. A page that was never saved is empty.
+type PageLoader func(name string) (string, error)
+
+// PageSaver persists an edited named page.
+type PageSaver func(name, content string) error
+
+// openPage is a named page the notebook shows in place of the day.
+type openPage struct {
+	name    string
+	content string
+}
+
+// pageEditDoneMsg is editDoneMsg for a named page.
+type pageEditDoneMsg struct {
+	name    string
+	base    string
+	path    string
+	cleanup func()
+	err     error
+}
+
+// pageChooser is the p key's list of pages: those the focused day links
+// to first, then every other saved page.
+type pageChooser struct {
+	options []string
+	linked  int
+	cursor  int
+}
+
+// SetPages lists the saved pages and wires the p key, which opens them in
+// the notebook. Without a loader the key does nothing.
+func (a *App) SetPages(names []string, load PageLoader, save PageSaver) {
+	a.nb.pageNames = append([]string(nil), names...)
+	a.nb.loadPage = load
+	a.nb.savePage = save
+	a.cal.pagesAvailable = load != nil
+}
+
+// startPageChooser opens the chooser, offering the pages linked from the
+// focused day (or open page) before the rest.
+func (a *App) startPageChooser() bool {
+	if a.nb.loadPage == nil || a.nb.revisions != nil || a.nb.merging != nil {
+		return false
+	}
+	var content string
+	switch {
+	case a.mode == ModeCalendar:
+		content = a.cal.content(a.cal.CursorDate())
+	case a.nb.page != nil:
+		content = a.nb.page.content
+	default:
+		_, content = a.nb.CurrentContent()
+	}
+	chooser := &pageChooser{}
+	seen := make(map[string]bool)
+	for _, name := range scratchpad.PageLinks(content) {
+		seen[scratchpad.PageSlug(name)] = true
+		chooser.options = append(chooser.options, name)
+	}
+	chooser.linked = len(chooser.options)
+	for _, name := range a.nb.pageNames {
+		if slug := scratchpad.PageSlug(name); !seen[slug] {
+			seen[slug] = true
+			chooser.options = append(chooser.options, name)
+		}
+	}
+	a.pageChooser = chooser
+	return true
+}
+
+func (a *App) updatePageChooser(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	chooser := a.pageChooser
+	switch key.String() {
+	case "ctrl+c", "q":
+		a.quitting = true
+		return a, tea.Quit
+	case "esc", "p":
+		a.pageChooser = nil
+	case "up", "k":
+		if chooser.cursor > 0 {
+			chooser.cursor--
+		}
+	case "down", "j":
+		if chooser.cursor < len(chooser.options)-1 {
+			chooser.cursor++
+		}
+	case "enter":
+		a.pageChooser = nil
+		if len(chooser.options) > 0 {
+			return a, a.openPage(chooser.options[chooser.cursor])
+		}
+	}
+	return a, nil
+}
+
+// openPage shows name in the notebook. Opened from the calendar, closing
+// the page returns there.
+func (a *App) openPage(name string) tea.Cmd {
+	content, err := a.nb.loadPage(name)
+	if err != nil {
+		return a.templateStatus(fmt.Sprintf("page: %v", err), true)
+	}
+	if a.mode == ModeCalendar {
+		a.pageFromCalendar = true
+		a.mode = ModeNotebook
+	}
+	a.nb.page = &openPage{name: name, content: content}
+	a.nb.updateViewportContent()
+	a.nb.viewport.GotoTop()
+	return nil
+}
+
+func (a *App) renderPageChooser() string {
+	palette := a.cal.theme.Palette()
+	width, height := a.cal.width, a.cal.height
+	if a.mode == ModeNotebook {
+		palette = a.nb.theme.Palette()
+		width, height = a.nb.width, a.nb.height
+	}
+
+	chooser := a.pageChooser
+	lines := []string{palette.Header.Render("Pages"), ""}
+	if len(chooser.options) == 0 {
+		lines = append(lines, palette.MutedText.Render("No pages yet. Link one from a day with [[page name]]."))
+	}
+	for i, name := range chooser.options {
+		label := name
+		style := lipgloss.NewStyle().Foreground(palette.Text)
+		if i < chooser.linked {
+			label += "  linked"
+		}
+		cursor := "  "
+		if i == chooser.cursor {
+			cursor = "▌ "
+			style = style.Foreground(palette.Highlight).Bold(true)
+		}
+		lines = append(lines, cursor+style.Render(label))
+	}
+	lines = append(lines, "", palette.Help.Render(renderHelp([]helpEntry{
+		{keys: "↑/k ↓/j", label: "move", visible: true},
+		{keys: "enter", label: "open", visible: len(chooser.options) > 0},
+		{keys: "esc", label: "cancel", visible: true},
+	})))
+	return lipgloss.NewStyle().Width(width).Height(height).Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
+
+// handlePageKey handles the keys that act differently on an open page.
+// The rest (scrolling, theme, quit) fall through to the day's bindings.
+func (n *Notebook) handlePageKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "backspace":
+		n.page = nil
+		n.updateViewportContent()
+		n.viewport.GotoTop()
+	case "enter", "e", "i":
+		return n.startPageEdit(), true
+	case "left", "h", "right", "l", "r", "m":
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// startPageEdit runs the external editor on the open page.
+func (n *Notebook) startPageEdit() tea.Cmd {
+	if n.editor == nil || n.savePage == nil {
+		n.flashError("no editor configured for pages")
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	name, base := n.page.name, n.page.content
+	cmd, path, cleanup, err := n.editor.Prepare(base)
+	if err != nil {
+		n.flashError(fmt.Sprintf("editor prepare: %v", err))
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	return tea.ExecProcess(cmd, func(execErr error) tea.Msg {
+		return pageEditDoneMsg{name: name, base: base, path: path, cleanup: cleanup, err: execErr}
+	})
+}
+
+// finishPageEdit saves an edited page and lists it when it is new.
+func (n *Notebook) finishPageEdit(msg pageEditDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.cleanup != nil {
+		defer msg.cleanup()
+	}
+	if msg.err != nil {
+		n.flashError(fmt.Sprintf("editor: %v", msg.err))
+		return n, n.theme.expireStatusCmd(2 * time.Second)
+	}
+	content, err := editor.ReadEdited(msg.path)
+	if err != nil {
+		n.flashError(fmt.Sprintf("read: %v", err))
+		return n, n.theme.expireStatusCmd(2 * time.Second)
+	}
+	if content == msg.base {
+		return n, nil
+	}
+	if err := n.savePage(msg.name, content); err != nil {
+		n.flashError(fmt.Sprintf("save: %v", err))
+		return n, n.theme.expireStatusCmd(2 * time.Second)
+	}
+	n.addPageName(msg.name)
+	if n.page != nil && n.page.name == msg.name {
+		n.page.content = content
+		n.updateViewportContent()
+	}
+	n.theme.SetStatus("Saved", 1500*time.Millisecond)
+	return n, n.theme.expireStatusCmd(1500 * time.Millisecond)
+}
+
+// addPageName lists a newly saved page, keeping the list in name order.
+func (n *Notebook) addPageName(name string) {
+	slug := scratchpad.PageSlug(name)
+	for _, existing := range n.pageNames {
+		if scratchpad.PageSlug(existing) == slug {
+			return
+		}
+	}
+	i := 0
+	for i < len(n.pageNames) && strings.ToLower(n.pageNames[i]) < strings.ToLower(name) {
+		i++
+	}
+	n.pageNames = slices.Insert(n.pageNames, i, name)
+}
+
+// renderPageFooter is renderFooter for an open page.
+func (n *Notebook) renderPageFooter() string {
+	palette := n.theme.Palette()
+	nav := lipgloss.NewStyle().Width(n.width).Align(lipgloss.Center).
+		Render(palette.MutedText.Render("[[" + n.page.name + "]]"))
+	rule := palette.Separator.Render(strings.Repeat("─", max(n.width, 0)))
+	help := palette.Help.Render(renderHelp([]helpEntry{
+		{keys: "↑/k", label: "up", visible: true},
+		{keys: "↓/j", label: "down", visible: true},
+		{keys: "Ctrl+u/d", label: "page up/down", visible: true},
+		{keys: "enter/e", label: "edit", visible: n.editor != nil && n.savePage != nil},
+		{keys: "p", label: "pages", visible: true},
+		{keys: "esc", label: "back", visible: true},
+		{keys: "q", label: "quit", visible: true},
+	}))
+	return lipgloss.JoinVertical(lipgloss.Left, nav, rule, help)
+}
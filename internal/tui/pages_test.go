@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newPagesApp(t *testing.T, mode AppMode) (*App, map[string]string) {
+	t.Helper()
+	app := newTestApp(mode)
+	t.Cleanup(app.Close)
+	app.cal.SetContents(map[string]string{"2024-01-15": "Paged, see [[Oncall Runbook]] and [[Postmortem]]"})
+	app.nb.SetContents(map[string]string{"2024-01-15": "Paged, see [[Oncall Runbook]] and [[Postmortem]]"})
+	stored := map[string]string{"Oncall Runbook": "# Runbook\n\nRestart the frobnicator.", "Reading list": "- SICP"}
+	app.SetPages([]string{"oncall runbook", "Reading list"},
+		func(name string) (string, error) { return stored[name], nil },
+		func(name, content string) error {
+			stored[name] = content
+			return nil
+		})
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	return app, stored
+}
+
+func TestAppPageChooserListsLinkedPagesFirst(t *testing.T) {
+	app, _ := newPagesApp(t, ModeNotebook)
+
+	pressRune(app, 'p')
+	if app.pageChooser == nil {
+		t.Fatal("p should open the page chooser")
+	}
+	if want := []string{"Oncall Runbook", "Postmortem", "Reading list"}; !slices.Equal(app.pageChooser.options, want) {
+		t.Errorf("options = %q, want %q", app.pageChooser.options, want)
+	}
+	if view := app.View(); !strings.Contains(view, "Postmortem  linked") || strings.Contains(view, "Reading list  linked") {
+		t.Errorf("linked pages should be marked:\n%s", view)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.pageChooser != nil || app.nb.page == nil || app.nb.page.name != "Oncall Runbook" {
+		t.Fatalf("enter should open the page, got %+v", app.nb.page)
+	}
+	if view := app.View(); !strings.Contains(view, "page Oncall Runbook") || !strings.Contains(view, "frobnicator") {
+		t.Errorf("page view:\n%s", view)
+	}
+	pressRune(app, 'd')
+	if app.confirmDelete != "" {
+		t.Error("d on a page should not offer to delete the day behind it")
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.nb.page != nil || app.Mode() != ModeNotebook || app.IsQuitting() {
+		t.Error("esc should close the page and stay on the day")
+	}
+}
+
+func TestAppPageOpenedFromCalendarReturnsThere(t *testing.T) {
+	app, _ := newPagesApp(t, ModeCalendar)
+	app.cal.SetCursor("2024-01-15")
+
+	pressRune(app, 'p')
+	pressRune(app, 'j')
+	app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.Mode() != ModeNotebook || app.nb.page == nil || app.nb.page.name != "Postmortem" {
+		t.Fatalf("page not opened from the calendar: mode %v, %+v", app.Mode(), app.nb.page)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.Mode() != ModeCalendar {
+		t.Error("closing the page should return to the calendar")
+	}
+}
+
+func TestNotebookPageEditSavesAndListsPage(t *testing.T) {
+	app, stored := newPagesApp(t, ModeNotebook)
+	tmpFile := filepath.Join(t.TempDir(), "edit.md")
+	if err := os.WriteFile(tmpFile, []byte("# Postmortem\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pressRune(app, 'p')
+	pressRune(app, 'j')
+	app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	app.Update(pageEditDoneMsg{name: "Postmortem", path: tmpFile})
+	if stored["Postmortem"] != "# Postmortem\n" || app.nb.page.content != "# Postmortem\n" {
+		t.Errorf("page not saved: %q", stored["Postmortem"])
+	}
+	if want := []string{"oncall runbook", "Postmortem", "Reading list"}; !slices.Equal(app.nb.pageNames, want) {
+		t.Errorf("pageNames = %q, want %q", app.nb.pageNames, want)
+	}
+}
+
+func TestAppPageKeyNeedsPages(t *testing.T) {
+	app := newTestApp(ModeCalendar)
+	defer app.Close()
+	pressRune(app, 'p')
+	if app.pageChooser != nil {
+		t.Error("p without pages should do nothing")
+	}
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PageRevision is one earlier version of a notebook page.
+type PageRevision struct {
+	ID      string
+	Saved   time.Time
+	Content string
+}
+
+// HistoryLoader returns the saved revisions of date, newest first.
+type HistoryLoader func(date string) ([]PageRevision, error)
+
+// revisionBrowser tracks which earlier version of the current page the
+// notebook is showing. Index 0 is the newest revision.
+type revisionBrowser struct {
+	date      string
+	revisions []PageRevision
+	index     int
+}
+
+func (r *revisionBrowser) current() PageRevision { return r.revisions[r.index] }
+
+// SetHistory wires the revision source behind the r key. Without it the
+// key does nothing.
+func (n *Notebook) SetHistory(load HistoryLoader) {
+	n.history = load
+}
+
+// startRevisions loads the current page's history and switches the
+// viewport to browse it.
+func (n *Notebook) startRevisions() tea.Cmd {
+	if n.history == nil || len(n.pages) == 0 {
+		return nil
+	}
+	date := n.pages[n.current]
+	revisions, err := n.history(date)
+	if err != nil {
+		n.flashError(fmt.Sprintf("history: %v", err))
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	if len(revisions) == 0 {
+		n.theme.SetStatus("No earlier versions", 1500*time.Millisecond)
+		return n.theme.expireStatusCmd(1500 * time.Millisecond)
+	}
+	n.revisions = &revisionBrowser{date: date, revisions: revisions}
+	n.updateViewportContent()
+	n.viewport.GotoTop()
+	return nil
+}
+
+func (n *Notebook) stopRevisions() {
+	n.revisions = nil
+	n.updateViewportContent()
+	n.viewport.GotoTop()
+}
+
+// handleRevisionKey drives the revision browser: h/l step older/newer,
+// Enter restores the shown version, Esc/r return to the live page.
+func (n *Notebook) handleRevisionKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := n.revisions
+	switch msg.String() {
+	case "ctrl+c", "q":
+		n.quitting = true
+	case "esc", "backspace", "r":
+		n.stopRevisions()
+	case "left", "h":
+		if r.index < len(r.revisions)-1 {
+			r.index++
+			n.updateViewportContent()
+			n.viewport.GotoTop()
+		}
+	case "right", "l":
+		if r.index > 0 {
+			r.index--
+			n.updateViewportContent()
+			n.viewport.GotoTop()
+		}
+	case "enter":
+		return n, n.restoreRevision()
+	case "up", "k":
+		n.viewport.LineUp(1)
+	case "down", "j":
+		n.viewport.LineDown(1)
+	case "ctrl+u":
+		n.viewport.SetYOffset(n.viewport.YOffset - n.viewport.Height/2)
+	case "ctrl+d":
+		n.viewport.SetYOffset(n.viewport.YOffset + n.viewport.Height/2)
+	}
+	return n, nil
+}
+
+// restoreRevision saves the browsed version as the page's new content.
+// The replaced version stays in history, so this is itself undoable.
+func (n *Notebook) restoreRevision() tea.Cmd {
+	rev := n.revisions.current()
+	date := n.revisions.date
+	if n.save == nil {
+		n.flashError("restore: no saver wired")
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	if rev.Content == n.content(date) {
+		n.stopRevisions()
+		return nil
+	}
+	if err := n.save(date, rev.Content); err != nil {
+		n.flashError(fmt.Sprintf("restore: %v", err))
+		return n.theme.expireStatusCmd(2 * time.Second)
+	}
+	n.contents[date] = rev.Content
+	n.stopRevisions()
+	n.theme.SetStatus("Restored "+rev.Saved.Format("2006-01-02 15:04"), 1500*time.Millisecond)
+	return n.theme.expireStatusCmd(1500 * time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newRevisionNotebook(t *testing.T, saved map[string]string) *Notebook {
+	t.Helper()
+	nb := NewNotebook([]string{"2024-01-01"})
+	t.Cleanup(nb.Close)
+	nb.SetContents(map[string]string{"2024-01-01": "current"})
+	nb.SetEditor(nil, func(date, content string) error {
+		saved[date] = content
+		return nil
+	})
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+	nb.SetHistory(func(string) ([]PageRevision, error) {
+		return []PageRevision{
+			{ID: "3", Saved: base.Add(2 * time.Hour), Content: "current"},
+			{ID: "2", Saved: base.Add(time.Hour), Content: "middle"},
+			{ID: "1", Saved: base, Content: "first"},
+		}, nil
+	})
+	nb.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	return nb
+}
+
+func pressRune(m tea.Model, r rune) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+}
+
+func TestNotebookRevisionBrowseAndRestore(t *testing.T) {
+	saved := map[string]string{}
+	nb := newRevisionNotebook(t, saved)
+
+	pressRune(nb, 'r')
+	if nb.revisions == nil {
+		t.Fatal("r should open the revision browser")
+	}
+	pressRune(nb, 'h')
+	pressRune(nb, 'h')
+	pressRune(nb, 'h') // clamps at the oldest revision
+	if got := nb.revisions.current().Content; got != "first" {
+		t.Fatalf("browsed revision = %q, want %q", got, "first")
+	}
+	if !strings.Contains(nb.View(), "revision 1/3") {
+		t.Error("header should show the revision position")
+	}
+
+	nb.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if saved["2024-01-01"] != "first" {
+		t.Errorf("saved = %q, want restored revision", saved["2024-01-01"])
+	}
+	if _, content := nb.CurrentContent(); content != "first" {
+		t.Errorf("page content = %q, want %q", content, "first")
+	}
+	if nb.revisions != nil {
+		t.Error("restore should close the revision browser")
+	}
+}
+
+func TestNotebookRevisionEscReturnsToPage(t *testing.T) {
+	saved := map[string]string{}
+	nb := newRevisionNotebook(t, saved)
+
+	pressRune(nb, 'r')
+	pressRune(nb, 'h')
+	nb.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if nb.revisions != nil {
+		t.Error("esc should close the revision browser")
+	}
+	if nb.IsPopping() {
+		t.Error("esc inside the browser must not pop the notebook")
+	}
+	if len(saved) != 0 {
+		t.Errorf("closing the browser saved %v", saved)
+	}
+}
+
+func TestNotebookRevisionKeyWithoutHistoryIsNoop(t *testing.T) {
+	nb := NewNotebook([]string{"2024-01-01"})
+	defer nb.Close()
+	pressRune(nb, 'r')
+	if nb.revisions != nil {
+		t.Error("r without a history loader should do nothing")
+	}
+}
@@ -74,6 +74,9 @@ func (a *App) startTemplateChooser() bool {
 	case ModeCalendar:
 		date = a.cal.CursorDate()
 	case ModeNotebook:
+		if a.nb.merging != nil || a.nb.page != nil {
+			return false
+		}
 		date, _ = a.nb.CurrentContent()
 	}
 	if date == "" {
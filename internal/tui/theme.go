@@ -19,7 +19,7 @@ const (
 	ThemePrefDark  = "dark"
 )
 
-// resolveGlamourStyle picks the glamour style ("dark"/"light") for a
+// ResolveGlamourStyle picks the glamour style ("dark"/"light") for a
 // given user preference. Resolution order:
 //
 //  1. "light" / "dark" — explicit override, return immediately.
@@ -37,7 +37,7 @@ const (
 // are intentionally avoided because they can block startup for up to
 // 5 s on terminals that never reply; the macOS plist watcher handles
 // dynamic detection there.
-func resolveGlamourStyle(pref string) string {
+func ResolveGlamourStyle(pref string) string {
 	switch strings.ToLower(strings.TrimSpace(pref)) {
 	case ThemePrefLight:
 		return styles.LightStyle
@@ -11,17 +11,17 @@ func TestResolveGlamourStyle_ExplicitPrefWins(t *testing.T) {
 	t.Setenv("GLAMOUR_STYLE", "")
 	t.Setenv("COLORFGBG", "0;15") // would normally select light
 
-	if got := resolveGlamourStyle(ThemePrefDark); got != styles.DarkStyle {
+	if got := ResolveGlamourStyle(ThemePrefDark); got != styles.DarkStyle {
 		t.Errorf("dark pref: got %q want %q", got, styles.DarkStyle)
 	}
-	if got := resolveGlamourStyle(ThemePrefLight); got != styles.LightStyle {
+	if got := ResolveGlamourStyle(ThemePrefLight); got != styles.LightStyle {
 		t.Errorf("light pref: got %q want %q", got, styles.LightStyle)
 	}
 }
 
 func TestResolveGlamourStyle_PrefIsCaseInsensitive(t *testing.T) {
 	for _, in := range []string{"LIGHT", "Light", "  light  "} {
-		if got := resolveGlamourStyle(in); got != styles.LightStyle {
+		if got := ResolveGlamourStyle(in); got != styles.LightStyle {
 			t.Errorf("input %q: got %q want %q", in, got, styles.LightStyle)
 		}
 	}
@@ -30,7 +30,7 @@ func TestResolveGlamourStyle_PrefIsCaseInsensitive(t *testing.T) {
 func TestResolveGlamourStyle_AutoHonorsGlamourStyleEnv(t *testing.T) {
 	t.Setenv("GLAMOUR_STYLE", "ascii")
 	t.Setenv("COLORFGBG", "")
-	if got := resolveGlamourStyle(ThemePrefAuto); got != "ascii" {
+	if got := ResolveGlamourStyle(ThemePrefAuto); got != "ascii" {
 		t.Errorf("got %q want %q", got, "ascii")
 	}
 }
@@ -50,7 +50,7 @@ func TestResolveGlamourStyle_AutoHonorsCOLORFGBG(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.fgbg, func(t *testing.T) {
 			t.Setenv("COLORFGBG", tc.fgbg)
-			if got := resolveGlamourStyle(ThemePrefAuto); got != tc.want {
+			if got := ResolveGlamourStyle(ThemePrefAuto); got != tc.want {
 				t.Errorf("COLORFGBG=%q got %q want %q", tc.fgbg, got, tc.want)
 			}
 		})
@@ -61,7 +61,7 @@ func TestResolveGlamourStyle_UnknownPrefFallsThrough(t *testing.T) {
 	t.Setenv("GLAMOUR_STYLE", "ascii")
 	t.Setenv("COLORFGBG", "")
 	for _, in := range []string{"", "weird", "AUTO"} {
-		if got := resolveGlamourStyle(in); got == "" {
+		if got := ResolveGlamourStyle(in); got == "" {
 			t.Errorf("input %q: got empty string", in)
 		}
 	}
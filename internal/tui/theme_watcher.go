@@ -30,14 +30,14 @@ type themeWatcher struct {
 }
 
 // newThemeWatcher returns a watcher seeded with the given preference.
-// pref is normalized via resolveGlamourStyle for the initial style.
+// pref is normalized via ResolveGlamourStyle for the initial style.
 func newThemeWatcher(pref string) *themeWatcher {
 	if pref == "" {
 		pref = ThemePrefAuto
 	}
 	return &themeWatcher{
 		pref:     pref,
-		resolved: resolveGlamourStyle(pref),
+		resolved: ResolveGlamourStyle(pref),
 		events:   make(chan struct{}, 1),
 	}
 }
@@ -48,7 +48,7 @@ func (w *themeWatcher) SetPref(pref string) {
 		pref = ThemePrefAuto
 	}
 	w.pref = pref
-	w.resolved = resolveGlamourStyle(pref)
+	w.resolved = ResolveGlamourStyle(pref)
 }
 
 // Pref returns the current user preference label.
@@ -70,7 +70,7 @@ func (w *themeWatcher) Cycle() {
 // applyResolved re-runs resolution against the current preference and
 // returns true when the resolved style actually changed.
 func (w *themeWatcher) applyResolved() bool {
-	next := resolveGlamourStyle(w.pref)
+	next := ResolveGlamourStyle(w.pref)
 	if next == w.resolved {
 		return false
 	}
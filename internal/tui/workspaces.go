@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WorkspaceSwitcher builds the views for the named workspace, wired like
+// the ones it replaces.
+type WorkspaceSwitcher func(name string) (*App, error)
+
+// workspaceChooser is the w key's list of workspaces.
+type workspaceChooser struct {
+	cursor int
+}
+
+// SetWorkspaces lists the configured workspaces, current being the one
+// shown ("" for the top-level store), and wires the w key that switches
+// between them. Without at least two choices the key does nothing.
+func (a *App) SetWorkspaces(names []string, current string, switchTo WorkspaceSwitcher) {
+	a.workspaces = append([]string(nil), names...)
+	a.workspace = current
+	a.switchWorkspace = switchTo
+	available := switchTo != nil && len(a.workspaceOptions()) > 1
+	a.cal.workspace, a.cal.workspacesAvailable = current, available
+	a.nb.workspace, a.nb.workspacesAvailable = current, available
+}
+
+// workspaceOptions is what the chooser offers: the top-level store ("")
+// and then every workspace.
+func (a *App) workspaceOptions() []string {
+	return append([]string{""}, a.workspaces...)
+}
+
+func workspaceLabel(name string) string {
+	if name == "" {
+		return "(default)"
+	}
+	return name
+}
+
+// startWorkspaceChooser opens the chooser on the current workspace.
+func (a *App) startWorkspaceChooser() bool {
+	if !a.cal.workspacesAvailable || a.nb.revisions != nil || a.nb.merging != nil {
+		return false
+	}
+	chooser := &workspaceChooser{}
+	for i, name := range a.workspaceOptions() {
+		if name == a.workspace {
+			chooser.cursor = i
+		}
+	}
+	a.workspaceChooser = chooser
+	return true
+}
+
+func (a *App) updateWorkspaceChooser(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	chooser := a.workspaceChooser
+	options := a.workspaceOptions()
+	switch key.String() {
+	case "ctrl+c", "q":
+		a.quitting = true
+		return a, tea.Quit
+	case "esc", "w":
+		a.workspaceChooser = nil
+	case "up", "k":
+		if chooser.cursor > 0 {
+			chooser.cursor--
+		}
+	case "down", "j":
+		if chooser.cursor < len(options)-1 {
+			chooser.cursor++
+		}
+	case "enter":
+		a.workspaceChooser = nil
+		if name := options[chooser.cursor]; name != a.workspace {
+			return a, a.switchTo(name)
+		}
+	}
+	return a, nil
+}
+
+// switchTo replaces both views with the named workspace's, in place: the
+// running program keeps this App, so it takes over the new one's state.
+func (a *App) switchTo(name string) tea.Cmd {
+	next, err := a.switchWorkspace(name)
+	if err != nil {
+		return a.templateStatus(fmt.Sprintf("workspace: %v", err), true)
+	}
+	width, height := a.cal.width, a.cal.height
+	workspaces, switchTo := a.workspaces, a.switchWorkspace
+	a.Close()
+	*a = *next
+	a.SetWorkspaces(workspaces, name, switchTo)
+	size := tea.WindowSizeMsg{Width: width, Height: height}
+	a.cal.Update(size)
+	a.nb.Update(size)
+	return tea.Batch(a.Init(), a.templateStatus("Workspace "+workspaceLabel(name), false))
+}
+
+func (a *App) renderWorkspaceChooser() string {
+	palette := a.cal.theme.Palette()
+	width, height := a.cal.width, a.cal.height
+	if a.mode == ModeNotebook {
+		palette = a.nb.theme.Palette()
+		width, height = a.nb.width, a.nb.height
+	}
+
+	lines := []string{palette.Header.Render("Workspaces"), ""}
+	for i, name := range a.workspaceOptions() {
+		label := workspaceLabel(name)
+		style := lipgloss.NewStyle().Foreground(palette.Text)
+		if name == a.workspace {
+			label += "  current"
+			style = palette.MutedText
+		}
+		cursor := "  "
+		if i == a.workspaceChooser.cursor {
+			cursor = "▌ "
+			style = style.Foreground(palette.Highlight).Bold(true)
+		}
+		lines = append(lines, cursor+style.Render(label))
+	}
+	lines = append(lines, "", palette.Help.Render(renderHelp([]helpEntry{
+		{keys: "↑/k ↓/j", label: "move", visible: true},
+		{keys: "enter", label: "switch", visible: true},
+		{keys: "esc", label: "cancel", visible: true},
+	})))
+	return lipgloss.NewStyle().Width(width).Height(height).Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
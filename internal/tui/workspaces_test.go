@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAppWorkspaceSwitchReplacesViews(t *testing.T) {
+	app := newTestApp(ModeCalendar)
+	defer app.Close()
+	var opened []string
+	app.SetWorkspaces([]string{"home", "work"}, "", func(name string) (*App, error) {
+		opened = append(opened, name)
+		if name == "home" {
+			return nil, errors.New("locked")
+		}
+		cal := NewCalendar([]string{"2024-02-01"})
+		nb := NewNotebook([]string{"2024-02-01"})
+		return NewApp(cal, nb, ModeCalendar), nil
+	})
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	pressRune(app, 'w')
+	if app.workspaceChooser == nil {
+		t.Fatal("w should open the workspace chooser")
+	}
+	if view := app.View(); !strings.Contains(view, "(default)") || !strings.Contains(view, "work") {
+		t.Errorf("chooser should list every workspace:\n%s", view)
+	}
+	pressRune(app, 'j')
+	app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(opened) != 1 || opened[0] != "home" {
+		t.Fatalf("opened = %v", opened)
+	}
+	if app.workspace != "" || !app.cal.HasData("2024-01-15") {
+		t.Error("a failed switch should keep the current workspace")
+	}
+
+	pressRune(app, 'w')
+	pressRune(app, 'j')
+	pressRune(app, 'j')
+	app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.workspace != "work" || !app.cal.HasData("2024-02-01") || app.cal.HasData("2024-01-15") {
+		t.Errorf("switch should load the work workspace, got %q", app.workspace)
+	}
+	if app.cal.width != 120 || app.nb.height != 40 {
+		t.Errorf("new views should keep the window size: %dx%d", app.cal.width, app.nb.height)
+	}
+	if !strings.Contains(app.View(), "work") {
+		t.Error("header should name the workspace")
+	}
+	pressRune(app, 'w')
+	if app.workspaceChooser == nil {
+		t.Error("the switched app should still offer the chooser")
+	}
+}
+
+func TestAppWorkspaceKeyNeedsWorkspaces(t *testing.T) {
+	app := newTestApp(ModeCalendar)
+	defer app.Close()
+	pressRune(app, 'w')
+	if app.workspaceChooser != nil {
+		t.Error("w without workspaces should do nothing")
+	}
+}
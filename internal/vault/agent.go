@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnvAgentSocket overrides the agent's socket path.
+const EnvAgentSocket = "SP_AGENT_SOCK"
+
+// agentTimeout bounds a client's conversation with the agent, so a wedged
+// agent never hangs sp.
+const agentTimeout = 2 * time.Second
+
+// SocketPath returns where `sp agent` listens: $SP_AGENT_SOCK, else
+// sp-agent.sock in $XDG_RUNTIME_DIR, else a per-user directory in the
+// system temp directory. Whichever it is, Listen and the clients only use
+// a socket in a directory private to the user; see checkPrivate.
+func SocketPath() string {
+	if path := os.Getenv(EnvAgentSocket); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sp-agent.sock")
+	}
+	return filepath.Join(os.TempDir(), "sp-"+strconv.Itoa(os.Getuid()), "agent.sock")
+}
+
+// agentRequest is one line sent to the agent. Op is "get", "put" or
+// "lock"; keys are looked up by Params.ID.
+type agentRequest struct {
+	Op  string `json:"op"`
+	ID  string `json:"id,omitempty"`
+	Key []byte `json:"key,omitempty"`
+}
+
+type agentResponse struct {
+	Key   []byte `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type cachedKey struct {
+	key     []byte
+	expires time.Time
+}
+
+// Agent keeps unlocked keys in memory so one passphrase prompt covers
+// every sp run until the TTL passes or the agent is locked.
+type Agent struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+// NewAgent returns an agent that forgets keys ttl after they were stored.
+// A zero ttl keeps them until the agent is locked or stopped.
+func NewAgent(ttl time.Duration) *Agent {
+	return &Agent{ttl: ttl, now: time.Now, keys: make(map[string]cachedKey)}
+}
+
+// Listen opens the agent socket at path. The socket's directory is
+// created private to the user, and refused when it already exists but
+// is not, since whoever controls it controls the socket. A stale socket
+// left by a crashed agent is replaced; a live one is an error.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create agent directory: %w", err)
+	}
+	if err := checkPrivate(dir, true); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, agentTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an agent is already listening on %s", path)
+	}
+	_ = os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to restrict %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// checkSocket verifies that the agent socket at path, and the directory
+// holding it, belong to the current user and are closed to everyone else,
+// so a socket planted by another local user is never trusted with a key.
+func checkSocket(path string) error {
+	if err := checkPrivate(filepath.Dir(path), true); err != nil {
+		return err
+	}
+	return checkPrivate(path, false)
+}
+
+// checkPeer refuses a connection whose other end runs as another user,
+// where the platform reports it.
+func checkPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	uid, known, err := peerUID(uc)
+	if err != nil {
+		return fmt.Errorf("failed to check agent peer: %w", err)
+	}
+	if known && uid != os.Getuid() {
+		return fmt.Errorf("agent peer runs as uid %d, not %d", uid, os.Getuid())
+	}
+	return nil
+}
+
+// Serve answers requests on l until it is closed.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	if checkPeer(conn) != nil {
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(agentTimeout))
+	var req agentRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return
+	}
+	_ = json.NewEncoder(conn).Encode(a.answer(req))
+}
+
+func (a *Agent) answer(req agentRequest) agentResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch req.Op {
+	case "get":
+		cached, ok := a.keys[req.ID]
+		if ok && a.ttl > 0 && a.now().After(cached.expires) {
+			a.forget(req.ID)
+			ok = false
+		}
+		if !ok {
+			return agentResponse{Error: "locked"}
+		}
+		return agentResponse{Key: append([]byte(nil), cached.key...)}
+	case "put":
+		if req.ID == "" || len(req.Key) != keySize {
+			return agentResponse{Error: "invalid key"}
+		}
+		a.forget(req.ID)
+		a.keys[req.ID] = cachedKey{key: req.Key, expires: a.now().Add(a.ttl)}
+		return agentResponse{}
+	case "lock":
+		for id := range a.keys {
+			a.forget(id)
+		}
+		return agentResponse{}
+	}
+	return agentResponse{Error: fmt.Sprintf("unknown op %q", req.Op)}
+}
+
+// forget drops a key and overwrites its bytes. Callers hold a.mu.
+func (a *Agent) forget(id string) {
+	if cached, ok := a.keys[id]; ok {
+		clear(cached.key)
+		delete(a.keys, id)
+	}
+}
+
+// AgentKey asks the agent at path for the key cached under id.
+func AgentKey(path, id string) ([]byte, error) {
+	resp, err := callAgent(path, agentRequest{Op: "get", ID: id})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// AgentStore hands key to the agent at path.
+func AgentStore(path, id string, key []byte) error {
+	_, err := callAgent(path, agentRequest{Op: "put", ID: id, Key: key})
+	return err
+}
+
+// AgentLock makes the agent at path forget every key.
+func AgentLock(path string) error {
+	_, err := callAgent(path, agentRequest{Op: "lock"})
+	return err
+}
+
+func callAgent(path string, req agentRequest) (agentResponse, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return agentResponse{}, fmt.Errorf("no agent running: %w", err)
+	}
+	if err := checkSocket(path); err != nil {
+		return agentResponse{}, err
+	}
+	conn, err := net.DialTimeout("unix", path, agentTimeout)
+	if err != nil {
+		return agentResponse{}, fmt.Errorf("no agent running: %w", err)
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		return agentResponse{}, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(agentTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return agentResponse{}, fmt.Errorf("agent: %w", err)
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return agentResponse{}, fmt.Errorf("agent: %w", err)
+	}
+	if resp.Error != "" {
+		return agentResponse{}, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+
+package vault
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkPrivate verifies that path is a directory (dir) or a socket owned
+// by the current user with no permissions for group or others. Symlinks
+// are refused rather than followed.
+func checkPrivate(path string, dir bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	want, kind := fs.ModeSocket, "socket"
+	if dir {
+		want, kind = fs.ModeDir, "directory"
+	}
+	if info.Mode().Type() != want {
+		return fmt.Errorf("refusing %s: not a %s", path, kind)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("refusing %s: cannot tell its owner", path)
+	}
+	if int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("refusing %s: owned by uid %d, not %d", path, st.Uid, os.Getuid())
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("refusing %s: mode %04o is open to other users; want %04o", path, perm, perm&0o700)
+	}
+	return nil
+}
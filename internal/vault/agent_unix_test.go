@@ -0,0 +1,112 @@
+//go:build !windows
+
+package vault
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// agentDir returns a short private directory; Unix socket paths are
+// limited in length and t.TempDir can exceed it.
+func agentDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListenRefusesSharedDirectory(t *testing.T) {
+	dir := agentDir(t)
+	shared := filepath.Join(dir, "shared")
+	if err := os.Mkdir(shared, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := Listen(filepath.Join(shared, "agent.sock")); err == nil {
+		l.Close()
+		t.Fatal("Listen accepted a directory other users can write to")
+	}
+
+	// A symlink to a private directory is not trusted either.
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(agentDir(t), link); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := Listen(filepath.Join(link, "agent.sock")); err == nil {
+		l.Close()
+		t.Fatal("Listen followed a symlinked directory")
+	}
+}
+
+func TestClientsRefuseSocketsOpenToOthers(t *testing.T) {
+	dir := agentDir(t)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- NewAgent(time.Hour).Serve(l) }()
+	t.Cleanup(func() {
+		l.Close()
+		<-done
+	})
+	if err := AgentLock(socket); err != nil {
+		t.Fatalf("private agent refused: %v", err)
+	}
+
+	if err := os.Chmod(socket, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := AgentStore(socket, "store", make([]byte, keySize)); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("AgentStore to a world-writable socket = %v", err)
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AgentKey(socket, "store"); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("AgentKey through a shared directory = %v", err)
+	}
+}
+
+func TestCheckPeerAcceptsOwnUser(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	if err := checkPeer(a); err != nil {
+		t.Errorf("checkPeer(pipe) = %v", err)
+	}
+
+	dir := agentDir(t)
+	l, err := net.Listen("unix", filepath.Join(dir, "peer.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", filepath.Join(dir, "peer.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		t.Errorf("checkPeer(own process) = %v", err)
+	}
+}
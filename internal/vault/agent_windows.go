@@ -0,0 +1,12 @@
+//go:build windows
+
+package vault
+
+import "net"
+
+// checkPrivate is a no-op on Windows: the fallback socket directory lives
+// in the user's own %TEMP%, and file ACLs are not modelled by fs.FileMode.
+func checkPrivate(string, bool) error { return nil }
+
+// peerUID cannot be answered on Windows.
+func peerUID(*net.UnixConn) (int, bool, error) { return 0, false, nil }
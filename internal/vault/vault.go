@@ -0,0 +1,233 @@
+// Package vault derives the key for an encrypted sp store and seals day
+// content with AES-256-GCM. The parameters needed to derive the key (but
+// never the key itself) live in a .vault file in the storage directory.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the parameters file inside the storage directory. Its
+// presence marks the store as encrypted.
+const FileName = ".vault"
+
+// Key derivation functions recorded in Params.KDF.
+const (
+	// KDFPassphrase stretches a passphrase with PBKDF2-HMAC-SHA256.
+	KDFPassphrase = "pbkdf2-sha256"
+	// KDFKeyFile expands the contents of a key file with HKDF-SHA256.
+	KDFKeyFile = "hkdf-sha256"
+)
+
+// DefaultIterations is the PBKDF2 work factor for new passphrase vaults.
+const DefaultIterations = 600_000
+
+const (
+	keySize  = 32
+	saltSize = 16
+	// checkText is sealed into Params.Check so a wrong passphrase is
+	// reported up front instead of as a failure on the first day read.
+	checkText = "sp vault"
+	hkdfInfo  = "sp day content"
+)
+
+// ErrWrongKey reports a passphrase or key file that does not open the
+// vault.
+var ErrWrongKey = errors.New("wrong passphrase or key file")
+
+// Params describe how the store's key is derived.
+type Params struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt"`
+	Check      []byte `json:"check"`
+}
+
+// NewParams returns parameters with a fresh random salt for kdf. The check
+// value is filled in by Seal once the key is known.
+func NewParams(kdf string) (*Params, error) {
+	p := &Params{Version: 1, KDF: kdf}
+	switch kdf {
+	case KDFPassphrase:
+		p.Iterations = DefaultIterations
+	case KDFKeyFile:
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q", kdf)
+	}
+	p.Salt = make([]byte, saltSize)
+	if _, err := rand.Read(p.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return p, nil
+}
+
+// Load reads the parameters in dir. A store that was never encrypted
+// returns an error wrapping fs.ErrNotExist.
+func Load(dir string) (*Params, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	var p Params
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	if p.Version != 1 {
+		return nil, fmt.Errorf("%s: unsupported version %d", FileName, p.Version)
+	}
+	if len(p.Salt) < saltSize || len(p.Check) == 0 {
+		return nil, fmt.Errorf("%s: missing salt or check value", FileName)
+	}
+	return &p, nil
+}
+
+// Save writes the parameters into dir, readable only by the owner.
+func (p *Params) Save(dir string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", FileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", FileName, err)
+	}
+	return nil
+}
+
+// Remove deletes the parameters from dir once the store is plain text
+// again.
+func Remove(dir string) error {
+	if err := os.Remove(filepath.Join(dir, FileName)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", FileName, err)
+	}
+	return nil
+}
+
+// ID names the vault in the agent's cache. It is derived from the salt,
+// so it identifies a store without revealing anything about its key.
+func (p *Params) ID() string {
+	return hex.EncodeToString(p.Salt)
+}
+
+// DeriveKey turns a passphrase or key file contents into the content key.
+func (p *Params) DeriveKey(secret []byte) ([]byte, error) {
+	switch p.KDF {
+	case KDFPassphrase:
+		if p.Iterations <= 0 {
+			return nil, fmt.Errorf("%s: invalid iteration count %d", FileName, p.Iterations)
+		}
+		return pbkdf2.Key(sha256.New, string(secret), p.Salt, p.Iterations, keySize)
+	case KDFKeyFile:
+		return hkdf.Key(sha256.New, secret, p.Salt, hkdfInfo, keySize)
+	}
+	return nil, fmt.Errorf("%s: unknown key derivation %q", FileName, p.KDF)
+}
+
+// Seal records a check value for key, so later unlocks can verify it.
+func (p *Params) Seal(key []byte) error {
+	c, err := NewCipher(key)
+	if err != nil {
+		return err
+	}
+	p.Check, err = c.Seal([]byte(checkText), p.Salt)
+	return err
+}
+
+// Unlock verifies key against the check value and returns a cipher for it.
+func (p *Params) Unlock(key []byte) (*Cipher, error) {
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if plain, err := c.Open(p.Check, p.Salt); err != nil || string(plain) != checkText {
+		return nil, ErrWrongKey
+	}
+	return c, nil
+}
+
+// ReadKeyFile returns the secret in a key file. Surrounding whitespace is
+// ignored so files written by editors or `openssl rand -hex` both work.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) < keySize {
+		return nil, fmt.Errorf("key file %s is too short; use at least %d bytes", path, keySize)
+	}
+	return secret, nil
+}
+
+// GenerateKeyFile writes a new random key file at path, readable only by
+// the owner. An existing file is never overwritten.
+func GenerateKeyFile(path string) error {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create key file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(secret) + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return f.Close()
+}
+
+// Cipher seals content with AES-256-GCM. Each sealed value is a fresh
+// random nonce followed by the ciphertext and tag.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a cipher for a 32-byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, binding it to additional.
+func (c *Cipher) Seal(plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// Open decrypts a value produced by Seal with the same additional data.
+func (c *Cipher) Open(sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, ciphertext, additional)
+}
@@ -0,0 +1,163 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastParams keeps PBKDF2 cheap in tests.
+func fastParams(t *testing.T, kdf string) *Params {
+	t.Helper()
+	p, err := NewParams(kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kdf == KDFPassphrase {
+		p.Iterations = 1000
+	}
+	return p
+}
+
+func TestPassphraseUnlocksSavedVault(t *testing.T) {
+	dir := t.TempDir()
+	p := fastParams(t, KDFPassphrase)
+	key, err := p.DeriveKey([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Seal(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, FileName)); info.Mode().Perm() != 0o600 {
+		t.Errorf("vault mode = %s", info.Mode().Perm())
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := loaded.DeriveKey([]byte("correct horse"))
+	c, err := loaded.Unlock(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := c.Seal([]byte("hello"), []byte("2024-03-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := c.Open(sealed, []byte("2024-03-01")); err != nil || string(plain) != "hello" {
+		t.Errorf("Open = %q, %v", plain, err)
+	}
+	if _, err := c.Open(sealed, []byte("2024-03-02")); err == nil {
+		t.Error("Open must fail with different additional data")
+	}
+
+	wrong, _ := loaded.DeriveKey([]byte("wrong horse"))
+	if _, err := loaded.Unlock(wrong); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("wrong passphrase: err = %v", err)
+	}
+}
+
+func TestLoadWithoutVault(t *testing.T) {
+	if _, err := Load(t.TempDir()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "sp.key")
+	if err := GenerateKeyFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateKeyFile(path); err == nil {
+		t.Error("an existing key file must not be overwritten")
+	}
+	secret, err := ReadKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := fastParams(t, KDFKeyFile)
+	key, _ := p.DeriveKey(secret)
+	if err := p.Seal(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Unlock(key); err != nil {
+		t.Error(err)
+	}
+
+	short := filepath.Join(t.TempDir(), "short.key")
+	_ = os.WriteFile(short, []byte("tiny\n"), 0o600)
+	if _, err := ReadKeyFile(short); err == nil {
+		t.Error("expected error for a short key file")
+	}
+}
+
+func TestSealUsesFreshNonces(t *testing.T) {
+	c, _ := NewCipher(bytes.Repeat([]byte{7}, keySize))
+	a, _ := c.Seal([]byte("same"), nil)
+	b, _ := c.Seal([]byte("same"), nil)
+	if bytes.Equal(a, b) {
+		t.Error("sealing twice produced identical output")
+	}
+}
+
+func TestAgentCachesKeysUntilLocked(t *testing.T) {
+	// Unix socket paths are short; t.TempDir can exceed the limit.
+	dir, err := os.MkdirTemp("", "sp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := NewAgent(time.Hour)
+	var clock atomic.Int64
+	clock.Store(time.Now().UnixNano())
+	agent.now = func() time.Time { return time.Unix(0, clock.Load()) }
+	done := make(chan error, 1)
+	go func() { done <- agent.Serve(l) }()
+	t.Cleanup(func() {
+		l.Close()
+		<-done
+	})
+
+	if _, err := Listen(socket); err == nil {
+		t.Error("a second agent must not replace a live one")
+	}
+	if _, err := AgentKey(socket, "store"); err == nil {
+		t.Error("expected a miss before any key is stored")
+	}
+	key := bytes.Repeat([]byte{3}, keySize)
+	if err := AgentStore(socket, "store", key); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := AgentKey(socket, "store"); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("AgentKey = %x, %v", got, err)
+	}
+
+	clock.Add(int64(2 * time.Hour))
+	if _, err := AgentKey(socket, "store"); err == nil {
+		t.Error("key should expire after the TTL")
+	}
+
+	_ = AgentStore(socket, "store", key)
+	if err := AgentLock(socket); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AgentKey(socket, "store"); err == nil {
+		t.Error("key should be gone after lock")
+	}
+}